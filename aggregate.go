@@ -0,0 +1,443 @@
+package nson
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
+	"strings"
+)
+
+// 聚合管道由多个阶段组成，每个阶段是只有一个键的 Map，整个管道是一个 Array，
+// 因此可以像普通文档一样编码传输：
+//
+//	Array{
+//		Map{"$match": Map{"status": String("ok")}},
+//		Map{"$unwind": String("$items")},
+//		Map{"$group": Map{
+//			"_id":   String("$items.sku"),
+//			"total": Map{"$sum": String("$items.qty")},
+//		}},
+//		Map{"$sort": Map{"total": I32(-1)}},
+//		Map{"$limit": I32(10)},
+//	}
+//
+// 支持的阶段：$match $project $addFields（别名 $set）$group $sort $limit $skip $unwind。
+// $group 支持的累加器：$sum $avg $min $max $count $push $addToSet $first $last。
+// $sort 使用 Compare 的顺序；多个排序键需要按顺序写成 Array{Map{...}, Map{...}}。
+//
+// 以 "$" 开头的字符串表示字段引用，如 String("$items.qty")；
+// $addFields 和 $group 中的值是表达式，见 Eval。
+
+// Pipeline 编译后的聚合管道
+type Pipeline struct {
+	stages []pipelineStage
+}
+
+type pipelineStage func(in iter.Seq2[Map, error]) iter.Seq2[Map, error]
+
+// Aggregate 编译并在 docs 上执行管道
+func Aggregate(docs []Map, pipeline Array) ([]Map, error) {
+	p, err := CompilePipeline(pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.Run(docs)
+}
+
+// CompilePipeline 编译聚合管道
+func CompilePipeline(pipeline Array) (*Pipeline, error) {
+	p := &Pipeline{stages: make([]pipelineStage, 0, len(pipeline))}
+
+	for i, item := range pipeline {
+		m, ok := item.(Map)
+		if !ok || len(m) != 1 {
+			return nil, fmt.Errorf("stage %d: expected Map with a single key, got %v", i, item)
+		}
+
+		for name, spec := range m {
+			compile, has := stageCompilers[name]
+			if !has {
+				return nil, fmt.Errorf("stage %d: unknown stage %v", i, name)
+			}
+
+			stage, err := compile(spec)
+			if err != nil {
+				return nil, fmt.Errorf("stage %d (%v): %w", i, name, err)
+			}
+
+			p.stages = append(p.stages, stage)
+		}
+	}
+
+	return p, nil
+}
+
+// Run 在 docs 上执行管道并收集结果，输入文档不会被修改
+func (self *Pipeline) Run(docs []Map) ([]Map, error) {
+	result := []Map{}
+
+	for doc, err := range self.Iter(slices.Values(docs)) {
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, doc)
+	}
+
+	return result, nil
+}
+
+// Iter 以迭代器的方式执行管道。$group 和 $sort 需要读取全部输入，
+// 其他阶段逐个处理文档。出错时产生一个 (nil, err) 并结束。
+func (self *Pipeline) Iter(docs iter.Seq[Map]) iter.Seq2[Map, error] {
+	var out iter.Seq2[Map, error] = func(yield func(Map, error) bool) {
+		for doc := range docs {
+			if !yield(doc, nil) {
+				return
+			}
+		}
+	}
+
+	for _, stage := range self.stages {
+		out = stage(out)
+	}
+
+	return out
+}
+
+var stageCompilers = map[string]func(spec Value) (pipelineStage, error){
+	"$match":     compileMatchStage,
+	"$project":   compileProjectStage,
+	"$addFields": compileAddFieldsStage,
+	"$set":       compileAddFieldsStage,
+	"$group":     compileGroupStage,
+	"$sort":      compileSortStage,
+	"$limit":     compileLimitStage,
+	"$skip":      compileSkipStage,
+	"$unwind":    compileUnwindStage,
+}
+
+// mapStage 逐个转换文档的阶段，fn 返回空切片表示丢弃文档
+func mapStage(fn func(doc Map) ([]Map, error)) pipelineStage {
+	return func(in iter.Seq2[Map, error]) iter.Seq2[Map, error] {
+		return func(yield func(Map, error) bool) {
+			for doc, err := range in {
+				if err != nil {
+					yield(nil, err)
+					return
+				}
+
+				out, err := fn(doc)
+				if err != nil {
+					yield(nil, err)
+					return
+				}
+
+				for _, d := range out {
+					if !yield(d, nil) {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+// collect 读取全部输入
+func collect(in iter.Seq2[Map, error]) ([]Map, error) {
+	var docs []Map
+
+	for doc, err := range in {
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+
+	return docs, nil
+}
+
+func compileMatchStage(spec Value) (pipelineStage, error) {
+	filter, ok := spec.(Map)
+	if !ok {
+		return nil, fmt.Errorf("expected Map, got %v", dataTypeOf(spec))
+	}
+
+	matcher, err := Compile(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapStage(func(doc Map) ([]Map, error) {
+		if matcher.Match(doc) {
+			return []Map{doc}, nil
+		}
+		return nil, nil
+	}), nil
+}
+
+func compileProjectStage(spec Value) (pipelineStage, error) {
+	m, ok := spec.(Map)
+	if !ok {
+		return nil, fmt.Errorf("expected Map, got %v", dataTypeOf(spec))
+	}
+
+	projection, err := CompileProjection(m)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapStage(func(doc Map) ([]Map, error) {
+		result, err := projection.Apply(doc)
+		if err != nil {
+			return nil, err
+		}
+		return []Map{result}, nil
+	}), nil
+}
+
+// compileAddFieldsStage 解析 Map{"total": Map{"$multiply": Array{String("$price"), String("$qty")}}}，
+// 键可以是点分路径，结果为 Null 的字段也会写入
+func compileAddFieldsStage(spec Value) (pipelineStage, error) {
+	m, ok := spec.(Map)
+	if !ok {
+		return nil, fmt.Errorf("expected Map, got %v", dataTypeOf(spec))
+	}
+
+	type field struct {
+		path string
+		expr exprFunc
+	}
+
+	fields := make([]field, 0, len(m))
+	for _, path := range sortedKeys(m) {
+		if _, err := splitPath(path); err != nil {
+			return nil, err
+		}
+		expr, err := compileExpr(m[path])
+		if err != nil {
+			return nil, fmt.Errorf("field %v: %w", path, err)
+		}
+		fields = append(fields, field{path: path, expr: expr})
+	}
+
+	return mapStage(func(doc Map) ([]Map, error) {
+		out := doc.Clone()
+		for _, f := range fields {
+			// 表达式在原文档上计算，互不影响
+			v, err := f.expr(doc)
+			if err != nil {
+				return nil, fmt.Errorf("field %v: %w", f.path, err)
+			}
+			if v == nil {
+				v = Null{}
+			}
+			if err := out.SetPath(f.path, v); err != nil {
+				return nil, err
+			}
+		}
+		return []Map{out}, nil
+	}), nil
+}
+
+func compileLimitStage(spec Value) (pipelineStage, error) {
+	n, ok := sliceInt(spec)
+	if !ok || n < 0 {
+		return nil, fmt.Errorf("expected non-negative integer, got %v", spec)
+	}
+
+	return func(in iter.Seq2[Map, error]) iter.Seq2[Map, error] {
+		return func(yield func(Map, error) bool) {
+			if n == 0 {
+				return
+			}
+			count := 0
+			for doc, err := range in {
+				if !yield(doc, err) || err != nil {
+					return
+				}
+				count++
+				if count >= n {
+					return
+				}
+			}
+		}
+	}, nil
+}
+
+func compileSkipStage(spec Value) (pipelineStage, error) {
+	n, ok := sliceInt(spec)
+	if !ok || n < 0 {
+		return nil, fmt.Errorf("expected non-negative integer, got %v", spec)
+	}
+
+	return func(in iter.Seq2[Map, error]) iter.Seq2[Map, error] {
+		return func(yield func(Map, error) bool) {
+			skipped := 0
+			for doc, err := range in {
+				if err == nil && skipped < n {
+					skipped++
+					continue
+				}
+				if !yield(doc, err) || err != nil {
+					return
+				}
+			}
+		}
+	}, nil
+}
+
+type sortKey struct {
+	segs []string
+	desc bool
+}
+
+// compileSortStage 解析 Map{"a": 1} 或 Array{Map{"a": 1}, Map{"b": -1}}
+func compileSortStage(spec Value) (pipelineStage, error) {
+	var specs []Map
+
+	switch x := spec.(type) {
+	case Map:
+		if len(x) != 1 {
+			return nil, errors.New("multiple sort keys must be given as an Array to keep their order")
+		}
+		specs = []Map{x}
+	case Array:
+		for _, item := range x {
+			m, ok := item.(Map)
+			if !ok || len(m) != 1 {
+				return nil, fmt.Errorf("expected Map with a single key, got %v", item)
+			}
+			specs = append(specs, m)
+		}
+	default:
+		return nil, fmt.Errorf("expected Map or Array, got %v", dataTypeOf(spec))
+	}
+
+	if len(specs) == 0 {
+		return nil, errors.New("empty sort specification")
+	}
+
+	keys := make([]sortKey, 0, len(specs))
+	for _, m := range specs {
+		for path, dir := range m {
+			segs, err := splitPath(path)
+			if err != nil {
+				return nil, err
+			}
+			n, ok := sliceInt(dir)
+			if !ok || (n != 1 && n != -1) {
+				return nil, fmt.Errorf("sort direction must be 1 or -1, got %v", dir)
+			}
+			keys = append(keys, sortKey{segs: segs, desc: n == -1})
+		}
+	}
+
+	return func(in iter.Seq2[Map, error]) iter.Seq2[Map, error] {
+		return func(yield func(Map, error) bool) {
+			docs, err := collect(in)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			slices.SortStableFunc(docs, func(a, b Map) int {
+				for _, key := range keys {
+					av, _ := lookupPath(a, key.segs)
+					bv, _ := lookupPath(b, key.segs)
+					if c := Compare(av, bv); c != 0 {
+						if key.desc {
+							return -c
+						}
+						return c
+					}
+				}
+				return 0
+			})
+
+			for _, doc := range docs {
+				if !yield(doc, nil) {
+					return
+				}
+			}
+		}
+	}, nil
+}
+
+// compileUnwindStage 解析 String("$path") 或
+// Map{"path": String("$path"), "includeArrayIndex": String("idx"), "preserveNullAndEmptyArrays": Bool(true)}
+func compileUnwindStage(spec Value) (pipelineStage, error) {
+	var path, indexField string
+	preserve := false
+
+	switch x := spec.(type) {
+	case String:
+		path = string(x)
+	case Map:
+		p, err := x.GetString("path")
+		if err != nil {
+			return nil, err
+		}
+		path = p
+		if v, has := x["includeArrayIndex"]; has {
+			s, ok := v.(String)
+			if !ok {
+				return nil, fmt.Errorf("includeArrayIndex expects String, got %v", dataTypeOf(v))
+			}
+			indexField = string(s)
+		}
+		if v, has := x["preserveNullAndEmptyArrays"]; has {
+			b, ok := v.(Bool)
+			if !ok {
+				return nil, fmt.Errorf("preserveNullAndEmptyArrays expects Bool, got %v", dataTypeOf(v))
+			}
+			preserve = bool(b)
+		}
+	default:
+		return nil, fmt.Errorf("expected String or Map, got %v", dataTypeOf(spec))
+	}
+
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("path must start with $, got %v", path)
+	}
+
+	segs, err := splitPath(path[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	return mapStage(func(doc Map) ([]Map, error) {
+		value, has := lookupPath(doc, segs)
+
+		arr, isArray := value.(Array)
+		if !has || isNullValue(value) || (isArray && len(arr) == 0) {
+			if !preserve {
+				return nil, nil
+			}
+			out := doc.Clone()
+			if indexField != "" {
+				out[indexField] = Null{}
+			}
+			return []Map{out}, nil
+		}
+
+		if !isArray {
+			arr = Array{value}
+		}
+
+		out := make([]Map, 0, len(arr))
+		for i, item := range arr {
+			d := doc.Clone()
+			if err := d.SetPath(path[1:], item); err != nil {
+				return nil, err
+			}
+			if indexField != "" {
+				d[indexField] = I64(i)
+			}
+			out = append(out, d)
+		}
+
+		return out, nil
+	}), nil
+}
@@ -0,0 +1,366 @@
+package nson
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// $group 阶段：
+//
+//	Map{"$group": Map{
+//		"_id":   String("$category"),             // 分组键，可以是任意表达式
+//		"total": Map{"$sum": String("$amount")},
+//		"count": Map{"$count": Map{}},
+//	}}
+//
+// 输出文档包含 "_id" 和各个累加器的结果，按分组首次出现的顺序输出。
+
+type accumulator interface {
+	add(v Value, has bool) error
+	result() Value
+}
+
+type accumulatorSpec struct {
+	field   string
+	newAcc  func() accumulator
+	operand exprFunc
+}
+
+func compileGroupStage(spec Value) (pipelineStage, error) {
+	m, ok := spec.(Map)
+	if !ok {
+		return nil, fmt.Errorf("expected Map, got %v", dataTypeOf(spec))
+	}
+
+	id, has := m["_id"]
+	if !has {
+		return nil, errors.New("missing _id")
+	}
+
+	idExpr, err := compileExpr(id)
+	if err != nil {
+		return nil, fmt.Errorf("_id: %w", err)
+	}
+
+	var specs []accumulatorSpec
+
+	for _, field := range sortedKeys(m) {
+		if field == "_id" {
+			continue
+		}
+
+		acc, ok := m[field].(Map)
+		if !ok || len(acc) != 1 {
+			return nil, fmt.Errorf("field %v: expected accumulator Map with a single key, got %v", field, m[field])
+		}
+
+		for name, operand := range acc {
+			newAcc, has := accumulators[name]
+			if !has {
+				return nil, fmt.Errorf("field %v: unknown accumulator %v", field, name)
+			}
+			expr, err := compileExpr(operand)
+			if err != nil {
+				return nil, fmt.Errorf("field %v: %w", field, err)
+			}
+			specs = append(specs, accumulatorSpec{field: field, newAcc: newAcc, operand: expr})
+		}
+	}
+
+	return func(in iter.Seq2[Map, error]) iter.Seq2[Map, error] {
+		return func(yield func(Map, error) bool) {
+			type group struct {
+				id   Value
+				accs []accumulator
+			}
+
+			var groups []*group
+			index := make(map[string]*group)
+
+			for doc, err := range in {
+				if err != nil {
+					yield(nil, err)
+					return
+				}
+
+				key, err := idExpr(doc)
+				if err != nil {
+					yield(nil, fmt.Errorf("_id: %w", err))
+					return
+				}
+				if key == nil {
+					key = Null{}
+				}
+
+				k := groupKey(key)
+				g, has := index[k]
+				if !has {
+					g = &group{id: key, accs: make([]accumulator, len(specs))}
+					for i, s := range specs {
+						g.accs[i] = s.newAcc()
+					}
+					groups = append(groups, g)
+					index[k] = g
+				}
+
+				for i, s := range specs {
+					v, err := s.operand(doc)
+					if err != nil {
+						yield(nil, fmt.Errorf("field %v: %w", s.field, err))
+						return
+					}
+					if err := g.accs[i].add(v, v != nil); err != nil {
+						yield(nil, fmt.Errorf("field %v: %w", s.field, err))
+						return
+					}
+				}
+			}
+
+			for _, g := range groups {
+				out := Map{"_id": Clone(g.id)}
+				for i, s := range specs {
+					out[s.field] = g.accs[i].result()
+				}
+				if !yield(out, nil) {
+					return
+				}
+			}
+		}
+	}, nil
+}
+
+// groupKey 生成分组键的规范字符串，EqualNumeric 相等的值生成相同的字符串
+func groupKey(v Value) string {
+	var b strings.Builder
+	writeGroupKey(&b, v)
+	return b.String()
+}
+
+func writeGroupKey(b *strings.Builder, v Value) {
+	if n, ok := toNumber(v); ok {
+		switch {
+		case n.kind == numberInt:
+			b.WriteString("n" + strconv.FormatInt(n.i, 10))
+		case n.kind == numberUint:
+			b.WriteString("n" + strconv.FormatUint(n.u, 10))
+		case n.f == math.Trunc(n.f) && !math.IsInf(n.f, 0):
+			i, _ := big.NewFloat(n.f).Int(nil)
+			b.WriteString("n" + i.String())
+		default:
+			b.WriteString("f" + strconv.FormatFloat(n.f, 'g', -1, 64))
+		}
+		return
+	}
+
+	switch x := v.(type) {
+	case Array:
+		b.WriteString("[")
+		for _, item := range x {
+			writeGroupKey(b, item)
+			b.WriteString(",")
+		}
+		b.WriteString("]")
+	case Map:
+		b.WriteString("{")
+		for _, k := range sortedKeys(x) {
+			b.WriteString(strconv.Quote(k) + ":")
+			writeGroupKey(b, x[k])
+			b.WriteString(",")
+		}
+		b.WriteString("}")
+	case String:
+		b.WriteString("s" + strconv.Quote(string(x)))
+	case nil:
+		b.WriteString("Null")
+	default:
+		// 其他类型的 String() 已经包含类型名称
+		b.WriteString(x.String())
+	}
+}
+
+var accumulators = map[string]func() accumulator{
+	"$sum":      func() accumulator { return &sumAccumulator{sum: I32(0)} },
+	"$avg":      func() accumulator { return &avgAccumulator{} },
+	"$min":      func() accumulator { return &minMaxAccumulator{sign: -1} },
+	"$max":      func() accumulator { return &minMaxAccumulator{sign: 1} },
+	"$count":    func() accumulator { return &countAccumulator{} },
+	"$push":     func() accumulator { return &pushAccumulator{arr: Array{}} },
+	"$addToSet": func() accumulator { return &pushAccumulator{arr: Array{}, unique: true} },
+	"$first":    func() accumulator { return &firstLastAccumulator{first: true} },
+	"$last":     func() accumulator { return &firstLastAccumulator{} },
+}
+
+// sumAccumulator 累加数值，忽略非数值。整数溢出时先提升为 I64，再提升为 F64
+type sumAccumulator struct {
+	sum Value
+}
+
+func (self *sumAccumulator) add(v Value, has bool) error {
+	if _, ok := toNumber(v); !has || !ok {
+		return nil
+	}
+
+	sum, err := arith(arithAdd, self.sum, v)
+	if err == nil {
+		self.sum = sum
+		return nil
+	}
+
+	if a, ok := toI64(self.sum); ok {
+		if b, ok := toI64(v); ok {
+			if sum, err := arith(arithAdd, a, b); err == nil {
+				self.sum = sum
+				return nil
+			}
+		}
+	}
+
+	x, _ := toNumber(self.sum)
+	y, _ := toNumber(v)
+	self.sum = F64(x.float64() + y.float64())
+
+	return nil
+}
+
+func (self *sumAccumulator) result() Value {
+	return self.sum
+}
+
+// toI64 将整数转换为 I64，超出范围或非整数返回 false
+func toI64(v Value) (Value, bool) {
+	n, ok := toNumber(v)
+	if !ok {
+		return nil, false
+	}
+
+	switch n.kind {
+	case numberInt:
+		return I64(n.i), true
+	case numberUint:
+		if n.u > 1<<63-1 {
+			return nil, false
+		}
+		return I64(n.u), true
+	default:
+		return nil, false
+	}
+}
+
+// avgAccumulator 计算数值的平均值，结果为 F64，没有数值时为 Null
+type avgAccumulator struct {
+	sum   float64
+	count int
+}
+
+func (self *avgAccumulator) add(v Value, has bool) error {
+	if n, ok := toNumber(v); has && ok {
+		self.sum += n.float64()
+		self.count++
+	}
+
+	return nil
+}
+
+func (self *avgAccumulator) result() Value {
+	if self.count == 0 {
+		return Null{}
+	}
+
+	return F64(self.sum / float64(self.count))
+}
+
+// minMaxAccumulator 按 Compare 的顺序取最小或最大值，忽略 Null 和不存在的字段
+type minMaxAccumulator struct {
+	value Value
+	sign  int
+}
+
+func (self *minMaxAccumulator) add(v Value, has bool) error {
+	if !has || isNullValue(v) {
+		return nil
+	}
+
+	if self.value == nil || Compare(v, self.value)*self.sign > 0 {
+		self.value = v
+	}
+
+	return nil
+}
+
+func (self *minMaxAccumulator) result() Value {
+	if self.value == nil {
+		return Null{}
+	}
+
+	return Clone(self.value)
+}
+
+type countAccumulator struct {
+	count int64
+}
+
+func (self *countAccumulator) add(v Value, has bool) error {
+	self.count++
+	return nil
+}
+
+func (self *countAccumulator) result() Value {
+	return I64(self.count)
+}
+
+// pushAccumulator 收集值到 Array，unique 时去掉重复的值
+type pushAccumulator struct {
+	arr    Array
+	unique bool
+}
+
+func (self *pushAccumulator) add(v Value, has bool) error {
+	if !has {
+		return nil
+	}
+
+	if self.unique && arrayContains(self.arr, v) {
+		return nil
+	}
+
+	self.arr = append(self.arr, Clone(v))
+	return nil
+}
+
+func (self *pushAccumulator) result() Value {
+	return self.arr
+}
+
+type firstLastAccumulator struct {
+	value Value
+	seen  bool
+	first bool
+}
+
+func (self *firstLastAccumulator) add(v Value, has bool) error {
+	if self.first && self.seen {
+		return nil
+	}
+
+	self.seen = true
+	if has {
+		self.value = v
+	} else {
+		self.value = nil
+	}
+
+	return nil
+}
+
+func (self *firstLastAccumulator) result() Value {
+	if self.value == nil {
+		return Null{}
+	}
+
+	return Clone(self.value)
+}
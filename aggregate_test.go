@@ -0,0 +1,206 @@
+package nson
+
+import (
+	"bytes"
+	"math"
+	"slices"
+	"testing"
+)
+
+func salesDocs() []Map {
+	return []Map{
+		{"region": String("east"), "amount": I32(10), "items": Array{String("a"), String("b")}},
+		{"region": String("west"), "amount": I64(20), "items": Array{String("b")}},
+		{"region": String("east"), "amount": F64(5.5), "items": Array{}},
+		{"region": String("north"), "amount": U8(1)},
+		{"region": String("west"), "amount": I32(30), "items": Array{String("c")}},
+	}
+}
+
+func TestAggregateGroupSort(t *testing.T) {
+	pipeline := Array{
+		Map{"$match": Map{"amount": Map{"$gt": I32(1)}}},
+		Map{"$group": Map{
+			"_id":     String("$region"),
+			"total":   Map{"$sum": String("$amount")},
+			"avg":     Map{"$avg": String("$amount")},
+			"min":     Map{"$min": String("$amount")},
+			"max":     Map{"$max": String("$amount")},
+			"count":   Map{"$count": Map{}},
+			"amounts": Map{"$push": String("$amount")},
+		}},
+		Map{"$sort": Map{"total": I32(-1)}},
+	}
+
+	result, err := Aggregate(salesDocs(), pipeline)
+	if err != nil {
+		t.Fatalf("Aggregate failed: %v", err)
+	}
+
+	if len(result) != 2 {
+		t.Fatalf("Expected 2 groups, got %v", result)
+	}
+
+	west := result[0]
+	if west["_id"] != String("west") || west["total"] != I64(50) || west["count"] != I64(2) {
+		t.Errorf("Unexpected west group: %v", west)
+	}
+	if west["avg"] != F64(25) || west["min"] != I64(20) || west["max"] != I32(30) {
+		t.Errorf("Unexpected west group: %v", west)
+	}
+	if !Equal(west["amounts"], Array{I64(20), I32(30)}) {
+		t.Errorf("Unexpected west amounts: %v", west["amounts"])
+	}
+
+	east := result[1]
+	if east["_id"] != String("east") || east["total"] != F64(15.5) {
+		t.Errorf("Unexpected east group: %v", east)
+	}
+}
+
+func TestAggregateUnwindSkipLimit(t *testing.T) {
+	pipeline := Array{
+		Map{"$unwind": String("$items")},
+		Map{"$group": Map{"_id": String("$items"), "n": Map{"$sum": I32(1)}}},
+		Map{"$sort": Array{Map{"n": I32(-1)}, Map{"_id": I32(1)}}},
+		Map{"$skip": I32(1)},
+		Map{"$limit": I32(1)},
+	}
+
+	result, err := Aggregate(salesDocs(), pipeline)
+	if err != nil {
+		t.Fatalf("Aggregate failed: %v", err)
+	}
+
+	want := []Map{{"_id": String("a"), "n": I32(1)}}
+	if !slices.EqualFunc(result, want, func(a, b Map) bool { return Equal(a, b) }) {
+		t.Errorf("Expected %v, got %v", want, result)
+	}
+}
+
+func TestAggregateUnwindOptions(t *testing.T) {
+	pipeline := Array{
+		Map{"$unwind": Map{
+			"path":                       String("$items"),
+			"includeArrayIndex":          String("idx"),
+			"preserveNullAndEmptyArrays": Bool(true),
+		}},
+		Map{"$project": Map{"region": I32(1), "items": I32(1), "idx": I32(1)}},
+	}
+
+	result, err := Aggregate(salesDocs(), pipeline)
+	if err != nil {
+		t.Fatalf("Aggregate failed: %v", err)
+	}
+
+	if len(result) != 6 {
+		t.Fatalf("Expected 6 documents, got %d: %v", len(result), result)
+	}
+	if !Equal(result[1], Map{"region": String("east"), "items": String("b"), "idx": I64(1)}) {
+		t.Errorf("Unexpected document: %v", result[1])
+	}
+	if !Equal(result[3], Map{"region": String("east"), "items": Array{}, "idx": Null{}}) {
+		t.Errorf("Unexpected document: %v", result[3])
+	}
+}
+
+func TestAggregateGroupKeys(t *testing.T) {
+	docs := []Map{
+		{"k": I32(1), "v": I32(1)},
+		{"k": F64(1), "v": I32(2)},
+		{"k": U64(1), "v": I32(3)},
+		{"v": I32(4)},
+		{"k": Null{}, "v": I32(5)},
+	}
+
+	result, err := Aggregate(docs, Array{
+		Map{"$group": Map{"_id": Map{"key": String("$k")}, "first": Map{"$first": String("$v")}, "last": Map{"$last": String("$v")}}},
+	})
+	if err != nil {
+		t.Fatalf("Aggregate failed: %v", err)
+	}
+
+	if len(result) != 3 {
+		t.Fatalf("Expected 3 groups, got %v", result)
+	}
+	if result[0]["first"] != I32(1) || result[0]["last"] != I32(3) {
+		t.Errorf("Expected numeric keys to group together, got %v", result[0])
+	}
+
+	all, err := Aggregate(docs, Array{Map{"$group": Map{"_id": Null{}, "vs": Map{"$addToSet": String("$k")}}}})
+	if err != nil {
+		t.Fatalf("Aggregate failed: %v", err)
+	}
+	if len(all) != 1 || len(all[0]["vs"].(Array)) != 4 {
+		t.Errorf("Unexpected result: %v", all)
+	}
+}
+
+func TestAggregateSumOverflow(t *testing.T) {
+	docs := []Map{{"v": I32(math.MaxInt32)}, {"v": I32(1)}, {"v": I64(math.MaxInt64)}}
+
+	result, err := Aggregate(docs, Array{Map{"$group": Map{"_id": Null{}, "s": Map{"$sum": String("$v")}}}})
+	if err != nil {
+		t.Fatalf("Aggregate failed: %v", err)
+	}
+
+	if _, ok := result[0]["s"].(F64); !ok {
+		t.Errorf("Expected sum to be promoted to F64, got %v", result[0]["s"])
+	}
+}
+
+func TestPipelineEncoding(t *testing.T) {
+	pipeline := Array{
+		Map{"$match": Map{"region": String("west")}},
+		Map{"$group": Map{"_id": String("$region"), "total": Map{"$sum": String("$amount")}}},
+	}
+
+	buf := new(bytes.Buffer)
+	if err := EncodeArray(pipeline, buf); err != nil {
+		t.Fatalf("EncodeArray failed: %v", err)
+	}
+
+	decoded, err := DecodeArray(buf)
+	if err != nil {
+		t.Fatalf("DecodeArray failed: %v", err)
+	}
+
+	p, err := CompilePipeline(decoded)
+	if err != nil {
+		t.Fatalf("CompilePipeline failed: %v", err)
+	}
+
+	var out []Map
+	for doc, err := range p.Iter(slices.Values(salesDocs())) {
+		if err != nil {
+			t.Fatalf("Iter failed: %v", err)
+		}
+		out = append(out, doc)
+	}
+
+	if len(out) != 1 || out[0]["total"] != I64(50) {
+		t.Errorf("Unexpected result: %v", out)
+	}
+}
+
+func TestCompilePipelineErrors(t *testing.T) {
+	pipelines := []Array{
+		{I32(1)},
+		{Map{"$bogus": Map{}}},
+		{Map{"$match": Map{}, "$limit": I32(1)}},
+		{Map{"$limit": I32(-1)}},
+		{Map{"$skip": String("x")}},
+		{Map{"$sort": Map{"a": I32(1), "b": I32(1)}}},
+		{Map{"$sort": Map{"a": I32(2)}}},
+		{Map{"$group": Map{"total": Map{"$sum": I32(1)}}}},
+		{Map{"$group": Map{"_id": Null{}, "total": Map{"$median": I32(1)}}}},
+		{Map{"$unwind": String("items")}},
+		{Map{"$match": Map{"a": Map{"$bogus": I32(1)}}}},
+	}
+
+	for _, pipeline := range pipelines {
+		if _, err := CompilePipeline(pipeline); err == nil {
+			t.Errorf("Expected error for %v", pipeline)
+		}
+	}
+}
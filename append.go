@@ -0,0 +1,131 @@
+package nson
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// 以下函数直接向 []byte 追加编码结果，输出与 EncodeValue、EncodeMap 相同，
+// 主要供 nsongen -marshal 生成的 AppendNSON 方法使用：
+//
+//	buf, start := AppendMapStart(buf)
+//	buf, err = AppendKey(buf, "name")
+//	buf = AppendString(buf, "Alice")
+//	buf = AppendMapEnd(buf, start)
+//
+// AppendXxx 写入的值带有类型标记，可以直接作为 Map 的值或 Array 的元素。
+
+// AppendMapStart 写入 Map 长度的占位，返回长度的位置，写完字段后调用 AppendMapEnd
+func AppendMapStart(buf []byte) ([]byte, int) {
+	return append(buf, 0, 0, 0, 0), len(buf)
+}
+
+// AppendMapEnd 写入 Map 的结束标记并回填长度
+func AppendMapEnd(buf []byte, start int) []byte {
+	buf = append(buf, 0x00)
+	binary.LittleEndian.PutUint32(buf[start:], uint32(len(buf)-start))
+	return buf
+}
+
+// AppendArrayStart 写入 Array 长度的占位，返回长度的位置，写完元素后调用 AppendArrayEnd
+func AppendArrayStart(buf []byte) ([]byte, int) {
+	return AppendMapStart(buf)
+}
+
+// AppendArrayEnd 写入 Array 的结束标记并回填长度
+func AppendArrayEnd(buf []byte, start int) []byte {
+	return AppendMapEnd(buf, start)
+}
+
+// AppendKey 写入 Map 的键
+func AppendKey(buf []byte, key string) ([]byte, error) {
+	if len(key) == 0 || len(key) >= 255 {
+		return buf, errors.New("Key len must > 0 and < 255")
+	}
+
+	buf = append(buf, uint8(len(key)+1))
+	return append(buf, key...), nil
+}
+
+func AppendBool(buf []byte, v Bool) []byte {
+	if v {
+		return append(buf, byte(DataTypeBOOL), 0x01)
+	}
+
+	return append(buf, byte(DataTypeBOOL), 0x00)
+}
+
+func AppendNull(buf []byte) []byte {
+	return append(buf, byte(DataTypeNULL))
+}
+
+func AppendI8(buf []byte, v I8) []byte {
+	return append(buf, byte(DataTypeI8), byte(v))
+}
+
+func AppendU8(buf []byte, v U8) []byte {
+	return append(buf, byte(DataTypeU8), byte(v))
+}
+
+func AppendI16(buf []byte, v I16) []byte {
+	return binary.LittleEndian.AppendUint16(append(buf, byte(DataTypeI16)), uint16(v))
+}
+
+func AppendU16(buf []byte, v U16) []byte {
+	return binary.LittleEndian.AppendUint16(append(buf, byte(DataTypeU16)), uint16(v))
+}
+
+func AppendI32(buf []byte, v I32) []byte {
+	return binary.LittleEndian.AppendUint32(append(buf, byte(DataTypeI32)), uint32(v))
+}
+
+func AppendU32(buf []byte, v U32) []byte {
+	return binary.LittleEndian.AppendUint32(append(buf, byte(DataTypeU32)), uint32(v))
+}
+
+func AppendI64(buf []byte, v I64) []byte {
+	return binary.LittleEndian.AppendUint64(append(buf, byte(DataTypeI64)), uint64(v))
+}
+
+func AppendU64(buf []byte, v U64) []byte {
+	return binary.LittleEndian.AppendUint64(append(buf, byte(DataTypeU64)), uint64(v))
+}
+
+func AppendF32(buf []byte, v F32) []byte {
+	return binary.LittleEndian.AppendUint32(append(buf, byte(DataTypeF32)), math.Float32bits(float32(v)))
+}
+
+func AppendF64(buf []byte, v F64) []byte {
+	return binary.LittleEndian.AppendUint64(append(buf, byte(DataTypeF64)), math.Float64bits(float64(v)))
+}
+
+func AppendTimestamp(buf []byte, v Timestamp) []byte {
+	return binary.LittleEndian.AppendUint64(append(buf, byte(DataTypeTIMESTAMP)), uint64(v))
+}
+
+func AppendId(buf []byte, v Id) []byte {
+	return append(append(buf, byte(DataTypeID)), v[:]...)
+}
+
+func AppendString(buf []byte, v String) []byte {
+	buf = binary.LittleEndian.AppendUint32(append(buf, byte(DataTypeSTRING)), uint32(len(v)+4))
+	return append(buf, v...)
+}
+
+func AppendBinary(buf []byte, v Binary) []byte {
+	buf = binary.LittleEndian.AppendUint32(append(buf, byte(DataTypeBINARY)), uint32(len(v)+4))
+	return append(buf, v...)
+}
+
+// AppendValue 写入任意值
+func AppendValue(buf []byte, v Value) ([]byte, error) {
+	b := bytes.NewBuffer(buf)
+
+	if err := EncodeValue(b, v); err != nil {
+		return buf, err
+	}
+
+	return b.Bytes(), nil
+}
@@ -0,0 +1,241 @@
+package nson
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// 数值运算的类型提升规则：
+//
+//   - 同类型运算结果仍为该类型，如 I32 + I32 = I32，溢出时返回错误
+//   - 有符号整数之间、无符号整数之间取较宽的类型，如 I8 + I32 = I32
+//   - 有符号与无符号整数取能同时容纳二者的有符号类型，如 I8 + U8 = I16，
+//     U32 + I32 = I64，最宽为 I64
+//   - 只要有浮点数结果即为浮点数，F32 与 F32 为 F32，其他情况为 F64
+
+// PromoteTypes 返回两个数值类型运算后的结果类型，非数值类型返回 false
+func PromoteTypes(a, b DataType) (DataType, bool) {
+	if !a.IsNumeric() || !b.IsNumeric() {
+		return 0, false
+	}
+
+	if a == b {
+		return a, true
+	}
+
+	if isFloatType(a) || isFloatType(b) {
+		if a == DataTypeF32 && b == DataTypeF32 {
+			return DataTypeF32, true
+		}
+		return DataTypeF64, true
+	}
+
+	as, bs := isSignedType(a), isSignedType(b)
+	aw, bw := a.Size(), b.Size()
+
+	switch {
+	case as && bs:
+		return signedType(max(aw, bw)), true
+	case !as && !bs:
+		return unsignedType(max(aw, bw)), true
+	case as:
+		return signedType(min(max(aw, bw*2), 8)), true
+	default:
+		return signedType(min(max(bw, aw*2), 8)), true
+	}
+}
+
+func isFloatType(dt DataType) bool {
+	return dt == DataTypeF32 || dt == DataTypeF64
+}
+
+func isSignedType(dt DataType) bool {
+	switch dt {
+	case DataTypeI8, DataTypeI16, DataTypeI32, DataTypeI64:
+		return true
+	default:
+		return false
+	}
+}
+
+func signedType(size int) DataType {
+	switch size {
+	case 1:
+		return DataTypeI8
+	case 2:
+		return DataTypeI16
+	case 4:
+		return DataTypeI32
+	default:
+		return DataTypeI64
+	}
+}
+
+func unsignedType(size int) DataType {
+	switch size {
+	case 1:
+		return DataTypeU8
+	case 2:
+		return DataTypeU16
+	case 4:
+		return DataTypeU32
+	default:
+		return DataTypeU64
+	}
+}
+
+type arithOp uint8
+
+const (
+	arithAdd arithOp = iota
+	arithSub
+	arithMul
+)
+
+func (self arithOp) String() string {
+	switch self {
+	case arithAdd:
+		return "+"
+	case arithSub:
+		return "-"
+	default:
+		return "*"
+	}
+}
+
+func (self arithOp) float(f, g float64) float64 {
+	switch self {
+	case arithAdd:
+		return f + g
+	case arithSub:
+		return f - g
+	default:
+		return f * g
+	}
+}
+
+func (self arithOp) int(x, y *big.Int) *big.Int {
+	switch self {
+	case arithAdd:
+		return x.Add(x, y)
+	case arithSub:
+		return x.Sub(x, y)
+	default:
+		return x.Mul(x, y)
+	}
+}
+
+// arith 按类型提升规则计算 a op b，整数结果超出类型范围时返回错误
+func arith(op arithOp, a, b Value) (Value, error) {
+	x, ok := toNumber(a)
+	if !ok {
+		return nil, fmt.Errorf("Unexpected Type, expected number, got %v", dataTypeOf(a))
+	}
+
+	y, ok := toNumber(b)
+	if !ok {
+		return nil, fmt.Errorf("Unexpected Type, expected number, got %v", dataTypeOf(b))
+	}
+
+	dt, _ := PromoteTypes(a.DataType(), b.DataType())
+
+	if isFloatType(dt) {
+		r := op.float(x.float64(), y.float64())
+		if dt == DataTypeF32 {
+			return F32(r), nil
+		}
+		return F64(r), nil
+	}
+
+	v, ok := intValue(op.int(x.bigInt(), y.bigInt()), dt)
+	if !ok {
+		return nil, fmt.Errorf("Overflow: %v %v %v does not fit in %v", a, op, b, dt)
+	}
+
+	return v, nil
+}
+
+// arithInPlace 计算 a op b，结果保持 a 的类型，用于 $inc 和 $mul 修改已有的字段。
+// 整数结果超出范围或不是整数时返回错误，浮点数按 a 的精度舍入
+func arithInPlace(op arithOp, a, b Value) (Value, error) {
+	x, ok := toNumber(a)
+	if !ok {
+		return nil, fmt.Errorf("Unexpected Type, expected number, got %v", dataTypeOf(a))
+	}
+
+	y, ok := toNumber(b)
+	if !ok {
+		return nil, fmt.Errorf("Unexpected Type, expected number, got %v", dataTypeOf(b))
+	}
+
+	dt := a.DataType()
+
+	if isFloatType(dt) || y.kind == numberFloat {
+		r := op.float(x.float64(), y.float64())
+		switch dt {
+		case DataTypeF32:
+			return F32(r), nil
+		case DataTypeF64:
+			return F64(r), nil
+		}
+		if math.IsNaN(r) || math.IsInf(r, 0) || math.Trunc(r) != r {
+			return nil, fmt.Errorf("%v %v %v cannot be represented exactly as %v", a, op, b, dt)
+		}
+		v, err := convertNumber(number{kind: numberFloat, f: r}, F64(r), dt)
+		if err != nil {
+			return nil, fmt.Errorf("Overflow: %v %v %v does not fit in %v", a, op, b, dt)
+		}
+		return v, nil
+	}
+
+	v, ok := intValue(op.int(x.bigInt(), y.bigInt()), dt)
+	if !ok {
+		return nil, fmt.Errorf("Overflow: %v %v %v does not fit in %v", a, op, b, dt)
+	}
+
+	return v, nil
+}
+
+func (self number) bigInt() *big.Int {
+	if self.kind == numberUint {
+		return new(big.Int).SetUint64(self.u)
+	}
+
+	return big.NewInt(self.i)
+}
+
+// intValue 将整数转换为 dt 类型，超出范围返回 false
+func intValue(r *big.Int, dt DataType) (Value, bool) {
+	if isSignedType(dt) {
+		if !r.IsInt64() {
+			return nil, false
+		}
+		i := r.Int64()
+		switch dt {
+		case DataTypeI8:
+			return I8(i), i >= math.MinInt8 && i <= math.MaxInt8
+		case DataTypeI16:
+			return I16(i), i >= math.MinInt16 && i <= math.MaxInt16
+		case DataTypeI32:
+			return I32(i), i >= math.MinInt32 && i <= math.MaxInt32
+		default:
+			return I64(i), true
+		}
+	}
+
+	if !r.IsUint64() {
+		return nil, false
+	}
+	u := r.Uint64()
+	switch dt {
+	case DataTypeU8:
+		return U8(u), u <= math.MaxUint8
+	case DataTypeU16:
+		return U16(u), u <= math.MaxUint16
+	case DataTypeU32:
+		return U32(u), u <= math.MaxUint32
+	default:
+		return U64(u), true
+	}
+}
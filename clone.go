@@ -0,0 +1,92 @@
+package nson
+
+// Clone 深拷贝一个值，Map、Array 和 Binary 都会复制底层存储，
+// 返回值与原值之间不共享任何可变数据。
+func Clone(v Value) Value {
+	switch x := v.(type) {
+	case Map:
+		return x.Clone()
+	case Array:
+		return x.Clone()
+	case Binary:
+		return x.Clone()
+	default:
+		// 其他类型都是值类型，直接复制即可
+		return v
+	}
+}
+
+// ShallowClone 浅拷贝一个值，只复制最外层的 Map、Array 或 Binary，
+// 内部的容器仍与原值共享。
+func ShallowClone(v Value) Value {
+	switch x := v.(type) {
+	case Map:
+		return x.ShallowClone()
+	case Array:
+		return x.ShallowClone()
+	case Binary:
+		return x.Clone()
+	default:
+		return v
+	}
+}
+
+// Clone 深拷贝 Map
+func (self Map) Clone() Map {
+	if self == nil {
+		return nil
+	}
+
+	m := make(Map, len(self))
+	for k, v := range self {
+		m[k] = Clone(v)
+	}
+
+	return m
+}
+
+// ShallowClone 浅拷贝 Map，值不会被复制
+func (self Map) ShallowClone() Map {
+	if self == nil {
+		return nil
+	}
+
+	m := make(Map, len(self))
+	for k, v := range self {
+		m[k] = v
+	}
+
+	return m
+}
+
+// Clone 深拷贝 Array
+func (self Array) Clone() Array {
+	if self == nil {
+		return nil
+	}
+
+	a := make(Array, len(self))
+	for i, v := range self {
+		a[i] = Clone(v)
+	}
+
+	return a
+}
+
+// ShallowClone 浅拷贝 Array，元素不会被复制
+func (self Array) ShallowClone() Array {
+	if self == nil {
+		return nil
+	}
+
+	return append(make(Array, 0, len(self)), self...)
+}
+
+// Clone 复制 Binary 的字节
+func (self Binary) Clone() Binary {
+	if self == nil {
+		return nil
+	}
+
+	return append(make(Binary, 0, len(self)), self...)
+}
@@ -0,0 +1,74 @@
+package nson
+
+import (
+	"testing"
+)
+
+func TestClone(t *testing.T) {
+	original := Map{
+		"name": String("device"),
+		"data": Binary{1, 2, 3},
+		"list": Array{Map{"a": I32(1)}, Binary{4}},
+		"sub":  Map{"b": Array{I32(2)}},
+	}
+
+	cloned := original.Clone()
+	if !Equal(original, cloned) {
+		t.Fatalf("Expected clone to be equal, got %v", cloned)
+	}
+
+	cloned["data"].(Binary)[0] = 9
+	cloned["list"].(Array)[0].(Map)["a"] = I32(9)
+	cloned["list"].(Array)[1].(Binary)[0] = 9
+	cloned["sub"].(Map)["b"].(Array)[0] = I32(9)
+	cloned["sub"].(Map)["c"] = Null{}
+
+	if original["data"].(Binary)[0] != 1 {
+		t.Error("Binary shares storage with clone")
+	}
+	if original["list"].(Array)[0].(Map)["a"] != I32(1) {
+		t.Error("Nested Map shares storage with clone")
+	}
+	if original["list"].(Array)[1].(Binary)[0] != 4 {
+		t.Error("Binary inside Array shares storage with clone")
+	}
+	if original["sub"].(Map)["b"].(Array)[0] != I32(2) {
+		t.Error("Nested Array shares storage with clone")
+	}
+	if _, has := original["sub"].(Map)["c"]; has {
+		t.Error("Nested Map shares storage with clone")
+	}
+}
+
+func TestShallowClone(t *testing.T) {
+	original := Map{
+		"a":   I32(1),
+		"sub": Map{"b": I32(2)},
+	}
+
+	cloned := ShallowClone(original).(Map)
+	cloned["a"] = I32(9)
+	cloned["sub"].(Map)["b"] = I32(9)
+
+	if original["a"] != I32(1) {
+		t.Error("Top level should not be shared")
+	}
+	if original["sub"].(Map)["b"] != I32(9) {
+		t.Error("Nested Map should be shared")
+	}
+}
+
+func TestCloneNil(t *testing.T) {
+	var m Map
+	if m.Clone() != nil {
+		t.Error("Expected nil Map clone to be nil")
+	}
+
+	if Clone(nil) != nil {
+		t.Error("Expected Clone(nil) to be nil")
+	}
+
+	if Clone(I32(1)) != I32(1) {
+		t.Error("Expected scalar clone to be equal")
+	}
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/danclive/nson-go"
+)
+
+// generator 根据 Schema 生成 Go 类型
+type generator struct {
+	pkg     string
+	types   []*goType
+	names   map[string]bool
+	imports map[string]bool
+}
+
+type goType struct {
+	name   string
+	doc    string
+	fields []goField
+}
+
+type goField struct {
+	name string
+	typ  string
+	tag  string
+	doc  string
+}
+
+// generate 生成以 name 为根类型的 Go 源码，根 Schema 必须描述带字段的 Map
+func generate(schema *nson.Schema, name, pkg string) ([]byte, error) {
+	g := &generator{
+		pkg:     pkg,
+		names:   map[string]bool{},
+		imports: map[string]bool{},
+	}
+
+	if !slices.Equal(nonNullTypes(schema), []nson.DataType{nson.DataTypeMAP}) || schema.Fields == nil {
+		return nil, fmt.Errorf("root schema must be a Map with fields")
+	}
+
+	if _, err := g.structType(schema, name); err != nil {
+		return nil, err
+	}
+
+	return g.source()
+}
+
+// structType 为带字段的 Map 生成结构体，返回类型名称
+func (g *generator) structType(schema *nson.Schema, hint string) (string, error) {
+	name := g.uniqueName(hint)
+
+	t := &goType{name: name, doc: schema.Description}
+	g.types = append(g.types, t)
+
+	used := map[string]bool{}
+
+	for _, key := range sortedFields(schema.Fields) {
+		field := schema.Fields[key]
+
+		fieldName := goName(key)
+		for i := 2; used[fieldName]; i++ {
+			fieldName = fmt.Sprintf("%v%d", goName(key), i)
+		}
+		used[fieldName] = true
+
+		typ, ref, err := g.valueType(field, name+fieldName)
+		if err != nil {
+			return "", fmt.Errorf("field %v: %w", key, err)
+		}
+
+		optional := !field.Required
+		if (optional || slices.Contains(field.Types, nson.DataTypeNULL)) && !ref {
+			typ = "*" + typ
+		}
+
+		tag := key
+		if optional {
+			tag += ",omitempty"
+		}
+
+		t.fields = append(t.fields, goField{
+			name: fieldName,
+			typ:  typ,
+			tag:  fmt.Sprintf("`nson:%q`", tag),
+			doc:  field.Description,
+		})
+	}
+
+	return name, nil
+}
+
+// valueType 返回值的 Go 类型，ref 表示类型本身可以为 nil，不需要再加指针
+func (g *generator) valueType(schema *nson.Schema, hint string) (typ string, ref bool, err error) {
+	types := nonNullTypes(schema)
+
+	if len(types) != 1 {
+		// 任意类型或联合类型
+		g.imports["nson"] = true
+		return "nson.Value", true, nil
+	}
+
+	switch dt := types[0]; dt {
+	case nson.DataTypeBOOL:
+		return "bool", false, nil
+	case nson.DataTypeI8:
+		return "int8", false, nil
+	case nson.DataTypeI16:
+		return "int16", false, nil
+	case nson.DataTypeI32:
+		return "int32", false, nil
+	case nson.DataTypeI64:
+		return "int64", false, nil
+	case nson.DataTypeU8:
+		return "uint8", false, nil
+	case nson.DataTypeU16:
+		return "uint16", false, nil
+	case nson.DataTypeU32:
+		return "uint32", false, nil
+	case nson.DataTypeU64:
+		return "uint64", false, nil
+	case nson.DataTypeF32:
+		return "float32", false, nil
+	case nson.DataTypeF64:
+		return "float64", false, nil
+	case nson.DataTypeSTRING:
+		return "string", false, nil
+	case nson.DataTypeBINARY:
+		return "[]byte", true, nil
+
+	case nson.DataTypeTIMESTAMP:
+		g.imports["time"] = true
+		return "time.Time", false, nil
+
+	case nson.DataTypeID:
+		g.imports["nson"] = true
+		return "nson.Id", false, nil
+
+	case nson.DataTypeMAP:
+		if schema.Fields != nil {
+			name, err := g.structType(schema, hint)
+			return name, false, err
+		}
+		if schema.Values != nil {
+			elem, err := g.elemType(schema.Values, hint+"Value")
+			return "map[string]" + elem, true, err
+		}
+		g.imports["nson"] = true
+		return "nson.Map", true, nil
+
+	case nson.DataTypeARRAY:
+		if schema.Items == nil {
+			g.imports["nson"] = true
+			return "nson.Array", true, nil
+		}
+		elem, err := g.elemType(schema.Items, hint+"Item")
+		if err != nil {
+			return "", false, err
+		}
+		if schema.MinItems != nil && schema.MaxItems != nil && *schema.MinItems == *schema.MaxItems && *schema.MinItems > 0 {
+			return fmt.Sprintf("[%d]%v", *schema.MinItems, elem), false, nil
+		}
+		return "[]" + elem, true, nil
+
+	default:
+		return "", false, fmt.Errorf("unsupported type %v", dt)
+	}
+}
+
+// elemType 返回数组元素或 map 值的类型，可以为 Null 时使用指针
+func (g *generator) elemType(schema *nson.Schema, hint string) (string, error) {
+	typ, ref, err := g.valueType(schema, hint)
+	if err != nil {
+		return "", err
+	}
+
+	if slices.Contains(schema.Types, nson.DataTypeNULL) && !ref {
+		typ = "*" + typ
+	}
+
+	return typ, nil
+}
+
+func (g *generator) uniqueName(hint string) string {
+	name := hint
+	for i := 2; g.names[name]; i++ {
+		name = fmt.Sprintf("%v%d", hint, i)
+	}
+	g.names[name] = true
+
+	return name
+}
+
+func (g *generator) source() ([]byte, error) {
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "// Code generated by nsongen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %v\n\n", g.pkg)
+
+	if len(g.imports) > 0 {
+		b.WriteString("import (\n")
+		if g.imports["time"] {
+			b.WriteString("\t\"time\"\n\n")
+		}
+		if g.imports["nson"] {
+			b.WriteString("\t\"github.com/danclive/nson-go\"\n")
+		}
+		b.WriteString(")\n\n")
+	}
+
+	for _, t := range g.types {
+		writeDoc(&b, "", t.doc)
+		fmt.Fprintf(&b, "type %v struct {\n", t.name)
+		for _, f := range t.fields {
+			writeDoc(&b, "\t", f.doc)
+			fmt.Fprintf(&b, "\t%v %v %v\n", f.name, f.typ, f.tag)
+		}
+		b.WriteString("}\n\n")
+	}
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %w\n%s", err, b.Bytes())
+	}
+
+	return src, nil
+}
+
+func writeDoc(b *bytes.Buffer, indent, doc string) {
+	if doc == "" {
+		return
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(doc), "\n") {
+		fmt.Fprintf(b, "%v// %v\n", indent, line)
+	}
+}
+
+func nonNullTypes(schema *nson.Schema) []nson.DataType {
+	types := make([]nson.DataType, 0, len(schema.Types))
+	for _, dt := range schema.Types {
+		if dt != nson.DataTypeNULL {
+			types = append(types, dt)
+		}
+	}
+
+	return types
+}
+
+func sortedFields(fields map[string]*nson.Schema) []string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	return keys
+}
+
+// commonInitialisms 按 Go 的命名习惯整体大写的缩写
+var commonInitialisms = map[string]bool{
+	"API": true, "ASCII": true, "CPU": true, "CSS": true, "DNS": true, "EOF": true,
+	"GUID": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true, "IP": true,
+	"JSON": true, "LHS": true, "QPS": true, "RAM": true, "RHS": true, "RPC": true,
+	"SLA": true, "SMTP": true, "SQL": true, "SSH": true, "TCP": true, "TLS": true,
+	"TTL": true, "UDP": true, "UI": true, "UID": true, "UUID": true, "URI": true,
+	"URL": true, "UTF8": true, "VM": true, "XML": true, "XMPP": true, "XSRF": true,
+	"XSS": true,
+}
+
+// goName 将字段名转换为导出的 Go 标识符，如 "device_id" -> "DeviceID"
+func goName(name string) string {
+	var words []string
+	var word []rune
+
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = word[:0]
+		}
+	}
+
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) ||
+			(i+1 < len(runes) && unicode.IsUpper(runes[i-1]) && unicode.IsLower(runes[i+1]))):
+			// camelCase 和 HTTPServer 的边界
+			flush()
+			word = append(word, r)
+		default:
+			word = append(word, r)
+		}
+	}
+	flush()
+
+	var b strings.Builder
+	for _, w := range words {
+		upper := strings.ToUpper(w)
+		if commonInitialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		rs := []rune(strings.ToLower(w))
+		rs[0] = unicode.ToUpper(rs[0])
+		b.WriteString(string(rs))
+	}
+
+	out := b.String()
+	if out == "" || !unicode.IsLetter([]rune(out)[0]) {
+		out = "X" + out
+	}
+
+	return out
+}
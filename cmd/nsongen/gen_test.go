@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/danclive/nson-go"
+)
+
+func deviceSchema() *nson.Schema {
+	return &nson.Schema{
+		Types:       []nson.DataType{nson.DataTypeMAP},
+		Description: "Device is a registered sensor.",
+		Fields: map[string]*nson.Schema{
+			"device_id": {Types: []nson.DataType{nson.DataTypeID}, Required: true},
+			"port":      {Types: []nson.DataType{nson.DataTypeU16}, Required: true, Description: "Listening port."},
+			"seen_at":   {Types: []nson.DataType{nson.DataTypeTIMESTAMP}},
+			"note":      {Types: []nson.DataType{nson.DataTypeSTRING, nson.DataTypeNULL}, Required: true},
+			"payload":   {Types: []nson.DataType{nson.DataTypeBINARY}},
+			"extra":     {Types: []nson.DataType{nson.DataTypeI32, nson.DataTypeSTRING}, Required: true},
+			"labels":    {Types: []nson.DataType{nson.DataTypeMAP}, Values: &nson.Schema{Types: []nson.DataType{nson.DataTypeSTRING}}},
+			"readings": {
+				Types:    []nson.DataType{nson.DataTypeARRAY},
+				Required: true,
+				Items: &nson.Schema{
+					Types: []nson.DataType{nson.DataTypeMAP},
+					Fields: map[string]*nson.Schema{
+						"value": {Types: []nson.DataType{nson.DataTypeF32, nson.DataTypeNULL}, Required: true},
+					},
+				},
+			},
+			"meta": {
+				Types: []nson.DataType{nson.DataTypeMAP},
+				Fields: map[string]*nson.Schema{
+					"rev":      {Types: []nson.DataType{nson.DataTypeI8}, Required: true},
+					"httpHost": {Types: []nson.DataType{nson.DataTypeSTRING}, Required: true},
+				},
+			},
+		},
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	src, err := generate(deviceSchema(), "Device", "model")
+	if err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+
+	if _, err := parser.ParseFile(token.NewFileSet(), "device.go", src, parser.ParseComments); err != nil {
+		t.Fatalf("Generated code does not parse: %v\n%s", err, src)
+	}
+
+	code := string(src)
+	normalized := strings.Join(strings.Fields(code), " ")
+
+	want := []string{
+		"// Code generated by nsongen. DO NOT EDIT.",
+		"package model",
+		`"time"`,
+		`"github.com/danclive/nson-go"`,
+		"// Device is a registered sensor. type Device struct {",
+		"DeviceID nson.Id `nson:\"device_id\"`",
+		"Extra nson.Value `nson:\"extra\"`",
+		"Labels map[string]string `nson:\"labels,omitempty\"`",
+		"Meta *DeviceMeta `nson:\"meta,omitempty\"`",
+		"Note *string `nson:\"note\"`",
+		"Payload []byte `nson:\"payload,omitempty\"`",
+		"// Listening port. Port uint16 `nson:\"port\"`",
+		"Readings []DeviceReadingsItem `nson:\"readings\"`",
+		"SeenAt *time.Time `nson:\"seen_at,omitempty\"`",
+		"type DeviceMeta struct { HTTPHost string `nson:\"httpHost\"` Rev int8 `nson:\"rev\"` }",
+		"type DeviceReadingsItem struct { Value *float32 `nson:\"value\"` }",
+	}
+
+	for _, w := range want {
+		if !strings.Contains(normalized, w) {
+			t.Errorf("Expected generated code to contain %q\n%s", w, code)
+		}
+	}
+}
+
+func TestParseSchemaFormats(t *testing.T) {
+	schema := deviceSchema()
+
+	buf := new(bytes.Buffer)
+	if err := nson.EncodeMap(schema.Map(), buf); err != nil {
+		t.Fatalf("EncodeMap failed: %v", err)
+	}
+
+	jsonData, err := schema.JSONSchema()
+	if err != nil {
+		t.Fatalf("JSONSchema failed: %v", err)
+	}
+
+	inputs := map[string][]byte{"nson": buf.Bytes(), "json": jsonData}
+
+	for name, data := range inputs {
+		for _, format := range []string{name, "auto"} {
+			parsed, err := parseSchema(data, format)
+			if err != nil {
+				t.Fatalf("%v/%v: parseSchema failed: %v", name, format, err)
+			}
+			if !nson.Equal(parsed.Map(), schema.Map()) {
+				t.Errorf("%v/%v: schema mismatch:\n got: %v\nwant: %v", name, format, parsed.Map(), schema.Map())
+			}
+		}
+	}
+
+	if _, err := parseSchema(jsonData, "yaml"); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+func TestGoName(t *testing.T) {
+	tests := map[string]string{
+		"device_id":  "DeviceID",
+		"httpServer": "HTTPServer",
+		"HTTPServer": "HTTPServer",
+		"seen-at":    "SeenAt",
+		"url":        "URL",
+		"2fa":        "X2fa",
+		"userName":   "UserName",
+	}
+
+	for in, want := range tests {
+		if got := goName(in); got != want {
+			t.Errorf("goName(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	if _, err := generate(&nson.Schema{Types: []nson.DataType{nson.DataTypeARRAY}}, "X", "p"); err == nil {
+		t.Error("Expected error for non-Map root")
+	}
+}
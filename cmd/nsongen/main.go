@@ -0,0 +1,163 @@
+// nsongen 根据 Schema 生成带 nson tag 的 Go 结构体。
+//
+// 用法：
+//
+//	nsongen [-type Name] [-package pkg] [-o output.go] [-format auto|nson|json] schema
+//
+// schema 为文件路径，"-" 表示标准输入。NSON 形式是编码后的 Schema.Map()，
+// JSON 形式是 JSON Schema（如 Schema.JSONSchema 的输出）。format 为 auto 时，
+// 以 "{" 开头的输入先按 JSON 解析。
+//
+// 生成规则：整数使用对应宽度的类型，Timestamp 为 time.Time，Id 为 nson.Id，
+// 嵌套的 Map 生成独立的结构体，非 Required 的字段带 omitempty，
+// 可选或可为 Null 的字段使用指针，联合类型和任意类型为 nson.Value。
+//
+// 在 go:generate 中使用时，默认包名取自 $GOPACKAGE。
+//
+// 加上 -marshal 时，输入为 Go 源文件，为其中带 //nsongen:marshal 注释
+// 或由 -type 指定（以逗号分隔）的结构体生成 MarshalNSON、UnmarshalNSON
+// 和 AppendNSON 方法，Marshal 和 Unmarshal 会直接调用它们而不使用反射：
+//
+//	//go:generate nsongen -marshal $GOFILE
+//
+// 输出默认为同目录下的 <name>_nson.go（测试文件为 <name>_nson_test.go）。
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/danclive/nson-go"
+)
+
+func main() {
+	typeName := flag.String("type", "", "root type name (default: derived from the schema file name)")
+	pkg := flag.String("package", os.Getenv("GOPACKAGE"), "package name of the generated file")
+	output := flag.String("o", "", "output file (default: stdout)")
+	formatName := flag.String("format", "auto", "schema format: auto, nson or json")
+	marshal := flag.Bool("marshal", false, "generate marshal methods for struct types in a Go source file")
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: nsongen [flags] schema\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var err error
+	if *marshal {
+		err = runMarshal(flag.Arg(0), *typeName, *output)
+	} else {
+		err = run(flag.Arg(0), *typeName, *pkg, *output, *formatName)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "nsongen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(input, typeName, pkg, output, formatName string) error {
+	data, err := readInput(input)
+	if err != nil {
+		return err
+	}
+
+	schema, err := parseSchema(data, formatName)
+	if err != nil {
+		return err
+	}
+
+	if typeName == "" {
+		typeName = "Message"
+		if input != "-" {
+			base := filepath.Base(input)
+			typeName = goName(strings.TrimSuffix(base, filepath.Ext(base)))
+		}
+	}
+
+	if pkg == "" {
+		pkg = "main"
+	}
+
+	src, err := generate(schema, typeName, pkg)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+
+	return os.WriteFile(output, src, 0o644)
+}
+
+func runMarshal(input, typeNames, output string) error {
+	var types []string
+	if typeNames != "" {
+		types = strings.Split(typeNames, ",")
+	}
+
+	src, err := generateMarshal(input, types)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		base := strings.TrimSuffix(input, ".go")
+		if strings.HasSuffix(base, "_test") {
+			output = strings.TrimSuffix(base, "_test") + "_nson_test.go"
+		} else {
+			output = base + "_nson.go"
+		}
+	} else if output == "-" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+
+	return os.WriteFile(output, src, 0o644)
+}
+
+func readInput(input string) ([]byte, error) {
+	if input == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+
+	return os.ReadFile(input)
+}
+
+// parseSchema 按格式解析 Schema
+func parseSchema(data []byte, formatName string) (*nson.Schema, error) {
+	if formatName == "auto" {
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+			// 编码后的 NSON 也可能以 '{' 开头，JSON 解析失败时再按 NSON 尝试
+			if schema, err := nson.SchemaFromJSON(data); err == nil {
+				return schema, nil
+			}
+		}
+		formatName = "nson"
+	}
+
+	switch formatName {
+	case "json":
+		return nson.SchemaFromJSON(data)
+	case "nson":
+		m, err := nson.DecodeMap(bytes.NewBuffer(data))
+		if err != nil {
+			return nil, err
+		}
+		return nson.SchemaFromMap(m)
+	default:
+		return nil, fmt.Errorf("unknown format %q", formatName)
+	}
+}
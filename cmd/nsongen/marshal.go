@@ -0,0 +1,1387 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/danclive/nson-go/internal/nsontag"
+)
+
+// -marshal 模式为结构体生成 MarshalNSON、UnmarshalNSON 和 AppendNSON 方法，
+// 字段规则与 buildFieldsRecursive 相同：跳过未导出字段和 nson:"-"，
+// 展开匿名嵌入的结构体，名称取自 tag 或字段名，支持 omitempty、remain、inline、
+// required 和 default，同名字段按 Go 的遮蔽规则处理。default 只支持基础类型、
+// time.Time 和指向它们的指针，值在生成时解析。type 选项需要运行时的范围检查，
+// 有这个选项的结构体不能生成，只能使用反射。
+// 字段引用的同包结构体会一并生成。声明了 nson.Marshaler 和 nson.Unmarshaler
+// 方法的同包类型直接调用这些方法，作为嵌入字段时也不展开，
+// encoding.TextMarshaler 和 encoding.BinaryMarshaler 的处理与反射相同。
+// 除 time.Time 和 nson 包的类型外，不支持其他包中的类型。
+//
+// 需要生成的类型由 -type 指定，或者在类型声明的注释中写上 //nsongen:marshal。
+
+const marshalDirective = "//nsongen:marshal"
+
+const nsonImportPath = "github.com/danclive/nson-go"
+
+type fieldKind int
+
+const (
+	kindBasic     fieldKind = iota // 数值、字符串和布尔
+	kindBinary                     // []byte
+	kindTime                       // time.Time
+	kindId                         // nson.Id
+	kindValue                      // nson.Value
+	kindMap                        // nson.Map
+	kindPointer                    //
+	kindSlice                      //
+	kindArray                      // 定长数组
+	kindStringMap                  // 键为字符串的 map
+	kindStruct                     // 同包的结构体
+	kindCustom                     // 实现了 Marshaler 和 Unmarshaler 的类型
+	kindEncoding                   // 实现了 encoding 包中编解码接口的类型
+)
+
+type fieldType struct {
+	kind     fieldKind
+	goType   string     // Go 类型表达式
+	nsonType string     // kindBasic 对应的 NSON 类型，如 "I32"
+	base     string     // kindBasic 的基础类型，如 "int64"
+	elem     *fieldType // 指针、切片、数组和 map 的元素
+	keyType  string     // kindStringMap 的键类型
+	under    *fieldType // kindCustom 和 kindEncoding 的底层类型，用于 omitempty，结构体为 nil
+	methods  methodSet  // kindEncoding 声明的方法
+}
+
+// methodSet 同包类型声明的编解码方法
+type methodSet uint8
+
+const (
+	hasMarshalNSON methodSet = 1 << iota
+	hasUnmarshalNSON
+	hasMarshalText
+	hasUnmarshalText
+	hasMarshalBinary
+	hasUnmarshalBinary
+)
+
+// basicTypes 基础类型对应的 NSON 类型，与 marshalValue 一致
+var basicTypes = map[string]string{
+	"bool":    "Bool",
+	"int8":    "I8",
+	"int16":   "I16",
+	"int32":   "I32",
+	"rune":    "I32",
+	"int":     "I32",
+	"int64":   "I64",
+	"uint8":   "U8",
+	"byte":    "U8",
+	"uint16":  "U16",
+	"uint32":  "U32",
+	"uint":    "U32",
+	"uint64":  "U64",
+	"float32": "F32",
+	"float64": "F64",
+	"string":  "String",
+}
+
+// nsonBasicTypes nson 包中按基础类型处理的值类型
+var nsonBasicTypes = map[string]string{
+	"Bool":      "bool",
+	"I8":        "int8",
+	"I16":       "int16",
+	"I32":       "int32",
+	"I64":       "int64",
+	"U8":        "uint8",
+	"U16":       "uint16",
+	"U32":       "uint32",
+	"U64":       "uint64",
+	"F32":       "float32",
+	"F64":       "float64",
+	"String":    "string",
+	"Timestamp": "uint64",
+}
+
+type structField struct {
+	name      string // Go 字段名，用于错误信息
+	access    string // 访问路径，嵌入字段为 "Base.Name"
+	key       string
+	omitEmpty bool
+	required  bool
+	def       string    // default 选项生成的表达式，为空表示没有默认值
+	remain    bool      // remain 字段或内联的 map
+	ptrs      []ptrStep // 访问路径上内联的指针
+	depth     int       // 嵌入和内联的层级
+	tagged    bool      // tag 中指定了名称
+	typ       *fieldType
+}
+
+// ptrStep 访问路径上内联的结构体指针
+type ptrStep struct {
+	access string // 指针字段的访问路径
+	elem   string // 指向的类型
+}
+
+type structInfo struct {
+	name   string
+	fields []structField
+	remain *structField // 收集没有对应字段的键的 remain 字段或内联的 map
+}
+
+type marshalGen struct {
+	fset    *token.FileSet
+	pkgName string
+	specs   map[string]*ast.TypeSpec
+	files   map[*ast.TypeSpec]*ast.File
+
+	methods map[string]methodSet
+
+	queue    []string
+	queued   map[string]bool
+	structs  []*structInfo
+	visiting map[*ast.StructType]bool // 正在收集字段的结构体，用于检查内联的循环
+
+	usesFmt     bool
+	usesTime    bool
+	usesStrings bool
+	n           int
+}
+
+// generateMarshal 解析 input 所在的包，为 types（为空时为带注释标记的类型）生成方法
+func generateMarshal(input string, types []string) ([]byte, error) {
+	g := &marshalGen{
+		fset:  token.NewFileSet(),
+		specs: map[string]*ast.TypeSpec{},
+		files: map[*ast.TypeSpec]*ast.File{},
+
+		methods:  map[string]methodSet{},
+		queued:   map[string]bool{},
+		visiting: map[*ast.StructType]bool{},
+	}
+
+	roots, err := g.parsePackage(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(types) > 0 {
+		roots = types
+	}
+
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("no types to generate: use -type or annotate types with %v", marshalDirective)
+	}
+
+	for _, name := range roots {
+		spec, has := g.specs[name]
+		if !has {
+			return nil, fmt.Errorf("type %v not found", name)
+		}
+		if _, ok := spec.Type.(*ast.StructType); !ok {
+			return nil, fmt.Errorf("type %v is not a struct", name)
+		}
+		g.enqueue(name)
+	}
+
+	for len(g.queue) > 0 {
+		name := g.queue[0]
+		g.queue = g.queue[1:]
+
+		info, err := g.buildStruct(name)
+		if err != nil {
+			return nil, err
+		}
+		g.structs = append(g.structs, info)
+	}
+
+	return g.source()
+}
+
+// parsePackage 读取 input 所在目录中同一个包的全部文件，返回 input 中带标记的类型
+func (g *marshalGen) parsePackage(input string) ([]string, error) {
+	main, err := parser.ParseFile(g.fset, input, nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	g.pkgName = main.Name.Name
+
+	paths, err := filepath.Glob(filepath.Join(filepath.Dir(input), "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	var roots []string
+
+	for _, path := range paths {
+		var file *ast.File
+		if sameFile(path, input) {
+			file = main
+		} else {
+			if file, err = parser.ParseFile(g.fset, path, nil, parser.ParseComments); err != nil {
+				return nil, err
+			}
+			if file.Name.Name != g.pkgName {
+				continue
+			}
+		}
+
+		for _, decl := range file.Decls {
+			if fd, ok := decl.(*ast.FuncDecl); ok {
+				g.collectMethod(fd, file)
+				continue
+			}
+
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, s := range gd.Specs {
+				spec := s.(*ast.TypeSpec)
+				g.specs[spec.Name.Name] = spec
+				g.files[spec] = file
+
+				if file == main && (hasDirective(gd.Doc) || hasDirective(spec.Doc)) {
+					roots = append(roots, spec.Name.Name)
+				}
+			}
+		}
+	}
+
+	return roots, nil
+}
+
+// collectMethod 记录 Marshaler、Unmarshaler 和 encoding 包中编解码接口的方法
+func (g *marshalGen) collectMethod(fd *ast.FuncDecl, file *ast.File) {
+	if fd.Recv == nil || len(fd.Recv.List) != 1 {
+		return
+	}
+
+	recv := fd.Recv.List[0].Type
+	if star, ok := recv.(*ast.StarExpr); ok {
+		recv = star.X
+	}
+	ident, ok := recv.(*ast.Ident)
+	if !ok {
+		return
+	}
+
+	isValue := func(fields *ast.FieldList, n int) bool {
+		if fields == nil || fields.NumFields() != n {
+			return false
+		}
+		sel, ok := fields.List[0].Type.(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != "Value" {
+			return false
+		}
+		x, ok := sel.X.(*ast.Ident)
+		return ok && importPath(file, x.Name) == nsonImportPath
+	}
+
+	isBytes := func(fields *ast.FieldList, n int) bool {
+		if fields == nil || fields.NumFields() != n {
+			return false
+		}
+		arr, ok := fields.List[0].Type.(*ast.ArrayType)
+		if !ok || arr.Len != nil {
+			return false
+		}
+		elt, ok := arr.Elt.(*ast.Ident)
+		return ok && (elt.Name == "byte" || elt.Name == "uint8")
+	}
+
+	params, results := fd.Type.Params.NumFields(), fd.Type.Results.NumFields()
+
+	var m methodSet
+	switch fd.Name.Name {
+	case "MarshalNSON":
+		if params == 0 && isValue(fd.Type.Results, 2) {
+			m = hasMarshalNSON
+		}
+	case "UnmarshalNSON":
+		if isValue(fd.Type.Params, 1) && results == 1 {
+			m = hasUnmarshalNSON
+		}
+	case "MarshalText", "MarshalBinary":
+		if params == 0 && isBytes(fd.Type.Results, 2) {
+			m = hasMarshalText
+			if fd.Name.Name == "MarshalBinary" {
+				m = hasMarshalBinary
+			}
+		}
+	case "UnmarshalText", "UnmarshalBinary":
+		if isBytes(fd.Type.Params, 1) && results == 1 {
+			m = hasUnmarshalText
+			if fd.Name.Name == "UnmarshalBinary" {
+				m = hasUnmarshalBinary
+			}
+		}
+	}
+
+	g.methods[ident.Name] |= m
+}
+
+// customCodec 检查表达式是否为有自己编码方式的同包类型，与 hasCustomCodec 相同
+func (g *marshalGen) customCodec(expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && g.methods[ident.Name]&(hasMarshalNSON|hasUnmarshalNSON|hasMarshalText|hasMarshalBinary) != 0
+}
+
+func sameFile(a, b string) bool {
+	x, err1 := os.Stat(a)
+	y, err2 := os.Stat(b)
+	return err1 == nil && err2 == nil && os.SameFile(x, y)
+}
+
+func hasDirective(doc *ast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+
+	for _, c := range doc.List {
+		if strings.TrimSpace(c.Text) == marshalDirective {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (g *marshalGen) enqueue(name string) {
+	if !g.queued[name] {
+		g.queued[name] = true
+		g.queue = append(g.queue, name)
+	}
+}
+
+func (g *marshalGen) buildStruct(name string) (*structInfo, error) {
+	spec := g.specs[name]
+	if spec.TypeParams != nil {
+		return nil, fmt.Errorf("type %v: generic types are not supported", name)
+	}
+
+	var fields []structField
+	if err := g.collectFields(spec.Type.(*ast.StructType), g.files[spec], "", 0, nil, &fields); err != nil {
+		return nil, fmt.Errorf("type %v: %w", name, err)
+	}
+
+	info := &structInfo{name: name}
+	for _, f := range fields {
+		if !f.remain {
+			info.fields = append(info.fields, f)
+			continue
+		}
+		if info.remain != nil {
+			return nil, fmt.Errorf("type %v: field %v: duplicate remain field, already %v", name, f.name, info.remain.name)
+		}
+		info.remain = &f
+	}
+
+	dominant, err := nsontag.Dominant(info.fields, func(f structField) nsontag.Field {
+		return nsontag.Field{Name: f.name, Key: f.key, Depth: f.depth, Tagged: f.tagged}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("type %v: %w", name, err)
+	}
+	info.fields = dominant
+
+	for _, f := range info.fields {
+		if len(f.key) == 0 || len(f.key) >= 255 {
+			return nil, fmt.Errorf("type %v: field %v: key length must be between 1 and 254", name, f.name)
+		}
+	}
+
+	return info, nil
+}
+
+// collectFields 按 buildFieldsRecursive 的规则收集字段
+func (g *marshalGen) collectFields(st *ast.StructType, file *ast.File, prefix string, depth int, ptrs []ptrStep, out *[]structField) error {
+	if g.visiting[st] {
+		return fmt.Errorf("inline cycle through %v", strings.TrimSuffix(prefix, "."))
+	}
+
+	g.visiting[st] = true
+	defer delete(g.visiting, st)
+
+	for _, f := range st.Fields.List {
+		names := make([]string, 0, len(f.Names))
+		for _, n := range f.Names {
+			names = append(names, n.Name)
+		}
+
+		if len(f.Names) == 0 {
+			name, isPtr, err := embeddedName(f.Type)
+			if err != nil {
+				return err
+			}
+			if !ast.IsExported(name) {
+				continue
+			}
+
+			if !isPtr && !g.customCodec(f.Type) {
+				embedded, embeddedFile, err := g.embeddedStruct(f.Type, file)
+				if err != nil {
+					return fmt.Errorf("embedded %v: %w", name, err)
+				}
+				if embedded != nil {
+					if err := g.collectFields(embedded, embeddedFile, prefix+name+".", depth+1, ptrs, out); err != nil {
+						return err
+					}
+					continue
+				}
+				if isExternalStruct(f.Type, file) {
+					// time.Time 等外部结构体没有可导出的字段
+					continue
+				}
+			}
+
+			names = append(names, name)
+		}
+
+		tag := ""
+		if f.Tag != nil {
+			raw, err := strconv.Unquote(f.Tag.Value)
+			if err != nil {
+				return err
+			}
+			tag = reflect.StructTag(raw).Get("nson")
+		}
+
+		if tag == "-" {
+			continue
+		}
+
+		nsonName, opts := nsontag.Parse(tag)
+
+		for _, name := range names {
+			if !ast.IsExported(name) {
+				continue
+			}
+
+			if opts.Type != "" {
+				return fmt.Errorf("field %v: type option is not supported", name)
+			}
+
+			if opts.Inline {
+				if err := g.collectInline(f.Type, file, name, prefix, depth, ptrs, out); err != nil {
+					return err
+				}
+				continue
+			}
+
+			typ, err := g.resolveType(f.Type, file)
+			if err != nil {
+				return fmt.Errorf("field %v: %w", name, err)
+			}
+
+			if opts.Remain && typ.kind != kindMap {
+				return fmt.Errorf("field %v: remain requires nson.Map, got %v", name, typ.goType)
+			}
+
+			def := ""
+			if opts.HasDefault {
+				if def, err = defaultExpr(typ, opts.Default); err != nil {
+					return fmt.Errorf("field %v: invalid default %q: %w", name, opts.Default, err)
+				}
+			}
+
+			key := nsonName
+			if key == "" {
+				key = name
+			}
+
+			*out = append(*out, structField{
+				name:      name,
+				access:    prefix + name,
+				key:       key,
+				omitEmpty: opts.OmitEmpty,
+				required:  opts.Required,
+				def:       def,
+				remain:    opts.Remain,
+				ptrs:      ptrs,
+				depth:     depth,
+				tagged:    nsonName != "",
+				typ:       typ,
+			})
+		}
+	}
+
+	return nil
+}
+
+// collectInline 展开内联的同包结构体、结构体指针或键为字符串的 map
+func (g *marshalGen) collectInline(expr ast.Expr, file *ast.File, name, prefix string, depth int, ptrs []ptrStep, out *[]structField) error {
+	access := prefix + name
+
+	if star, ok := expr.(*ast.StarExpr); ok {
+		if st, sf, _ := g.embeddedStruct(star.X, file); st != nil {
+			step := ptrStep{access: access, elem: g.exprString(star.X)}
+			return g.collectFields(st, sf, access+".", depth+1, append(slices.Clip(ptrs), step), out)
+		}
+	} else if st, sf, _ := g.embeddedStruct(expr, file); st != nil {
+		return g.collectFields(st, sf, access+".", depth+1, ptrs, out)
+	}
+
+	typ, err := g.resolveType(expr, file)
+	if err == nil && typ.kind != kindMap && typ.kind != kindStringMap {
+		err = fmt.Errorf("inline requires a struct, pointer to struct or map with string keys, got %v", typ.goType)
+	}
+	if err != nil {
+		return fmt.Errorf("field %v: %w", name, err)
+	}
+
+	*out = append(*out, structField{
+		name:   name,
+		access: access,
+		remain: true,
+		ptrs:   ptrs,
+		depth:  depth,
+		typ:    typ,
+	})
+
+	return nil
+}
+
+func embeddedName(expr ast.Expr) (string, bool, error) {
+	isPtr := false
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+		isPtr = true
+	}
+
+	switch x := expr.(type) {
+	case *ast.Ident:
+		return x.Name, isPtr, nil
+	case *ast.SelectorExpr:
+		return x.Sel.Name, isPtr, nil
+	}
+
+	return "", false, fmt.Errorf("unsupported embedded type %T", expr)
+}
+
+// embeddedStruct 返回嵌入的同包结构体定义，不是同包结构体时返回 nil
+func (g *marshalGen) embeddedStruct(expr ast.Expr, file *ast.File) (*ast.StructType, *ast.File, error) {
+	for {
+		ident, ok := expr.(*ast.Ident)
+		if !ok {
+			return nil, nil, nil
+		}
+		spec, has := g.specs[ident.Name]
+		if !has {
+			return nil, nil, nil
+		}
+		if st, ok := spec.Type.(*ast.StructType); ok {
+			return st, g.files[spec], nil
+		}
+		expr, file = spec.Type, g.files[spec]
+	}
+}
+
+func isExternalStruct(expr ast.Expr, file *ast.File) bool {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+
+	x, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return false
+	}
+
+	switch importPath(file, x.Name) + "." + sel.Sel.Name {
+	case "time.Time", nsonImportPath + ".Null":
+		return true
+	}
+
+	return false
+}
+
+func importPath(file *ast.File, name string) string {
+	for _, imp := range file.Imports {
+		path, _ := strconv.Unquote(imp.Path.Value)
+		local := filepath.Base(path)
+		if path == nsonImportPath {
+			local = "nson"
+		}
+		if imp.Name != nil {
+			local = imp.Name.Name
+		}
+		if local == name {
+			return path
+		}
+	}
+
+	return ""
+}
+
+func (g *marshalGen) resolveType(expr ast.Expr, file *ast.File) (*fieldType, error) {
+	switch x := expr.(type) {
+	case *ast.ParenExpr:
+		return g.resolveType(x.X, file)
+
+	case *ast.Ident:
+		if nsonType, ok := basicTypes[x.Name]; ok {
+			base := x.Name
+			switch base {
+			case "byte":
+				base = "uint8"
+			case "rune":
+				base = "int32"
+			}
+			return &fieldType{kind: kindBasic, goType: x.Name, nsonType: nsonType, base: base}, nil
+		}
+		spec, has := g.specs[x.Name]
+		if !has {
+			return nil, fmt.Errorf("unsupported type %v", x.Name)
+		}
+		return g.resolveNamed(spec)
+
+	case *ast.StarExpr:
+		elem, err := g.resolveType(x.X, file)
+		if err != nil {
+			return nil, err
+		}
+		return &fieldType{kind: kindPointer, goType: "*" + elem.goType, elem: elem}, nil
+
+	case *ast.ArrayType:
+		elem, err := g.resolveType(x.Elt, file)
+		if err != nil {
+			return nil, err
+		}
+		if x.Len == nil {
+			if elem.kind == kindBasic && elem.base == "uint8" {
+				return &fieldType{kind: kindBinary, goType: "[]" + elem.goType}, nil
+			}
+			return &fieldType{kind: kindSlice, goType: "[]" + elem.goType, elem: elem}, nil
+		}
+		if _, ok := x.Len.(*ast.Ellipsis); ok {
+			return nil, fmt.Errorf("unsupported array length")
+		}
+		length := g.exprString(x.Len)
+		return &fieldType{kind: kindArray, goType: "[" + length + "]" + elem.goType, elem: elem}, nil
+
+	case *ast.MapType:
+		key, err := g.resolveType(x.Key, file)
+		if err != nil {
+			return nil, err
+		}
+		if key.kind != kindBasic || key.base != "string" {
+			return nil, fmt.Errorf("map key must be string")
+		}
+		elem, err := g.resolveType(x.Value, file)
+		if err != nil {
+			return nil, err
+		}
+		return &fieldType{kind: kindStringMap, goType: "map[" + key.goType + "]" + elem.goType, elem: elem, keyType: key.goType}, nil
+
+	case *ast.SelectorExpr:
+		pkg, ok := x.X.(*ast.Ident)
+		if !ok {
+			break
+		}
+		path := importPath(file, pkg.Name)
+		name := x.Sel.Name
+
+		if path == "time" && name == "Time" {
+			g.usesTime = true
+			return &fieldType{kind: kindTime, goType: "time.Time"}, nil
+		}
+
+		if path == nsonImportPath {
+			goType := "nson." + name
+			if base, ok := nsonBasicTypes[name]; ok {
+				return &fieldType{kind: kindBasic, goType: goType, nsonType: basicTypes[base], base: base}, nil
+			}
+			switch name {
+			case "Binary":
+				return &fieldType{kind: kindBinary, goType: goType}, nil
+			case "Id":
+				return &fieldType{kind: kindId, goType: goType}, nil
+			case "Value":
+				return &fieldType{kind: kindValue, goType: goType}, nil
+			case "Map":
+				return &fieldType{kind: kindMap, goType: goType, keyType: "string", elem: &fieldType{kind: kindValue, goType: "nson.Value"}}, nil
+			case "Array":
+				return &fieldType{kind: kindSlice, goType: goType, elem: &fieldType{kind: kindValue, goType: "nson.Value"}}, nil
+			}
+		}
+
+		return nil, fmt.Errorf("unsupported type %v", g.exprString(x))
+	}
+
+	return nil, fmt.Errorf("unsupported type %v", g.exprString(expr))
+}
+
+// resolveNamed 解析同包的命名类型
+func (g *marshalGen) resolveNamed(spec *ast.TypeSpec) (*fieldType, error) {
+	name := spec.Name.Name
+
+	if spec.TypeParams != nil {
+		return nil, fmt.Errorf("generic type %v is not supported", name)
+	}
+
+	_, isStruct := spec.Type.(*ast.StructType)
+	methods := g.methods[name]
+
+	var codec *fieldType
+	switch {
+	case methods&(hasMarshalNSON|hasUnmarshalNSON) != 0:
+		if methods&hasMarshalNSON == 0 || methods&hasUnmarshalNSON == 0 {
+			return nil, fmt.Errorf("type %v must implement both nson.Marshaler and nson.Unmarshaler", name)
+		}
+		codec = &fieldType{kind: kindCustom, goType: name}
+
+	case isStruct && g.queued[name]:
+		// 生成的 MapMarshaler 优先于 encoding 包的接口
+
+	case methods&(hasMarshalText|hasMarshalBinary|hasUnmarshalText|hasUnmarshalBinary) != 0:
+		if methods&(hasMarshalText|hasMarshalBinary) == 0 || methods&(hasUnmarshalText|hasUnmarshalBinary) == 0 {
+			return nil, fmt.Errorf("type %v must implement both a text or binary marshaler and unmarshaler", name)
+		}
+		codec = &fieldType{kind: kindEncoding, goType: name, methods: methods}
+	}
+
+	if codec != nil {
+		if !isStruct {
+			codec.under, _ = g.resolveType(spec.Type, g.files[spec])
+		}
+		return codec, nil
+	}
+
+	if isStruct {
+		g.enqueue(name)
+		return &fieldType{kind: kindStruct, goType: name}, nil
+	}
+
+	underlying, err := g.resolveType(spec.Type, g.files[spec])
+	if err != nil {
+		return nil, fmt.Errorf("type %v: %w", name, err)
+	}
+
+	if spec.Assign.IsValid() {
+		// 类型别名与原类型相同
+		return underlying, nil
+	}
+
+	t := *underlying
+	t.goType = name
+
+	switch t.kind {
+	case kindStruct, kindTime, kindId:
+		// 反射按底层的结构体或数组处理，与原类型的编码不同
+		return nil, fmt.Errorf("type %v: named %v types are not supported", name, underlying.goType)
+	case kindMap:
+		// 只有 nson.Map 本身在 Unmarshal 时直接赋值
+		t.kind = kindStringMap
+	}
+
+	return &t, nil
+}
+
+func (g *marshalGen) exprString(expr ast.Expr) string {
+	var b bytes.Buffer
+	printer.Fprint(&b, g.fset, expr)
+	return b.String()
+}
+
+func (g *marshalGen) tmp(prefix string) string {
+	g.n++
+	return fmt.Sprintf("%v%d", prefix, g.n)
+}
+
+// errCtx 错误信息的前缀，如 "field Tags: index %d: " 和对应的参数
+type errCtx struct {
+	format string
+	args   []string
+}
+
+func (c errCtx) with(format string, args ...string) errCtx {
+	return errCtx{format: c.format + format, args: append(slices.Clone(c.args), args...)}
+}
+
+// errorf 生成 fmt.Errorf 调用
+func (g *marshalGen) errorf(c errCtx, format string, args ...string) string {
+	g.usesFmt = true
+	all := append(slices.Clone(c.args), args...)
+	if len(all) == 0 {
+		return fmt.Sprintf("fmt.Errorf(%q)", c.format+format)
+	}
+	return fmt.Sprintf("fmt.Errorf(%q, %v)", c.format+format, strings.Join(all, ", "))
+}
+
+// notEmpty 返回 omitempty 时写入字段的条件，空字符串表示总是写入
+func notEmpty(t *fieldType, src string) string {
+	switch t.kind {
+	case kindBasic:
+		switch t.base {
+		case "bool":
+			return src
+		case "string":
+			return src + ` != ""`
+		default:
+			return src + " != 0"
+		}
+	case kindBinary, kindSlice, kindArray, kindStringMap, kindMap:
+		return "len(" + src + ") != 0"
+	case kindPointer, kindValue:
+		return src + " != nil"
+	case kindCustom, kindEncoding:
+		if t.under != nil {
+			return notEmpty(t.under, src)
+		}
+	}
+
+	return ""
+}
+
+func (g *marshalGen) source() ([]byte, error) {
+	var body bytes.Buffer
+
+	for _, s := range g.structs {
+		g.writeMarshal(&body, s)
+		g.writeAppend(&body, s)
+		g.writeUnmarshal(&body, s)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by nsongen -marshal. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %v\n\n", g.pkgName)
+	b.WriteString("import (\n")
+	if g.usesFmt {
+		b.WriteString("\t\"fmt\"\n")
+	}
+	if g.usesStrings {
+		b.WriteString("\t\"strings\"\n")
+	}
+	if g.usesTime {
+		b.WriteString("\t\"time\"\n")
+	}
+	b.WriteString("\n\tnson \"" + nsonImportPath + "\"\n)\n\n")
+	b.Write(body.Bytes())
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %w\n%s", err, b.Bytes())
+	}
+
+	return src, nil
+}
+
+func (g *marshalGen) writeMarshal(w *bytes.Buffer, s *structInfo) {
+	fmt.Fprintf(w, "// MarshalNSON 实现 nson.MapMarshaler\n")
+	fmt.Fprintf(w, "func (self %v) MarshalNSON() (nson.Map, error) {\n", s.name)
+	fmt.Fprintf(w, "m := make(nson.Map, %d)\n", len(s.fields))
+
+	for _, f := range s.fields {
+		src := "self." + f.access
+		cond := writeCond(f)
+		if cond != "" {
+			fmt.Fprintf(w, "if %v {\n", cond)
+		}
+		g.marshalValue(w, f.typ, src, fmt.Sprintf("m[%q]", f.key), errCtx{format: "field " + f.name + ": "})
+		if cond != "" {
+			fmt.Fprintf(w, "}\n")
+		}
+	}
+
+	if r := s.remain; r != nil {
+		cond := ptrCond(r.ptrs)
+		if cond != "" {
+			fmt.Fprintf(w, "if %v {\n", cond)
+		}
+		fmt.Fprintf(w, "for k, v := range self.%v {\n", r.access)
+		s.skipKnown(w, "k")
+		g.marshalValue(w, r.typ.elem, "v", "m[string(k)]", errCtx{format: "field " + r.name + ": "}.with("key %s: ", "k"))
+		fmt.Fprintf(w, "}\n")
+		if cond != "" {
+			fmt.Fprintf(w, "}\n")
+		}
+	}
+
+	fmt.Fprintf(w, "return m, nil\n}\n\n")
+}
+
+// writeCond 返回写入字段的条件：访问路径上内联的指针不为 nil，omitempty 时不为空。
+// 空字符串表示总是写入
+func writeCond(f structField) string {
+	var conds []string
+	if cond := ptrCond(f.ptrs); cond != "" {
+		conds = append(conds, cond)
+	}
+	if f.omitEmpty {
+		if cond := notEmpty(f.typ, "self."+f.access); cond != "" {
+			conds = append(conds, cond)
+		}
+	}
+
+	return strings.Join(conds, " && ")
+}
+
+// ptrCond 返回访问路径上内联的指针都不为 nil 的条件
+func ptrCond(ptrs []ptrStep) string {
+	conds := make([]string, 0, len(ptrs))
+	for _, p := range ptrs {
+		conds = append(conds, "self."+p.access+" != nil")
+	}
+
+	return strings.Join(conds, " && ")
+}
+
+// allocPtrs 生成为访问路径上 nil 的内联指针分配内存的代码
+func allocPtrs(w *bytes.Buffer, ptrs []ptrStep) {
+	for _, p := range ptrs {
+		fmt.Fprintf(w, "if self.%v == nil {\nself.%v = new(%v)\n}\n", p.access, p.access, p.elem)
+	}
+}
+
+// skipKnown 生成跳过与字段同名的键的代码，与反射一致，remain 中的这些键被忽略
+func (s *structInfo) skipKnown(w *bytes.Buffer, key string) {
+	if len(s.fields) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(s.fields))
+	for _, f := range s.fields {
+		keys = append(keys, strconv.Quote(f.key))
+	}
+
+	fmt.Fprintf(w, "switch %v {\ncase %v:\ncontinue\n}\n", key, strings.Join(keys, ", "))
+}
+
+// marshalValue 生成将 src 转换为 nson.Value 并赋值给 dst 的代码
+func (g *marshalGen) marshalValue(w *bytes.Buffer, t *fieldType, src, dst string, c errCtx) {
+	switch t.kind {
+	case kindBasic:
+		fmt.Fprintf(w, "%v = nson.%v(%v)\n", dst, t.nsonType, src)
+
+	case kindBinary:
+		fmt.Fprintf(w, "%v = nson.Binary(%v)\n", dst, src)
+
+	case kindTime:
+		fmt.Fprintf(w, "%v = nson.Timestamp(%v.UnixMilli())\n", dst, src)
+
+	case kindId:
+		fmt.Fprintf(w, "%v = %v\n", dst, src)
+
+	case kindValue:
+		fmt.Fprintf(w, "if %v == nil {\n%v = nson.Null{}\n} else {\n%v = %v\n}\n", src, dst, dst, src)
+
+	case kindPointer:
+		fmt.Fprintf(w, "if %v == nil {\n%v = nson.Null{}\n} else {\n", src, dst)
+		g.marshalValue(w, t.elem, "(*"+src+")", dst, c)
+		fmt.Fprintf(w, "}\n")
+
+	case kindSlice, kindArray:
+		arr, i := g.tmp("arr"), g.tmp("i")
+		fmt.Fprintf(w, "%v := make(nson.Array, len(%v))\n", arr, src)
+		fmt.Fprintf(w, "for %v := range %v {\n", i, src)
+		g.marshalValue(w, t.elem, src+"["+i+"]", arr+"["+i+"]", c.with("index %d: ", i))
+		fmt.Fprintf(w, "}\n%v = %v\n", dst, arr)
+
+	case kindStringMap, kindMap:
+		mv, k, v := g.tmp("m"), g.tmp("k"), g.tmp("v")
+		fmt.Fprintf(w, "%v := make(nson.Map, len(%v))\n", mv, src)
+		fmt.Fprintf(w, "for %v, %v := range %v {\n", k, v, src)
+		g.marshalValue(w, t.elem, v, mv+"[string("+k+")]", c.with("key %s: ", k))
+		fmt.Fprintf(w, "}\n%v = %v\n", dst, mv)
+
+	case kindCustom:
+		cv, err := g.tmp("v"), g.tmp("err")
+		fmt.Fprintf(w, "%v, %v := %v.MarshalNSON()\n", cv, err, src)
+		fmt.Fprintf(w, "if %v != nil {\nreturn nil, %v\n}\n", err, g.errorf(c, "%w", err))
+		fmt.Fprintf(w, "if %v == nil {\n%v = nson.Null{}\n} else {\n%v = %v\n}\n", cv, dst, dst, cv)
+
+	case kindEncoding:
+		data, err := g.tmp("data"), g.tmp("err")
+		method, typ := encodingMethod(t)
+		fmt.Fprintf(w, "%v, %v := %v.%v()\n", data, err, src, method)
+		fmt.Fprintf(w, "if %v != nil {\nreturn nil, %v\n}\n", err, g.errorf(c, "%w", err))
+		fmt.Fprintf(w, "%v = nson.%v(%v)\n", dst, typ, data)
+
+	case kindStruct:
+		sm, err := g.tmp("m"), g.tmp("err")
+		fmt.Fprintf(w, "%v, %v := %v.MarshalNSON()\n", sm, err, src)
+		fmt.Fprintf(w, "if %v != nil {\nreturn nil, %v\n}\n", err, g.errorf(c, "%w", err))
+		fmt.Fprintf(w, "%v = %v\n", dst, sm)
+	}
+}
+
+func (g *marshalGen) writeAppend(w *bytes.Buffer, s *structInfo) {
+	var body bytes.Buffer
+	usesErr := false
+
+	for _, f := range s.fields {
+		src := "self." + f.access
+		cond := writeCond(f)
+		if cond != "" {
+			fmt.Fprintf(&body, "if %v {\n", cond)
+		}
+		fmt.Fprintf(&body, "buf = append(buf, %q...)\n", string([]byte{byte(len(f.key) + 1)})+f.key)
+		if g.appendValue(&body, f.typ, src, errCtx{format: "field " + f.name + ": "}) {
+			usesErr = true
+		}
+		if cond != "" {
+			fmt.Fprintf(&body, "}\n")
+		}
+	}
+
+	if r := s.remain; r != nil {
+		c := errCtx{format: "field " + r.name + ": "}
+		cond := ptrCond(r.ptrs)
+		if cond != "" {
+			fmt.Fprintf(&body, "if %v {\n", cond)
+		}
+		fmt.Fprintf(&body, "for k, v := range self.%v {\n", r.access)
+		s.skipKnown(&body, "k")
+		fmt.Fprintf(&body, "if buf, err = nson.AppendKey(buf, string(k)); err != nil {\nreturn nil, %v\n}\n", g.errorf(c, "key %s: %w", "k", "err"))
+		g.appendValue(&body, r.typ.elem, "v", c.with("key %s: ", "k"))
+		fmt.Fprintf(&body, "}\n")
+		if cond != "" {
+			fmt.Fprintf(&body, "}\n")
+		}
+		usesErr = true
+	}
+
+	fmt.Fprintf(w, "// AppendNSON 将编码后的 Map 追加到 buf，结果与 nson.EncodeMap 相同\n")
+	fmt.Fprintf(w, "func (self %v) AppendNSON(buf []byte) ([]byte, error) {\n", s.name)
+	if usesErr {
+		fmt.Fprintf(w, "var err error\n")
+	}
+	fmt.Fprintf(w, "var start int\nbuf, start = nson.AppendMapStart(buf)\n")
+	w.Write(body.Bytes())
+	fmt.Fprintf(w, "return nson.AppendMapEnd(buf, start), nil\n}\n\n")
+}
+
+// appendValue 生成写入带类型标记的值的代码，返回是否使用了 err
+func (g *marshalGen) appendValue(w *bytes.Buffer, t *fieldType, src string, c errCtx) bool {
+	switch t.kind {
+	case kindBasic:
+		fmt.Fprintf(w, "buf = nson.Append%v(buf, nson.%v(%v))\n", t.nsonType, t.nsonType, src)
+
+	case kindBinary:
+		fmt.Fprintf(w, "buf = nson.AppendBinary(buf, nson.Binary(%v))\n", src)
+
+	case kindTime:
+		fmt.Fprintf(w, "buf = nson.AppendTimestamp(buf, nson.Timestamp(%v.UnixMilli()))\n", src)
+
+	case kindId:
+		fmt.Fprintf(w, "buf = nson.AppendId(buf, %v)\n", src)
+
+	case kindValue:
+		fmt.Fprintf(w, "if %v == nil {\nbuf = nson.AppendNull(buf)\n} else {\n", src)
+		fmt.Fprintf(w, "if buf, err = nson.AppendValue(buf, %v); err != nil {\nreturn nil, %v\n}\n}\n", src, g.errorf(c, "%w", "err"))
+		return true
+
+	case kindPointer:
+		fmt.Fprintf(w, "if %v == nil {\nbuf = nson.AppendNull(buf)\n} else {\n", src)
+		usesErr := g.appendValue(w, t.elem, "(*"+src+")", c)
+		fmt.Fprintf(w, "}\n")
+		return usesErr
+
+	case kindSlice, kindArray:
+		start, i := g.tmp("start"), g.tmp("i")
+		fmt.Fprintf(w, "buf = append(buf, byte(nson.DataTypeARRAY))\n")
+		fmt.Fprintf(w, "var %v int\nbuf, %v = nson.AppendArrayStart(buf)\n", start, start)
+		fmt.Fprintf(w, "for %v := range %v {\n", i, src)
+		usesErr := g.appendValue(w, t.elem, src+"["+i+"]", c.with("index %d: ", i))
+		fmt.Fprintf(w, "}\nbuf = nson.AppendArrayEnd(buf, %v)\n", start)
+		return usesErr
+
+	case kindStringMap, kindMap:
+		start, k, v := g.tmp("start"), g.tmp("k"), g.tmp("v")
+		fmt.Fprintf(w, "buf = append(buf, byte(nson.DataTypeMAP))\n")
+		fmt.Fprintf(w, "var %v int\nbuf, %v = nson.AppendMapStart(buf)\n", start, start)
+		fmt.Fprintf(w, "for %v, %v := range %v {\n", k, v, src)
+		fmt.Fprintf(w, "if buf, err = nson.AppendKey(buf, string(%v)); err != nil {\nreturn nil, %v\n}\n", k, g.errorf(c, "key %s: %w", k, "err"))
+		g.appendValue(w, t.elem, v, c.with("key %s: ", k))
+		fmt.Fprintf(w, "}\nbuf = nson.AppendMapEnd(buf, %v)\n", start)
+		return true
+
+	case kindCustom:
+		cv, cerr := g.tmp("v"), g.tmp("err")
+		fmt.Fprintf(w, "%v, %v := %v.MarshalNSON()\n", cv, cerr, src)
+		fmt.Fprintf(w, "if %v != nil {\nreturn nil, %v\n}\n", cerr, g.errorf(c, "%w", cerr))
+		fmt.Fprintf(w, "if %v == nil {\nbuf = nson.AppendNull(buf)\n} else if buf, err = nson.AppendValue(buf, %v); err != nil {\nreturn nil, %v\n}\n", cv, cv, g.errorf(c, "%w", "err"))
+		return true
+
+	case kindEncoding:
+		data, err := g.tmp("data"), g.tmp("err")
+		method, typ := encodingMethod(t)
+		fmt.Fprintf(w, "%v, %v := %v.%v()\n", data, err, src, method)
+		fmt.Fprintf(w, "if %v != nil {\nreturn nil, %v\n}\n", err, g.errorf(c, "%w", err))
+		fmt.Fprintf(w, "buf = nson.Append%v(buf, nson.%v(%v))\n", typ, typ, data)
+
+	case kindStruct:
+		fmt.Fprintf(w, "buf = append(buf, byte(nson.DataTypeMAP))\n")
+		fmt.Fprintf(w, "if buf, err = %v.AppendNSON(buf); err != nil {\nreturn nil, %v\n}\n", src, g.errorf(c, "%w", "err"))
+		return true
+	}
+
+	return false
+}
+
+func (g *marshalGen) writeUnmarshal(w *bytes.Buffer, s *structInfo) {
+	fmt.Fprintf(w, "// UnmarshalNSON 实现 nson.MapUnmarshaler\n")
+	fmt.Fprintf(w, "func (self *%v) UnmarshalNSON(m nson.Map) error {\n", s.name)
+
+	hasRequired := slices.ContainsFunc(s.fields, func(f structField) bool { return f.required })
+	if hasRequired {
+		fmt.Fprintf(w, "var missing []string\n")
+	}
+
+	for _, f := range s.fields {
+		v := g.tmp("v")
+		fmt.Fprintf(w, "if %v, has := m[%q]; has {\n", v, f.key)
+		allocPtrs(w, f.ptrs)
+		g.unmarshalValue(w, f.typ, v, "self."+f.access, errCtx{format: "field " + f.name + ": "})
+
+		switch {
+		case f.required:
+			fmt.Fprintf(w, "} else {\nmissing = append(missing, %q)\n", f.key)
+		case f.def != "":
+			// 与反射相同，内联的指针为 nil 时不写入默认值
+			if cond := ptrCond(f.ptrs); cond != "" {
+				fmt.Fprintf(w, "} else if %v {\n", cond)
+			} else {
+				fmt.Fprintf(w, "} else {\n")
+			}
+			if f.typ.kind == kindPointer {
+				fmt.Fprintf(w, "self.%v = new(%v)\n*self.%v = %v\n", f.access, f.typ.elem.goType, f.access, f.def)
+			} else {
+				fmt.Fprintf(w, "self.%v = %v\n", f.access, f.def)
+			}
+		}
+		fmt.Fprintf(w, "}\n")
+	}
+
+	if hasRequired {
+		g.usesStrings = true
+		fmt.Fprintf(w, "if len(missing) > 0 {\nreturn %v\n}\n", g.errorf(errCtx{}, "missing required fields: %s", `strings.Join(missing, ", ")`))
+	}
+
+	if r := s.remain; r != nil {
+		// 与反射相同，nson.Map 直接保存原值，其他 map 按元素类型解码
+		fmt.Fprintf(w, "var rest %v\nfor k, v := range m {\n", r.typ.goType)
+		s.skipKnown(w, "k")
+		fmt.Fprintf(w, "if rest == nil {\nrest = make(%v)\n}\n", r.typ.goType)
+		if r.typ.kind == kindMap {
+			fmt.Fprintf(w, "rest[k] = v\n}\n")
+		} else {
+			e := g.tmp("e")
+			fmt.Fprintf(w, "var %v %v\n", e, r.typ.elem.goType)
+			g.unmarshalValue(w, r.typ.elem, "v", e, errCtx{format: "field " + r.name + ": "}.with("key %s: ", "k"))
+			fmt.Fprintf(w, "rest[%v(k)] = %v\n}\n", r.typ.keyType, e)
+		}
+		fmt.Fprintf(w, "if rest != nil {\n")
+		allocPtrs(w, r.ptrs)
+		fmt.Fprintf(w, "self.%v = rest\n}\n", r.access)
+	}
+
+	fmt.Fprintf(w, "return nil\n}\n\n")
+}
+
+// unmarshalValue 生成将 nson.Value 类型的 val 写入 dst 的代码，规则与 unmarshalValue 相同
+func (g *marshalGen) unmarshalValue(w *bytes.Buffer, t *fieldType, val, dst string, c errCtx) {
+	if t.kind == kindPointer {
+		fmt.Fprintf(w, "if _, ok := %v.(nson.Null); ok {\n%v = nil\n} else {\n", val, dst)
+		fmt.Fprintf(w, "if %v == nil {\n%v = new(%v)\n}\n", dst, dst, t.elem.goType)
+		g.unmarshalValue(w, t.elem, val, "(*"+dst+")", c)
+		fmt.Fprintf(w, "}\n")
+		return
+	}
+
+	if t.kind == kindCustom {
+		err := g.tmp("err")
+		fmt.Fprintf(w, "if %v := %v.UnmarshalNSON(%v); %v != nil {\nreturn %v\n}\n", err, dst, val, err, g.errorf(c, "%w", err))
+		return
+	}
+
+	if t.kind == kindValue {
+		fmt.Fprintf(w, "if _, ok := %v.(nson.Null); !ok {\n%v = %v\n}\n", val, dst, val)
+		return
+	}
+
+	x := g.tmp("x")
+	fmt.Fprintf(w, "switch %v := %v.(type) {\ncase nson.Null:\n", x, val)
+
+	expected := ""
+
+	switch t.kind {
+	case kindBasic:
+		switch t.base {
+		case "int64", "uint64":
+			fmt.Fprintf(w, "case nson.%v:\n%v = %v(%v)\n", t.nsonType, dst, t.goType, x)
+			fmt.Fprintf(w, "case nson.Timestamp:\n%v = %v(%v)\n", dst, t.goType, x)
+			expected = t.nsonType + " or Timestamp"
+		default:
+			fmt.Fprintf(w, "case nson.%v:\n%v = %v(%v)\n", t.nsonType, dst, t.goType, x)
+			expected = t.nsonType
+		}
+
+	case kindBinary:
+		fmt.Fprintf(w, "case nson.Binary:\n%v = %v(%v)\n", dst, t.goType, x)
+		expected = "Binary"
+
+	case kindTime:
+		fmt.Fprintf(w, "case nson.Timestamp:\n%v = time.UnixMilli(int64(%v))\n", dst, x)
+		expected = "Timestamp for time.Time"
+
+	case kindId:
+		fmt.Fprintf(w, "case nson.Id:\n%v = %v\n", dst, x)
+		expected = "nson.Id"
+
+	case kindMap:
+		fmt.Fprintf(w, "case nson.Map:\n%v = %v\n", dst, x)
+		expected = "Map"
+
+	case kindSlice:
+		s, i, item := g.tmp("s"), g.tmp("i"), g.tmp("item")
+		fmt.Fprintf(w, "case nson.Array:\n%v := make(%v, len(%v))\n", s, t.goType, x)
+		fmt.Fprintf(w, "for %v, %v := range %v {\n", i, item, x)
+		g.unmarshalValue(w, t.elem, item, s+"["+i+"]", c.with("index %d: ", i))
+		fmt.Fprintf(w, "}\n%v = %v\n", dst, s)
+		expected = "Array"
+
+	case kindArray:
+		i, item := g.tmp("i"), g.tmp("item")
+		fmt.Fprintf(w, "case nson.Array:\n")
+		fmt.Fprintf(w, "if len(%v) != len(%v) {\nreturn %v\n}\n", x, dst,
+			g.errorf(c, "array length mismatch: expected %d, got %d", "len("+dst+")", "len("+x+")"))
+		fmt.Fprintf(w, "for %v, %v := range %v {\n", i, item, x)
+		g.unmarshalValue(w, t.elem, item, dst+"["+i+"]", c.with("index %d: ", i))
+		fmt.Fprintf(w, "}\n")
+		expected = "Array"
+
+	case kindStringMap:
+		mv, k, item, e := g.tmp("m"), g.tmp("k"), g.tmp("item"), g.tmp("e")
+		fmt.Fprintf(w, "case nson.Map:\n%v := make(%v, len(%v))\n", mv, t.goType, x)
+		fmt.Fprintf(w, "for %v, %v := range %v {\nvar %v %v\n", k, item, x, e, t.elem.goType)
+		g.unmarshalValue(w, t.elem, item, e, c.with("key %s: ", k))
+		fmt.Fprintf(w, "%v[%v(%v)] = %v\n}\n%v = %v\n", mv, t.keyType, k, e, dst, mv)
+		expected = "Map"
+
+	case kindEncoding:
+		var accepted []string
+		if t.methods&hasUnmarshalText != 0 {
+			err := g.tmp("err")
+			fmt.Fprintf(w, "case nson.String:\nif %v := %v.UnmarshalText([]byte(%v)); %v != nil {\nreturn %v\n}\n", err, dst, x, err, g.errorf(c, "%w", err))
+			accepted = append(accepted, "String")
+		}
+		if t.methods&hasUnmarshalBinary != 0 {
+			err := g.tmp("err")
+			fmt.Fprintf(w, "case nson.Binary:\nif %v := %v.UnmarshalBinary([]byte(%v)); %v != nil {\nreturn %v\n}\n", err, dst, x, err, g.errorf(c, "%w", err))
+			accepted = append(accepted, "Binary")
+		}
+		expected = strings.Join(accepted, " or ")
+
+	case kindStruct:
+		err := g.tmp("err")
+		fmt.Fprintf(w, "case nson.Map:\nif %v := %v.UnmarshalNSON(%v); %v != nil {\nreturn %v\n}\n", err, dst, x, err, g.errorf(c, "%w", err))
+		expected = "Map"
+	}
+
+	fmt.Fprintf(w, "default:\nreturn %v\n}\n", g.errorf(c, "expected "+expected+", got %T", val))
+}
+
+// encodingMethod 返回 kindEncoding 编码使用的方法和 NSON 类型，TextMarshaler 优先
+func encodingMethod(t *fieldType) (string, string) {
+	if t.methods&hasMarshalText != 0 {
+		return "MarshalText", "String"
+	}
+
+	return "MarshalBinary", "Binary"
+}
+
+// defaultExpr 按字段类型解析 default 选项的值，返回生成的表达式，规则与 parseDefault 相同。
+// 指针返回指向的值的表达式
+func defaultExpr(t *fieldType, s string) (string, error) {
+	if t.kind == kindPointer {
+		if t.elem.kind == kindPointer {
+			return "", fmt.Errorf("unsupported type %v", t.goType)
+		}
+		return defaultExpr(t.elem, s)
+	}
+
+	if t.kind == kindTime || t.goType == "nson.Timestamp" {
+		ms, err := nsontag.ParseMillis(s)
+		if err != nil {
+			return "", err
+		}
+		if t.kind == kindTime {
+			return fmt.Sprintf("time.UnixMilli(%d)", ms), nil
+		}
+		if ms < 0 {
+			return "", fmt.Errorf("timestamp before 1970")
+		}
+		return fmt.Sprintf("nson.Timestamp(%d)", ms), nil
+	}
+
+	if t.kind != kindBasic {
+		return "", fmt.Errorf("unsupported type %v", t.goType)
+	}
+
+	var lit string
+	switch t.base {
+	case "bool":
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return "", err
+		}
+		lit = strconv.FormatBool(b)
+
+	case "string":
+		lit = strconv.Quote(s)
+
+	case "float32", "float64":
+		bits := 64
+		if t.base == "float32" {
+			bits = 32
+		}
+		f, err := strconv.ParseFloat(s, bits)
+		if err != nil {
+			return "", err
+		}
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return "", fmt.Errorf("unsupported value %v", f)
+		}
+		lit = strconv.FormatFloat(f, 'g', -1, bits)
+
+	default:
+		bits := intBits[strings.TrimPrefix(t.base, "u")]
+		if strings.HasPrefix(t.base, "u") {
+			u, err := strconv.ParseUint(s, 0, bits)
+			if err != nil {
+				return "", err
+			}
+			lit = strconv.FormatUint(u, 10)
+		} else {
+			i, err := strconv.ParseInt(s, 0, bits)
+			if err != nil {
+				return "", err
+			}
+			lit = strconv.FormatInt(i, 10)
+		}
+	}
+
+	return fmt.Sprintf("%v(%v)", t.goType, lit), nil
+}
+
+// intBits 整数类型的位数，与 reflect.Type.Bits 相同，无符号类型去掉前缀 u
+var intBits = map[string]int{
+	"int8":  8,
+	"int16": 16,
+	"int32": 32,
+	"int":   64,
+	"int64": 64,
+}
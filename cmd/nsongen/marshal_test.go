@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// marshal_test/generated_nson_test.go 由 go generate 生成，生成结果必须与之一致
+func TestGenerateMarshalGolden(t *testing.T) {
+	input := filepath.Join("..", "..", "marshal_test", "generated_test.go")
+
+	src, err := generateMarshal(input, nil)
+	if err != nil {
+		t.Fatalf("generateMarshal failed: %v", err)
+	}
+
+	golden, err := os.ReadFile(filepath.Join("..", "..", "marshal_test", "generated_nson_test.go"))
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+
+	if !bytes.Equal(src, golden) {
+		t.Errorf("Generated code is out of date, run go generate in marshal_test")
+	}
+}
+
+func TestGenerateMarshalErrors(t *testing.T) {
+	tests := map[string]string{
+		"type A struct{ X any }":                                        "field X: unsupported type any",
+		"type A struct{ X map[int]string }":                             "field X: map key must be string",
+		"type A struct{ X string `nson:\"x\"`; Y string `nson:\"x\"` }": `duplicate key "x"`,
+		"type A struct{ X struct{ Y int } }":                            "field X: unsupported type",
+		"type A struct{ X []int `nson:\"x,default=1\"` }":               `field X: invalid default "1": unsupported type []int`,
+		"type A struct{ X int8 `nson:\"x,default=300\"` }":              `field X: invalid default "300"`,
+		"type A int": "type A is not a struct",
+		"type A struct{ X map[string]string `nson:\",remain\"` }":            "field X: remain requires nson.Map",
+		"type A struct{ N int `nson:\",inline\"` }":                          "field N: inline requires a struct, pointer to struct or map with string keys",
+		"type A struct{ B *A `nson:\",inline\"` }":                           "inline cycle through B",
+		"type A struct{ X, Y B `nson:\",inline\"` }; type B struct{ V int }": `duplicate key "V" in fields V and V`,
+		"type A struct{ N int64 `nson:\"n,type=i32\"` }":                     "field N: type option is not supported",
+	}
+
+	for code, want := range tests {
+		dir := t.TempDir()
+		input := filepath.Join(dir, "a.go")
+		if err := os.WriteFile(input, []byte("package p\n\n"+code+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := generateMarshal(input, []string{"A"})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%v: expected error %q, got %v", code, want, err)
+		}
+	}
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "a.go")
+	if err := os.WriteFile(input, []byte("package p\n\ntype A struct{}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := generateMarshal(input, nil); err == nil {
+		t.Error("Expected error when no types are annotated")
+	}
+}
@@ -0,0 +1,166 @@
+package nson
+
+import (
+	"encoding"
+	"reflect"
+	"runtime"
+	"sync"
+)
+
+// implMode 类型实现接口的方式
+type implMode uint8
+
+const (
+	implNone    implMode = iota // 没有实现，或者方法是从嵌入字段提升的
+	implValue                   // 值实现了接口
+	implPointer                 // 只有指针实现了接口，需要取地址
+)
+
+// typeCodec 类型实现的编解码接口，每个类型只计算一次
+type typeCodec struct {
+	marshaler         implMode
+	unmarshaler       implMode
+	mapMarshaler      implMode
+	mapUnmarshaler    implMode
+	mapAppender       implMode
+	textMarshaler     implMode
+	textUnmarshaler   implMode
+	binaryMarshaler   implMode
+	binaryUnmarshaler implMode
+}
+
+var typeCodecCache sync.Map // reflect.Type -> *typeCodec
+
+// codecOf 获取或计算类型 t 的 typeCodec
+func codecOf(t reflect.Type) *typeCodec {
+	if c, ok := typeCodecCache.Load(t); ok {
+		return c.(*typeCodec)
+	}
+
+	c := &typeCodec{
+		marshaler:         implModeOf(t, reflect.TypeFor[Marshaler]()),
+		unmarshaler:       implModeOf(t, reflect.TypeFor[Unmarshaler]()),
+		mapMarshaler:      implModeOf(t, reflect.TypeFor[MapMarshaler]()),
+		mapUnmarshaler:    implModeOf(t, reflect.TypeFor[MapUnmarshaler]()),
+		mapAppender:       implModeOf(t, reflect.TypeFor[mapAppender]()),
+		textMarshaler:     implModeOf(t, reflect.TypeFor[encoding.TextMarshaler]()),
+		textUnmarshaler:   implModeOf(t, reflect.TypeFor[encoding.TextUnmarshaler]()),
+		binaryMarshaler:   implModeOf(t, reflect.TypeFor[encoding.BinaryMarshaler]()),
+		binaryUnmarshaler: implModeOf(t, reflect.TypeFor[encoding.BinaryUnmarshaler]()),
+	}
+
+	actual, _ := typeCodecCache.LoadOrStore(t, c)
+	return actual.(*typeCodec)
+}
+
+// implModeOf 检查类型 t 或 *t 是否实现了接口 it，从嵌入字段提升的方法不算在内
+func implModeOf(t, it reflect.Type) implMode {
+	var mode implMode
+
+	switch {
+	case t.Implements(it):
+		mode = implValue
+	case t.Kind() != reflect.Pointer && t.Kind() != reflect.Interface && reflect.PointerTo(t).Implements(it):
+		mode = implPointer
+	default:
+		return implNone
+	}
+
+	if promotedMethods(t, it) {
+		return implNone
+	}
+
+	return mode
+}
+
+// promotedMethods 检查结构体 t 上接口 it 的方法是否来自嵌入字段：某个直接嵌入的字段
+// （取指针的方法集）中有同名方法，且 t 上的方法是编译器生成的转发方法，而不是结构体
+// 自己声明的。嵌入字段的方法集已经包含更深层提升的方法，因此只需要检查直接嵌入的字段
+func promotedMethods(t, it reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := 0; i < it.NumMethod(); i++ {
+		name := it.Method(i).Name
+		if embeddedMethod(t, name) && !declaredMethod(t, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// embeddedMethod 检查结构体 t 是否有直接嵌入的字段带有名为 name 的方法
+func embeddedMethod(t reflect.Type, name string) bool {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.Anonymous {
+			continue
+		}
+
+		ft := field.Type
+		if ft.Kind() != reflect.Pointer && ft.Kind() != reflect.Interface {
+			ft = reflect.PointerTo(ft)
+		}
+
+		if _, ok := ft.MethodByName(name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// declaredMethod 检查结构体 t 的方法 name 是否由 t 自己声明。先查值的方法集，值接收者
+// 的方法在 *t 上也是转发方法；提升的方法在 t 和 *t 上都是编译器生成的转发方法，
+// 位置为 <autogenerated>
+func declaredMethod(t reflect.Type, name string) bool {
+	m, ok := t.MethodByName(name)
+	if !ok {
+		if m, ok = reflect.PointerTo(t).MethodByName(name); !ok {
+			return false
+		}
+	}
+
+	fn := runtime.FuncForPC(m.Func.Pointer())
+	if fn == nil {
+		return false
+	}
+
+	file, _ := fn.FileLine(fn.Entry())
+	return file != "<autogenerated>"
+}
+
+// implementer 按类型缓存的实现方式 mode 将 rv 转换为接口 T，nil 指针和接口返回 false。
+// rv 不可寻址时（如 map 的值）复制一份再取指针
+func implementer[T any](rv reflect.Value, mode implMode) (T, bool) {
+	var zero T
+
+	if mode == implNone || !rv.CanInterface() {
+		return zero, false
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return zero, false
+		}
+	}
+
+	if mode == implValue {
+		return rv.Interface().(T), true
+	}
+
+	if !rv.CanAddr() {
+		c := reflect.New(rv.Type()).Elem()
+		c.Set(rv)
+		rv = c
+	}
+
+	return rv.Addr().Interface().(T), true
+}
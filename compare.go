@@ -0,0 +1,309 @@
+package nson
+
+import (
+	"bytes"
+	"cmp"
+	"math"
+	"slices"
+	"strings"
+)
+
+// Equal 判断两个值是否完全相同：类型和值都必须一致。
+// Map 按键比较，Array 按顺序比较；浮点数 NaN 视为与自身相等。
+func Equal(a, b Value) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	if a.DataType() != b.DataType() {
+		return false
+	}
+
+	switch x := a.(type) {
+	case F32:
+		return cmp.Compare(x, b.(F32)) == 0
+	case F64:
+		return cmp.Compare(x, b.(F64)) == 0
+	case Binary:
+		return bytes.Equal(x, b.(Binary))
+	case Array:
+		y := b.(Array)
+		if len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !Equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case Map:
+		y := b.(Map)
+		if len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, has := y[k]
+			if !has || !Equal(v, w) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+// EqualNumeric 与 Equal 相同，但数值按数学值比较，不区分宽度和整数/浮点，
+// 如 I32(1)、U8(1)、F64(1.0) 两两相等。
+func EqualNumeric(a, b Value) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	if x, ok := toNumber(a); ok {
+		if y, ok := toNumber(b); ok {
+			return compareNumber(x, y) == 0
+		}
+		return false
+	}
+
+	if a.DataType() != b.DataType() {
+		return false
+	}
+
+	switch x := a.(type) {
+	case Array:
+		y := b.(Array)
+		if len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !EqualNumeric(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case Map:
+		y := b.(Map)
+		if len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, has := y[k]
+			if !has || !EqualNumeric(v, w) {
+				return false
+			}
+		}
+		return true
+	default:
+		return Equal(a, b)
+	}
+}
+
+// Compare 比较两个值，返回 -1、0 或 1。
+//
+// 先按类型排序：
+//
+//	nil < Null < 数值 < String < Map < Array < Binary < Id < Bool < Timestamp
+//
+// 同类型再按值排序。所有数值类型属于同一类，按数学值比较，NaN 小于其他数值，
+// 因此 Compare(I32(1), F64(1)) == 0，与 EqualNumeric 一致。
+// Map 先将键排序，再依次比较键和值。
+func Compare(a, b Value) int {
+	ra, rb := typeRank(a), typeRank(b)
+	if ra != rb {
+		if ra < rb {
+			return -1
+		}
+		return 1
+	}
+
+	switch x := a.(type) {
+	case nil, Null:
+		return 0
+	case String:
+		return strings.Compare(string(x), string(b.(String)))
+	case Binary:
+		return bytes.Compare(x, b.(Binary))
+	case Id:
+		y := b.(Id)
+		return bytes.Compare(x[:], y[:])
+	case Bool:
+		y := b.(Bool)
+		if x == y {
+			return 0
+		}
+		if !x {
+			return -1
+		}
+		return 1
+	case Timestamp:
+		return cmp.Compare(x, b.(Timestamp))
+	case Array:
+		y := b.(Array)
+		for i := 0; i < len(x) && i < len(y); i++ {
+			if c := Compare(x[i], y[i]); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(len(x), len(y))
+	case Map:
+		y := b.(Map)
+		xk, yk := sortedKeys(x), sortedKeys(y)
+		for i := 0; i < len(xk) && i < len(yk); i++ {
+			if c := strings.Compare(xk[i], yk[i]); c != 0 {
+				return c
+			}
+			if c := Compare(x[xk[i]], y[yk[i]]); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(len(xk), len(yk))
+	}
+
+	x, _ := toNumber(a)
+	y, _ := toNumber(b)
+	return compareNumber(x, y)
+}
+
+// typeRank 返回 Compare 使用的类型顺序
+func typeRank(v Value) int {
+	if v == nil {
+		return 0
+	}
+
+	if v.DataType().IsNumeric() {
+		return 2
+	}
+
+	switch v.DataType() {
+	case DataTypeNULL:
+		return 1
+	case DataTypeSTRING:
+		return 3
+	case DataTypeMAP:
+		return 4
+	case DataTypeARRAY:
+		return 5
+	case DataTypeBINARY:
+		return 6
+	case DataTypeID:
+		return 7
+	case DataTypeBOOL:
+		return 8
+	case DataTypeTIMESTAMP:
+		return 9
+	default:
+		return 10
+	}
+}
+
+func sortedKeys(m Map) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+type numberKind uint8
+
+const (
+	numberInt numberKind = iota
+	numberUint
+	numberFloat
+)
+
+// number 是数值的统一表示，用于跨宽度比较和运算
+type number struct {
+	kind numberKind
+	i    int64
+	u    uint64
+	f    float64
+}
+
+// toNumber 将数值类型转换为 number，非数值返回 false
+func toNumber(v Value) (number, bool) {
+	switch x := v.(type) {
+	case I8:
+		return number{kind: numberInt, i: int64(x)}, true
+	case I16:
+		return number{kind: numberInt, i: int64(x)}, true
+	case I32:
+		return number{kind: numberInt, i: int64(x)}, true
+	case I64:
+		return number{kind: numberInt, i: int64(x)}, true
+	case U8:
+		return number{kind: numberUint, u: uint64(x)}, true
+	case U16:
+		return number{kind: numberUint, u: uint64(x)}, true
+	case U32:
+		return number{kind: numberUint, u: uint64(x)}, true
+	case U64:
+		return number{kind: numberUint, u: uint64(x)}, true
+	case F32:
+		return number{kind: numberFloat, f: float64(x)}, true
+	case F64:
+		return number{kind: numberFloat, f: float64(x)}, true
+	default:
+		return number{}, false
+	}
+}
+
+// float64 返回数值的近似浮点表示
+func (self number) float64() float64 {
+	switch self.kind {
+	case numberInt:
+		return float64(self.i)
+	case numberUint:
+		return float64(self.u)
+	default:
+		return self.f
+	}
+}
+
+// compareNumber 精确比较两个数值，不会因为转换为 float64 而丢失精度
+func compareNumber(a, b number) int {
+	switch {
+	case a.kind == numberInt && b.kind == numberInt:
+		return cmp.Compare(a.i, b.i)
+	case a.kind == numberUint && b.kind == numberUint:
+		return cmp.Compare(a.u, b.u)
+	case a.kind == numberInt && b.kind == numberUint:
+		if a.i < 0 {
+			return -1
+		}
+		return cmp.Compare(uint64(a.i), b.u)
+	case a.kind == numberUint && b.kind == numberInt:
+		return -compareNumber(b, a)
+	case a.kind == numberFloat && b.kind == numberFloat:
+		return cmp.Compare(a.f, b.f)
+	case a.kind == numberFloat:
+		return -compareNumber(b, a)
+	}
+
+	// a 是整数，b 是浮点数
+	f := b.f
+	if math.IsNaN(f) {
+		return 1
+	}
+
+	if c := cmp.Compare(a.float64(), f); c != 0 {
+		return c
+	}
+
+	// 转换后相等时 f 必为整数，再在整数域内精确比较
+	if a.kind == numberInt {
+		if f >= math.Exp2(63) {
+			return -1
+		}
+		return cmp.Compare(a.i, int64(f))
+	}
+
+	if f >= math.Exp2(64) {
+		return -1
+	}
+	return cmp.Compare(a.u, uint64(f))
+}
@@ -0,0 +1,107 @@
+package nson
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	a := Map{
+		"a": I32(1),
+		"b": Array{String("x"), Binary{1, 2}},
+		"c": Map{"d": F64(math.NaN())},
+	}
+	b := Map{
+		"a": I32(1),
+		"b": Array{String("x"), Binary{1, 2}},
+		"c": Map{"d": F64(math.NaN())},
+	}
+
+	if !Equal(a, b) {
+		t.Error("Expected maps to be equal")
+	}
+
+	b["a"] = I64(1)
+	if Equal(a, b) {
+		t.Error("Expected I32(1) and I64(1) to differ")
+	}
+
+	if !EqualNumeric(a, b) {
+		t.Error("Expected I32(1) and I64(1) to be numerically equal")
+	}
+
+	if Equal(Array{I32(1)}, Array{I32(1), I32(2)}) {
+		t.Error("Expected arrays of different length to differ")
+	}
+}
+
+func TestEqualNumeric(t *testing.T) {
+	tests := []struct {
+		a, b  Value
+		equal bool
+	}{
+		{U8(255), I64(255), true},
+		{I8(-1), U64(math.MaxUint64), false},
+		{F32(1.5), F64(1.5), true},
+		{I32(1), F64(1.0), true},
+		{I32(1), F64(1.1), false},
+		{I64(math.MaxInt64), F64(math.Exp2(63)), false},
+		{U64(math.MaxUint64), F64(math.Exp2(64)), false},
+		{I32(1), String("1"), false},
+		{Timestamp(1), U64(1), false},
+	}
+
+	for _, tt := range tests {
+		if got := EqualNumeric(tt.a, tt.b); got != tt.equal {
+			t.Errorf("EqualNumeric(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.equal)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b Value
+		want int
+	}{
+		{Null{}, I32(0), -1},
+		{I32(0), String(""), -1},
+		{String("z"), Map{}, -1},
+		{Map{}, Array{}, -1},
+		{Array{}, Binary{}, -1},
+		{Binary{}, Id{}, -1},
+		{Id{}, Bool(false), -1},
+		{Bool(true), Timestamp(0), -1},
+		{I8(-1), U8(0), -1},
+		{U64(math.MaxUint64), I64(math.MaxInt64), 1},
+		{F64(math.NaN()), I32(math.MinInt32), -1},
+		{I64(1 << 62), F64(1 << 62), 0},
+		{String("a"), String("b"), -1},
+		{Array{I32(1)}, Array{I32(1), I32(0)}, -1},
+		{Map{"a": I32(2)}, Map{"b": I32(1)}, -1},
+		{Map{"a": I32(1)}, Map{"a": I32(2)}, -1},
+		{Bool(false), Bool(true), -1},
+	}
+
+	for _, tt := range tests {
+		if got := Compare(tt.a, tt.b); got != tt.want {
+			t.Errorf("Compare(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+		if got := Compare(tt.b, tt.a); got != -tt.want {
+			t.Errorf("Compare(%v, %v) = %v, want %v", tt.b, tt.a, got, -tt.want)
+		}
+	}
+}
+
+func TestCompareSort(t *testing.T) {
+	values := []Value{String("b"), I32(3), Null{}, F64(1.5), U8(2), String("a")}
+
+	slices.SortFunc(values, Compare)
+
+	want := []Value{Null{}, F64(1.5), U8(2), I32(3), String("a"), String("b")}
+	for i := range want {
+		if !Equal(values[i], want[i]) {
+			t.Fatalf("Unexpected order: %v", values)
+		}
+	}
+}
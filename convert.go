@@ -0,0 +1,221 @@
+package nson
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+)
+
+// NumericConversion Unmarshal 时数值类型与字段类型不一致的处理方式。
+// 转换时 int 和 uint 按平台的实际位数，64 位平台上与 int64 和 uint64 相同
+type NumericConversion uint8
+
+const (
+	// NumericExact 要求类型完全一致，int64 和 uint64 也接受 Timestamp
+	NumericExact NumericConversion = iota
+	// NumericWiden 允许不会丢失精度的扩展，如 I32 到 int64、U8 到 int16、I16 到 float32
+	NumericWiden
+	// NumericLossless 另外允许值能精确表示时的收窄以及整数与浮点数互转，否则返回错误
+	NumericLossless
+	// NumericLenient 另外允许 String 与数值互相转换
+	NumericLenient
+)
+
+// convert 将 val 转换为类型 t 对应的 NSON 类型，类型已经一致时原样返回，不是数值或不允许转换时返回 nil
+func (self NumericConversion) convert(val Value, t reflect.Type) (Value, error) {
+	if t.Kind() == reflect.String {
+		if self < NumericLenient || !val.DataType().IsNumeric() {
+			return nil, nil
+		}
+		return String(formatNumber(val)), nil
+	}
+
+	// int 和 uint 按实际位数，64 位平台上能容纳 I64 和 U64
+	dt := exactDataType(t)
+	if dt == 0 {
+		return nil, nil
+	}
+	if val.DataType() == dt {
+		return val, nil
+	}
+
+	if s, ok := val.(String); ok {
+		if self < NumericLenient {
+			return nil, nil
+		}
+		return parseNumber(string(s), dt)
+	}
+
+	n, ok := toNumber(val)
+	if !ok {
+		return nil, nil
+	}
+
+	if self == NumericWiden && !widens(val.DataType(), dt) {
+		return nil, nil
+	}
+
+	return convertNumber(n, val, dt)
+}
+
+// setNumber 将 convert 的数值结果写入数值字段，结果类型与字段的实际位数一致
+func setNumber(rv reflect.Value, val Value) {
+	n, _ := toNumber(val)
+	switch n.kind {
+	case numberInt:
+		rv.SetInt(n.i)
+	case numberUint:
+		rv.SetUint(n.u)
+	default:
+		rv.SetFloat(n.f)
+	}
+}
+
+// kindDataType 返回 Kind 对应的数值类型，与 marshalValue 一致
+func kindDataType(k reflect.Kind) (DataType, bool) {
+	switch k {
+	case reflect.Int8:
+		return DataTypeI8, true
+	case reflect.Int16:
+		return DataTypeI16, true
+	case reflect.Int32, reflect.Int:
+		return DataTypeI32, true
+	case reflect.Int64:
+		return DataTypeI64, true
+	case reflect.Uint8:
+		return DataTypeU8, true
+	case reflect.Uint16:
+		return DataTypeU16, true
+	case reflect.Uint32, reflect.Uint:
+		return DataTypeU32, true
+	case reflect.Uint64:
+		return DataTypeU64, true
+	case reflect.Float32:
+		return DataTypeF32, true
+	case reflect.Float64:
+		return DataTypeF64, true
+	default:
+		return 0, false
+	}
+}
+
+// intBits 返回整数类型的位数，其他类型返回 0
+func intBits(dt DataType) int {
+	switch dt {
+	case DataTypeI8, DataTypeU8:
+		return 8
+	case DataTypeI16, DataTypeU16:
+		return 16
+	case DataTypeI32, DataTypeU32:
+		return 32
+	case DataTypeI64, DataTypeU64:
+		return 64
+	default:
+		return 0
+	}
+}
+
+// widens 检查 src 的所有值是否都能用 dst 精确表示
+func widens(src, dst DataType) bool {
+	srcBits, dstBits := intBits(src), intBits(dst)
+
+	switch {
+	case dst == DataTypeF64:
+		return src == DataTypeF32 || (srcBits > 0 && srcBits <= 32)
+	case dst == DataTypeF32:
+		return srcBits > 0 && srcBits <= 16
+	case srcBits == 0 || dstBits == 0:
+		return false
+	case isSignedType(src):
+		return isSignedType(dst) && srcBits <= dstBits
+	case isSignedType(dst):
+		return srcBits < dstBits
+	default:
+		return srcBits <= dstBits
+	}
+}
+
+// convertNumber 将数值精确转换为 dt 类型，超出范围或无法精确表示时返回错误
+func convertNumber(n number, val Value, dt DataType) (Value, error) {
+	if dt == DataTypeF32 || dt == DataTypeF64 {
+		if n.kind == numberFloat {
+			if dt == DataTypeF64 {
+				return F64(n.f), nil
+			}
+			if f := float32(n.f); float64(f) == n.f || math.IsNaN(n.f) {
+				return F32(f), nil
+			}
+			return nil, fmt.Errorf("%v cannot be represented exactly as %v", val, dt)
+		}
+
+		bf := new(big.Float).SetInt(n.bigInt())
+		if dt == DataTypeF64 {
+			if f, acc := bf.Float64(); acc == big.Exact {
+				return F64(f), nil
+			}
+		} else if f, acc := bf.Float32(); acc == big.Exact {
+			return F32(f), nil
+		}
+		return nil, fmt.Errorf("%v cannot be represented exactly as %v", val, dt)
+	}
+
+	r := n.bigInt()
+	if n.kind == numberFloat {
+		if math.IsNaN(n.f) || math.IsInf(n.f, 0) || math.Trunc(n.f) != n.f {
+			return nil, fmt.Errorf("%v cannot be represented exactly as %v", val, dt)
+		}
+		r, _ = new(big.Float).SetFloat64(n.f).Int(nil)
+	}
+
+	v, ok := intValue(r, dt)
+	if !ok {
+		return nil, fmt.Errorf("%v overflows %v", val, dt)
+	}
+
+	return v, nil
+}
+
+// parseNumber 将字符串解析为 dt 类型
+func parseNumber(s string, dt DataType) (Value, error) {
+	switch dt {
+	case DataTypeF32:
+		if f, err := strconv.ParseFloat(s, 32); err == nil {
+			return F32(f), nil
+		}
+	case DataTypeF64:
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return F64(f), nil
+		}
+	default:
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return convertNumber(number{kind: numberInt, i: i}, String(s), dt)
+		}
+		if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return convertNumber(number{kind: numberUint, u: u}, String(s), dt)
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return convertNumber(number{kind: numberFloat, f: f}, String(s), dt)
+		}
+	}
+
+	return nil, fmt.Errorf("cannot parse %q as %v", s, dt)
+}
+
+// formatNumber 将数值格式化为字符串，浮点数使用能还原原值的最短形式
+func formatNumber(val Value) string {
+	switch v := val.(type) {
+	case F32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case F64:
+		return strconv.FormatFloat(float64(v), 'g', -1, 64)
+	}
+
+	n, _ := toNumber(val)
+	if n.kind == numberUint {
+		return strconv.FormatUint(n.u, 10)
+	}
+
+	return strconv.FormatInt(n.i, 10)
+}
@@ -0,0 +1,171 @@
+package nson
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestNumericConversion(t *testing.T) {
+	type target struct {
+		I8  int8
+		I64 int64
+		Int int
+		U16 uint16
+		U64 uint64
+		F32 float32
+		F64 float64
+		Str string
+	}
+
+	tests := []struct {
+		mode  NumericConversion
+		field string
+		in    Value
+		want  any
+		err   string
+	}{
+		{NumericExact, "I64", I32(1), nil, "expected I64 or Timestamp, got nson.I32"},
+		{NumericWiden, "I64", I32(-5), int64(-5), ""},
+		{NumericWiden, "Int", U8(200), 200, ""},
+		{NumericWiden, "Int", U16(7), 7, ""},
+		{NumericWiden, "I64", U32(math.MaxUint32), int64(math.MaxUint32), ""},
+		{NumericWiden, "U64", I8(1), nil, "expected U64 or Timestamp, got nson.I8"},
+		{NumericWiden, "F32", I16(-300), float32(-300), ""},
+		{NumericWiden, "F32", I32(1), nil, "expected F32, got nson.I32"},
+		{NumericWiden, "F64", U32(9), float64(9), ""},
+		{NumericWiden, "F64", F32(1.5), float64(1.5), ""},
+		{NumericWiden, "I64", F64(1), nil, "expected I64 or Timestamp, got nson.F64"},
+		{NumericWiden, "Str", I32(1), nil, "expected String, got nson.I32"},
+
+		{NumericLossless, "I8", I64(-128), int8(-128), ""},
+		{NumericLossless, "I8", I64(128), nil, "I64(128) overflows I8"},
+		{NumericLossless, "U16", I32(-1), nil, "I32(-1) overflows U16"},
+		{NumericLossless, "U64", I64(5), uint64(5), ""},
+		{NumericLossless, "I64", U64(math.MaxUint64), nil, "overflows I64"},
+		{NumericLossless, "Int", F64(42), 42, ""},
+		{NumericLossless, "Int", F64(4.5), nil, "cannot be represented exactly as"},
+		{NumericLossless, "Int", F64(math.Inf(1)), nil, "cannot be represented exactly as"},
+		{NumericLossless, "F32", F64(0.5), float32(0.5), ""},
+		{NumericLossless, "F32", F64(0.1), nil, "cannot be represented exactly as F32"},
+		{NumericLossless, "F64", I64(1 << 53), float64(1 << 53), ""},
+		{NumericLossless, "F64", I64(1<<53 + 1), nil, "cannot be represented exactly as F64"},
+		{NumericLossless, "I64", String("1"), nil, "expected I64 or Timestamp, got nson.String"},
+
+		{NumericLenient, "I64", String("-12"), int64(-12), ""},
+		{NumericLenient, "U64", String("18446744073709551615"), uint64(math.MaxUint64), ""},
+		{NumericLenient, "I8", String("1e2"), int8(100), ""},
+		{NumericLenient, "I8", String("300"), nil, "String(300) overflows I8"},
+		{NumericLenient, "F32", String("0.1"), float32(0.1), ""},
+		{NumericLenient, "Int", String("x"), nil, `cannot parse "x" as`},
+		{NumericLenient, "Str", I32(-3), "-3", ""},
+		{NumericLenient, "Str", U64(math.MaxUint64), "18446744073709551615", ""},
+		{NumericLenient, "Str", F32(0.1), "0.1", ""},
+		{NumericLenient, "Str", Bool(true), nil, "expected String, got nson.Bool"},
+	}
+
+	for _, tt := range tests {
+		var v target
+		err := UnmarshalOptions{NumericConversion: tt.mode}.Unmarshal(Map{tt.field: tt.in}, &v)
+
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("mode %v %v <- %v: expected error %q, got %v", tt.mode, tt.field, tt.in, tt.err, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("mode %v %v <- %v: unexpected error %v", tt.mode, tt.field, tt.in, err)
+			continue
+		}
+
+		var got any
+		switch tt.field {
+		case "I8":
+			got = v.I8
+		case "I64":
+			got = v.I64
+		case "Int":
+			got = v.Int
+		case "U16":
+			got = v.U16
+		case "U64":
+			got = v.U64
+		case "F32":
+			got = v.F32
+		case "F64":
+			got = v.F64
+		case "Str":
+			got = v.Str
+		}
+
+		if got != tt.want {
+			t.Errorf("mode %v %v <- %v: got %#v, want %#v", tt.mode, tt.field, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNumericConversionPlatformInt(t *testing.T) {
+	if strconv.IntSize != 64 {
+		t.Skip("int is not 64 bits")
+	}
+
+	var v struct {
+		Int  int
+		Uint uint
+	}
+
+	for _, mode := range []NumericConversion{NumericWiden, NumericLossless} {
+		if err := (UnmarshalOptions{NumericConversion: mode}).Unmarshal(Map{"Int": I64(-5_000_000_000), "Uint": U64(5_000_000_000)}, &v); err != nil {
+			t.Fatalf("mode %v: Unmarshal failed: %v", mode, err)
+		}
+		if v.Int != -5_000_000_000 || v.Uint != 5_000_000_000 {
+			t.Errorf("mode %v: unexpected result %+v", mode, v)
+		}
+	}
+
+	lossless := UnmarshalOptions{NumericConversion: NumericLossless}
+	if err := lossless.Unmarshal(Map{"Int": U64(math.MaxInt64), "Uint": I64(1 << 40)}, &v); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if v.Int != math.MaxInt64 || v.Uint != 1<<40 {
+		t.Errorf("Unexpected result: %+v", v)
+	}
+
+	if err := lossless.Unmarshal(Map{"Int": U64(math.MaxUint64)}, &v); err == nil || !strings.Contains(err.Error(), "overflows I64") {
+		t.Errorf("Expected overflow error, got %v", err)
+	}
+
+	// 不转换时 int 仍然只接受 I32
+	if err := Unmarshal(Map{"Int": I64(1)}, &v); err == nil {
+		t.Error("Expected error without conversion")
+	}
+}
+
+func TestNumericConversionNested(t *testing.T) {
+	var v struct {
+		Counts map[string]int64
+		Sizes  []uint32
+		Ptr    *float64
+	}
+
+	m := Map{
+		"Counts": Map{"a": I8(1)},
+		"Sizes":  Array{U8(1), U16(2)},
+		"Ptr":    I32(3),
+	}
+
+	if err := Unmarshal(m, &v); err == nil {
+		t.Fatal("Expected error without conversion")
+	}
+
+	if err := (UnmarshalOptions{NumericConversion: NumericWiden}).Unmarshal(m, &v); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if v.Counts["a"] != 1 || v.Sizes[1] != 2 || *v.Ptr != 3 {
+		t.Errorf("Unexpected result: %+v", v)
+	}
+}
@@ -0,0 +1,117 @@
+package nson
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/danclive/nson-go/internal/nsontag"
+)
+
+// parseDefault 按字段类型解析 default 选项的值：
+//
+//   - time.Duration 使用 time.ParseDuration，如 "1m30s"
+//   - time.Time 和 Timestamp 接受毫秒时间戳或 RFC 3339 格式，精确到毫秒
+//   - 实现了 encoding.TextUnmarshaler 的类型使用 UnmarshalText
+//   - 布尔、整数、浮点数和字符串按 Kind 解析，整数支持 0x 等前缀
+//   - 指针解析为指向的类型
+func parseDefault(s string, t reflect.Type) (reflect.Value, error) {
+	v := reflect.New(t).Elem()
+
+	switch t {
+	case reflect.TypeFor[time.Duration]():
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetInt(int64(d))
+		return v, nil
+
+	case reflect.TypeFor[time.Time]():
+		ms, err := nsontag.ParseMillis(s)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.Set(reflect.ValueOf(time.UnixMilli(ms)))
+		return v, nil
+
+	case reflect.TypeFor[Timestamp]():
+		ms, err := nsontag.ParseMillis(s)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if ms < 0 {
+			return reflect.Value{}, fmt.Errorf("timestamp before 1970")
+		}
+		v.SetUint(uint64(ms))
+		return v, nil
+	}
+
+	if t.Kind() == reflect.Pointer {
+		elem, err := parseDefault(s, t.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.Set(reflect.New(t.Elem()))
+		v.Elem().Set(elem)
+		return v, nil
+	}
+
+	if u, ok := implementer[encoding.TextUnmarshaler](v, codecOf(t).textUnmarshaler); ok {
+		if err := u.UnmarshalText([]byte(s)); err != nil {
+			return reflect.Value{}, err
+		}
+		return v, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 0, t.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetInt(i)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 0, t.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetUint(u)
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, t.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetFloat(f)
+
+	case reflect.String:
+		v.SetString(s)
+
+	default:
+		return reflect.Value{}, fmt.Errorf("unsupported type %v", t)
+	}
+
+	return v, nil
+}
+
+// setDefault 将缓存的默认值写入字段，指针每次分配新的值
+func setDefault(fv reflect.Value, def reflect.Value) {
+	if def.Kind() == reflect.Pointer {
+		p := reflect.New(def.Type().Elem())
+		p.Elem().Set(def.Elem())
+		def = p
+	}
+
+	fv.Set(def)
+}
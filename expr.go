@@ -0,0 +1,724 @@
+package nson
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 表达式使用 NSON 值表示，语法参考 MongoDB 的聚合表达式：
+//
+//	String("$price")                                   // 字段引用，支持点分路径
+//	Map{"$multiply": Array{String("$price"), String("$qty")}}
+//	Map{"$concat": Array{String("$first"), String(" "), String("$last")}}
+//	Map{"$year": String("$created")}                   // 从 Timestamp 取年份
+//	Map{"$cond": Array{Map{"$gt": Array{String("$qty"), I32(10)}}, String("bulk"), String("retail")}}
+//	Map{"$literal": String("$not a field")}
+//
+// 只有一个以 "$" 开头的键的 Map 是运算符，其他 Map 逐个字段计算后组成新的 Map，
+// Array 逐个元素计算，其他值为常量。不存在的字段计算结果为 Null。
+//
+// 算术运算按 PromoteTypes 的规则提升类型，整数溢出返回错误。
+// Timestamp 视为毫秒时间戳：Timestamp 加减整数得到 Timestamp，两个 Timestamp 相减得到 I64。
+// 日期运算符按 UTC 计算。
+
+// TypeError 表达式的操作数类型错误
+type TypeError struct {
+	Op       string
+	Expected string
+	Got      DataType
+}
+
+func (self *TypeError) Error() string {
+	return fmt.Sprintf("%v: expected %v, got %v", self.Op, self.Expected, self.Got)
+}
+
+// Expr 编译后的表达式
+type Expr struct {
+	eval exprFunc
+}
+
+// exprFunc 计算表达式，返回值为 nil 表示字段不存在
+type exprFunc func(doc Map) (Value, error)
+
+// Eval 在 doc 上计算表达式
+func Eval(expr Value, doc Map) (Value, error) {
+	e, err := CompileExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.Eval(doc)
+}
+
+// CompileExpr 编译表达式，便于对多个文档重复使用
+func CompileExpr(expr Value) (*Expr, error) {
+	eval, err := compileExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Expr{eval: eval}, nil
+}
+
+// Eval 在 doc 上计算表达式，不存在的字段返回 Null
+func (self *Expr) Eval(doc Map) (Value, error) {
+	v, err := self.eval(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	if v == nil {
+		return Null{}, nil
+	}
+
+	return v, nil
+}
+
+func compileExpr(expr Value) (exprFunc, error) {
+	switch x := expr.(type) {
+	case String:
+		if strings.HasPrefix(string(x), "$") {
+			segs, err := splitPath(string(x)[1:])
+			if err != nil {
+				return nil, err
+			}
+			return func(doc Map) (Value, error) {
+				v, _ := lookupPath(doc, segs)
+				return v, nil
+			}, nil
+		}
+
+	case Map:
+		if len(x) == 1 {
+			for op, operand := range x {
+				if strings.HasPrefix(op, "$") {
+					return compileExprOperator(op, operand)
+				}
+			}
+		}
+
+		fields := make(map[string]exprFunc, len(x))
+		for k, v := range x {
+			f, err := compileExpr(v)
+			if err != nil {
+				return nil, fmt.Errorf("field %v: %w", k, err)
+			}
+			fields[k] = f
+		}
+		return func(doc Map) (Value, error) {
+			out := make(Map, len(fields))
+			for k, f := range fields {
+				v, err := f(doc)
+				if err != nil {
+					return nil, err
+				}
+				if v != nil {
+					out[k] = v
+				}
+			}
+			return out, nil
+		}, nil
+
+	case Array:
+		items, err := compileExprs(x)
+		if err != nil {
+			return nil, err
+		}
+		return func(doc Map) (Value, error) {
+			out := make(Array, 0, len(items))
+			for _, f := range items {
+				v, err := f(doc)
+				if err != nil {
+					return nil, err
+				}
+				if v == nil {
+					v = Null{}
+				}
+				out = append(out, v)
+			}
+			return out, nil
+		}, nil
+	}
+
+	value := Clone(expr)
+	return func(doc Map) (Value, error) { return value, nil }, nil
+}
+
+func compileExprs(arr Array) ([]exprFunc, error) {
+	out := make([]exprFunc, 0, len(arr))
+
+	for i, item := range arr {
+		f, err := compileExpr(item)
+		if err != nil {
+			return nil, fmt.Errorf("index %d: %w", i, err)
+		}
+		out = append(out, f)
+	}
+
+	return out, nil
+}
+
+// exprArgs 将操作数解析为参数列表，非 Array 的操作数视为单个参数
+func exprArgs(op string, operand Value, min, max int) ([]exprFunc, error) {
+	arr, ok := operand.(Array)
+	if !ok {
+		arr = Array{operand}
+	}
+
+	if len(arr) < min || (max >= 0 && len(arr) > max) {
+		if min == max {
+			return nil, fmt.Errorf("%v expects %d arguments, got %d", op, min, len(arr))
+		}
+		return nil, fmt.Errorf("%v expects at least %d arguments, got %d", op, min, len(arr))
+	}
+
+	return compileExprs(arr)
+}
+
+// evalArgs 计算全部参数，不存在的字段为 Null
+func evalArgs(args []exprFunc, doc Map) ([]Value, error) {
+	out := make([]Value, len(args))
+
+	for i, f := range args {
+		v, err := f(doc)
+		if err != nil {
+			return nil, err
+		}
+		if v == nil {
+			v = Null{}
+		}
+		out[i] = v
+	}
+
+	return out, nil
+}
+
+type exprOperator struct {
+	min, max int // 参数个数，max < 0 表示不限
+	fn       func(op string, args []Value) (Value, error)
+}
+
+var exprOperators = map[string]exprOperator{
+	// 算术
+	"$add":      {1, -1, exprAdd},
+	"$subtract": {2, 2, exprSubtract},
+	"$multiply": {1, -1, exprMultiply},
+	"$divide":   {2, 2, exprDivide},
+	"$mod":      {2, 2, exprMod},
+	"$abs":      {1, 1, exprAbs},
+
+	// 字符串
+	"$concat":  {1, -1, exprConcat},
+	"$toUpper": {1, 1, exprStringFunc(strings.ToUpper)},
+	"$toLower": {1, 1, exprStringFunc(strings.ToLower)},
+	"$strLen":  {1, 1, exprStrLen},
+	"$substr":  {3, 3, exprSubstr},
+
+	// 比较
+	"$eq":  {2, 2, exprCompare(func(c int) bool { return c == 0 })},
+	"$ne":  {2, 2, exprCompare(func(c int) bool { return c != 0 })},
+	"$gt":  {2, 2, exprCompare(func(c int) bool { return c > 0 })},
+	"$gte": {2, 2, exprCompare(func(c int) bool { return c >= 0 })},
+	"$lt":  {2, 2, exprCompare(func(c int) bool { return c < 0 })},
+	"$lte": {2, 2, exprCompare(func(c int) bool { return c <= 0 })},
+	"$cmp": {2, 2, exprCmp},
+
+	// 逻辑
+	"$and": {0, -1, exprAnd},
+	"$or":  {0, -1, exprOr},
+	"$not": {1, 1, exprNot},
+
+	// 日期，操作数为 Timestamp
+	"$year":        {1, 1, exprDatePart(func(t time.Time) int { return t.Year() })},
+	"$month":       {1, 1, exprDatePart(func(t time.Time) int { return int(t.Month()) })},
+	"$dayOfMonth":  {1, 1, exprDatePart(func(t time.Time) int { return t.Day() })},
+	"$dayOfWeek":   {1, 1, exprDatePart(func(t time.Time) int { return int(t.Weekday()) + 1 })},
+	"$dayOfYear":   {1, 1, exprDatePart(func(t time.Time) int { return t.YearDay() })},
+	"$hour":        {1, 1, exprDatePart(func(t time.Time) int { return t.Hour() })},
+	"$minute":      {1, 1, exprDatePart(func(t time.Time) int { return t.Minute() })},
+	"$second":      {1, 1, exprDatePart(func(t time.Time) int { return t.Second() })},
+	"$millisecond": {1, 1, exprDatePart(func(t time.Time) int { return t.Nanosecond() / 1e6 })},
+
+	// 其他
+	"$type":   {1, 1, exprType},
+	"$size":   {1, 1, exprSize},
+	"$ifNull": {2, -1, exprIfNull},
+}
+
+func compileExprOperator(op string, operand Value) (exprFunc, error) {
+	switch op {
+	case "$literal":
+		value := Clone(operand)
+		return func(doc Map) (Value, error) { return value, nil }, nil
+
+	case "$cond":
+		return compileCond(operand)
+	}
+
+	spec, has := exprOperators[op]
+	if !has {
+		return nil, fmt.Errorf("Unknown expression operator: %v", op)
+	}
+
+	args, err := exprArgs(op, operand, spec.min, spec.max)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(doc Map) (Value, error) {
+		values, err := evalArgs(args, doc)
+		if err != nil {
+			return nil, err
+		}
+		return spec.fn(op, values)
+	}, nil
+}
+
+// compileCond 解析 Array{if, then, else} 或 Map{"if": ..., "then": ..., "else": ...}
+func compileCond(operand Value) (exprFunc, error) {
+	var parts Array
+
+	switch x := operand.(type) {
+	case Array:
+		parts = x
+	case Map:
+		for _, k := range []string{"if", "then", "else"} {
+			v, has := x[k]
+			if !has {
+				return nil, fmt.Errorf("$cond missing %v", k)
+			}
+			parts = append(parts, v)
+		}
+		if len(x) != 3 {
+			return nil, errors.New("$cond expects only if, then and else")
+		}
+	default:
+		return nil, &TypeError{Op: "$cond", Expected: "Array or Map", Got: operand.DataType()}
+	}
+
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("$cond expects 3 arguments, got %d", len(parts))
+	}
+
+	fs, err := compileExprs(parts)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(doc Map) (Value, error) {
+		c, err := fs[0](doc)
+		if err != nil {
+			return nil, err
+		}
+		if Truthy(c) {
+			return fs[1](doc)
+		}
+		return fs[2](doc)
+	}, nil
+}
+
+// Truthy 判断值的真假：不存在、Null、False 和数值 0 为假，其他为真
+func Truthy(v Value) bool {
+	if v == nil {
+		return false
+	}
+
+	switch x := v.(type) {
+	case Null:
+		return false
+	case Bool:
+		return bool(x)
+	}
+
+	if n, ok := toNumber(v); ok {
+		return compareNumber(n, number{kind: numberInt}) != 0
+	}
+
+	return true
+}
+
+func hasNull(args []Value) bool {
+	for _, v := range args {
+		if _, ok := v.(Null); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// exprAdd 数值相加；最多一个参数可以是 Timestamp，此时结果为 Timestamp
+func exprAdd(op string, args []Value) (Value, error) {
+	if hasNull(args) {
+		return Null{}, nil
+	}
+
+	var acc Value
+	isTime := false
+
+	for _, v := range args {
+		if ts, ok := v.(Timestamp); ok {
+			if isTime {
+				return nil, &TypeError{Op: op, Expected: "at most one Timestamp", Got: DataTypeTIMESTAMP}
+			}
+			isTime = true
+			v = I64(ts)
+		} else if !v.DataType().IsNumeric() {
+			return nil, &TypeError{Op: op, Expected: "number or Timestamp", Got: v.DataType()}
+		}
+
+		if acc == nil {
+			acc = v
+			continue
+		}
+
+		r, err := arith(arithAdd, acc, v)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", op, err)
+		}
+		acc = r
+	}
+
+	if isTime {
+		return toTimestamp(op, acc)
+	}
+
+	return acc, nil
+}
+
+// exprSubtract 数值相减；Timestamp 减 Timestamp 得到 I64 毫秒，Timestamp 减数值得到 Timestamp
+func exprSubtract(op string, args []Value) (Value, error) {
+	if hasNull(args) {
+		return Null{}, nil
+	}
+
+	a, b := args[0], args[1]
+
+	at, aIsTime := a.(Timestamp)
+	bt, bIsTime := b.(Timestamp)
+
+	switch {
+	case aIsTime && bIsTime:
+		return arith(arithSub, I64(at), I64(bt))
+	case aIsTime:
+		if !b.DataType().IsNumeric() {
+			return nil, &TypeError{Op: op, Expected: "number or Timestamp", Got: b.DataType()}
+		}
+		r, err := arith(arithSub, I64(at), b)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", op, err)
+		}
+		return toTimestamp(op, r)
+	}
+
+	for _, v := range args {
+		if !v.DataType().IsNumeric() {
+			return nil, &TypeError{Op: op, Expected: "number", Got: v.DataType()}
+		}
+	}
+
+	r, err := arith(arithSub, a, b)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", op, err)
+	}
+
+	return r, nil
+}
+
+func toTimestamp(op string, v Value) (Value, error) {
+	n, _ := toNumber(v)
+
+	switch {
+	case n.kind == numberFloat:
+		if n.f < 0 || n.f >= math.Exp2(64) || math.IsNaN(n.f) {
+			return nil, fmt.Errorf("%v: Timestamp out of range: %v", op, v)
+		}
+		return Timestamp(n.f), nil
+	case n.kind == numberInt && n.i < 0:
+		return nil, fmt.Errorf("%v: Timestamp out of range: %v", op, v)
+	case n.kind == numberInt:
+		return Timestamp(n.i), nil
+	default:
+		return Timestamp(n.u), nil
+	}
+}
+
+func exprMultiply(op string, args []Value) (Value, error) {
+	if hasNull(args) {
+		return Null{}, nil
+	}
+
+	var acc Value
+
+	for _, v := range args {
+		if !v.DataType().IsNumeric() {
+			return nil, &TypeError{Op: op, Expected: "number", Got: v.DataType()}
+		}
+
+		if acc == nil {
+			acc = v
+			continue
+		}
+
+		r, err := arith(arithMul, acc, v)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", op, err)
+		}
+		acc = r
+	}
+
+	return acc, nil
+}
+
+// exprDivide 除法结果为浮点数：F32 除以 F32 为 F32，其他为 F64
+func exprDivide(op string, args []Value) (Value, error) {
+	if hasNull(args) {
+		return Null{}, nil
+	}
+
+	for _, v := range args {
+		if !v.DataType().IsNumeric() {
+			return nil, &TypeError{Op: op, Expected: "number", Got: v.DataType()}
+		}
+	}
+
+	x, _ := toNumber(args[0])
+	y, _ := toNumber(args[1])
+
+	if y.float64() == 0 {
+		return nil, fmt.Errorf("%v: division by zero", op)
+	}
+
+	r := x.float64() / y.float64()
+
+	if args[0].DataType() == DataTypeF32 && args[1].DataType() == DataTypeF32 {
+		return F32(r), nil
+	}
+
+	return F64(r), nil
+}
+
+// exprMod 取余，结果类型按 PromoteTypes 提升
+func exprMod(op string, args []Value) (Value, error) {
+	if hasNull(args) {
+		return Null{}, nil
+	}
+
+	for _, v := range args {
+		if !v.DataType().IsNumeric() {
+			return nil, &TypeError{Op: op, Expected: "number", Got: v.DataType()}
+		}
+	}
+
+	x, _ := toNumber(args[0])
+	y, _ := toNumber(args[1])
+	dt, _ := PromoteTypes(args[0].DataType(), args[1].DataType())
+
+	if isFloatType(dt) {
+		if y.float64() == 0 {
+			return nil, fmt.Errorf("%v: division by zero", op)
+		}
+		r := math.Mod(x.float64(), y.float64())
+		if dt == DataTypeF32 {
+			return F32(r), nil
+		}
+		return F64(r), nil
+	}
+
+	if y.bigInt().Sign() == 0 {
+		return nil, fmt.Errorf("%v: division by zero", op)
+	}
+
+	r := x.bigInt()
+	r.Rem(r, y.bigInt())
+
+	v, _ := intValue(r, dt)
+	return v, nil
+}
+
+// exprAbs 绝对值，结果类型不变，有符号整数的最小值返回溢出错误
+func exprAbs(op string, args []Value) (Value, error) {
+	v := args[0]
+
+	if _, ok := v.(Null); ok {
+		return Null{}, nil
+	}
+
+	if !v.DataType().IsNumeric() {
+		return nil, &TypeError{Op: op, Expected: "number", Got: v.DataType()}
+	}
+
+	switch x := v.(type) {
+	case F32:
+		return F32(math.Abs(float64(x))), nil
+	case F64:
+		return F64(math.Abs(float64(x))), nil
+	}
+
+	n, _ := toNumber(v)
+	if n.kind == numberUint || n.i >= 0 {
+		return v, nil
+	}
+
+	r := n.bigInt()
+	r.Neg(r)
+
+	abs, ok := intValue(r, v.DataType())
+	if !ok {
+		return nil, fmt.Errorf("%v: Overflow: %v", op, v)
+	}
+
+	return abs, nil
+}
+
+func exprConcat(op string, args []Value) (Value, error) {
+	if hasNull(args) {
+		return Null{}, nil
+	}
+
+	var b strings.Builder
+
+	for _, v := range args {
+		s, ok := v.(String)
+		if !ok {
+			return nil, &TypeError{Op: op, Expected: "String", Got: v.DataType()}
+		}
+		b.WriteString(string(s))
+	}
+
+	return String(b.String()), nil
+}
+
+func exprStringFunc(fn func(string) string) func(op string, args []Value) (Value, error) {
+	return func(op string, args []Value) (Value, error) {
+		switch x := args[0].(type) {
+		case Null:
+			return String(""), nil
+		case String:
+			return String(fn(string(x))), nil
+		default:
+			return nil, &TypeError{Op: op, Expected: "String", Got: x.DataType()}
+		}
+	}
+}
+
+// exprStrLen 返回字符串的字符数
+func exprStrLen(op string, args []Value) (Value, error) {
+	s, ok := args[0].(String)
+	if !ok {
+		return nil, &TypeError{Op: op, Expected: "String", Got: args[0].DataType()}
+	}
+
+	return I32(utf8.RuneCountInString(string(s))), nil
+}
+
+// exprSubstr 按字符截取子串：Array{s, start, length}，length 为负数时截取到末尾
+func exprSubstr(op string, args []Value) (Value, error) {
+	if _, ok := args[0].(Null); ok {
+		return String(""), nil
+	}
+
+	s, ok := args[0].(String)
+	if !ok {
+		return nil, &TypeError{Op: op, Expected: "String", Got: args[0].DataType()}
+	}
+
+	start, ok := sliceInt(args[1])
+	if !ok {
+		return nil, &TypeError{Op: op, Expected: "integer", Got: args[1].DataType()}
+	}
+
+	length, ok := sliceInt(args[2])
+	if !ok {
+		return nil, &TypeError{Op: op, Expected: "integer", Got: args[2].DataType()}
+	}
+
+	runes := []rune(string(s))
+	if start < 0 || start > len(runes) {
+		return String(""), nil
+	}
+
+	end := len(runes)
+	if length >= 0 {
+		// 先与剩余长度比较，避免 start+length 溢出
+		end = start + min(length, len(runes)-start)
+	}
+
+	return String(runes[start:end]), nil
+}
+
+func exprCompare(fn func(int) bool) func(op string, args []Value) (Value, error) {
+	return func(op string, args []Value) (Value, error) {
+		return Bool(fn(Compare(args[0], args[1]))), nil
+	}
+}
+
+func exprCmp(op string, args []Value) (Value, error) {
+	return I32(Compare(args[0], args[1])), nil
+}
+
+func exprAnd(op string, args []Value) (Value, error) {
+	for _, v := range args {
+		if !Truthy(v) {
+			return Bool(false), nil
+		}
+	}
+
+	return Bool(true), nil
+}
+
+func exprOr(op string, args []Value) (Value, error) {
+	for _, v := range args {
+		if Truthy(v) {
+			return Bool(true), nil
+		}
+	}
+
+	return Bool(false), nil
+}
+
+func exprNot(op string, args []Value) (Value, error) {
+	return Bool(!Truthy(args[0])), nil
+}
+
+func exprDatePart(fn func(time.Time) int) func(op string, args []Value) (Value, error) {
+	return func(op string, args []Value) (Value, error) {
+		switch x := args[0].(type) {
+		case Null:
+			return Null{}, nil
+		case Timestamp:
+			return I32(fn(time.UnixMilli(int64(x)).UTC())), nil
+		default:
+			return nil, &TypeError{Op: op, Expected: "Timestamp", Got: x.DataType()}
+		}
+	}
+}
+
+// exprType 返回值的类型名称，不存在的字段为 "Null"
+func exprType(op string, args []Value) (Value, error) {
+	return String(args[0].DataType().String()), nil
+}
+
+func exprSize(op string, args []Value) (Value, error) {
+	arr, ok := args[0].(Array)
+	if !ok {
+		return nil, &TypeError{Op: op, Expected: "Array", Got: args[0].DataType()}
+	}
+
+	return I32(len(arr)), nil
+}
+
+// exprIfNull 返回第一个不为 Null 的参数，全部为 Null 时返回最后一个参数
+func exprIfNull(op string, args []Value) (Value, error) {
+	for _, v := range args[:len(args)-1] {
+		if _, ok := v.(Null); !ok {
+			return v, nil
+		}
+	}
+
+	return args[len(args)-1], nil
+}
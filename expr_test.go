@@ -0,0 +1,176 @@
+package nson
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEvalArithmetic(t *testing.T) {
+	doc := Map{"price": U8(200), "qty": I8(3), "rate": F32(0.5), "big": I64(math.MaxInt64)}
+
+	tests := []struct {
+		expr Value
+		want Value
+	}{
+		{Map{"$multiply": Array{String("$price"), String("$qty")}}, I16(600)},
+		{Map{"$add": Array{String("$qty"), I32(1), I64(1)}}, I64(5)},
+		{Map{"$subtract": Array{String("$price"), U8(1)}}, U8(199)},
+		{Map{"$multiply": Array{String("$rate"), F32(4)}}, F32(2)},
+		{Map{"$multiply": Array{String("$rate"), I32(4)}}, F64(2)},
+		{Map{"$divide": Array{I32(7), I32(2)}}, F64(3.5)},
+		{Map{"$mod": Array{I32(7), I8(-3)}}, I32(1)},
+		{Map{"$abs": I8(-5)}, I8(5)},
+		{Map{"$add": Array{String("$missing"), I32(1)}}, Null{}},
+		{String("$missing"), Null{}},
+		{Map{"$literal": String("$price")}, String("$price")},
+	}
+
+	for _, tt := range tests {
+		got, err := Eval(tt.expr, doc)
+		if err != nil {
+			t.Errorf("Eval(%v) failed: %v", tt.expr, err)
+			continue
+		}
+		if !Equal(got, tt.want) {
+			t.Errorf("Eval(%v) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	errs := []Value{
+		Map{"$add": Array{String("$big"), I32(1)}},
+		Map{"$divide": Array{I32(1), I32(0)}},
+		Map{"$abs": I8(math.MinInt8)},
+	}
+
+	for _, expr := range errs {
+		if _, err := Eval(expr, doc); err == nil {
+			t.Errorf("Expected error for %v", expr)
+		}
+	}
+}
+
+func TestEvalTypeError(t *testing.T) {
+	_, err := Eval(Map{"$multiply": Array{I32(2), String("$name")}}, Map{"name": String("x")})
+
+	var te *TypeError
+	if !errors.As(err, &te) {
+		t.Fatalf("Expected TypeError, got %v", err)
+	}
+	if te.Got != DataTypeSTRING || te.Op != "$multiply" {
+		t.Errorf("Unexpected TypeError: %v", te)
+	}
+	if err.Error() != "$multiply: expected number, got String" {
+		t.Errorf("Unexpected message: %v", err)
+	}
+}
+
+func TestEvalStringsAndDates(t *testing.T) {
+	created := time.Date(2024, time.March, 5, 14, 30, 15, 250e6, time.UTC)
+	doc := Map{"first": String("Ada"), "last": String("Lovelace"), "created": Timestamp(created.UnixMilli())}
+
+	tests := []struct {
+		expr Value
+		want Value
+	}{
+		{Map{"$concat": Array{String("$first"), String(" "), String("$last")}}, String("Ada Lovelace")},
+		{Map{"$toUpper": String("$first")}, String("ADA")},
+		{Map{"$substr": Array{String("$last"), I32(0), I32(4)}}, String("Love")},
+		{Map{"$substr": Array{String("hello"), I32(1), I64(math.MaxInt64)}}, String("ello")},
+		{Map{"$substr": Array{String("hello"), I32(1), U64(math.MaxUint64)}}, String("ello")},
+		{Map{"$strLen": String("$last")}, I32(8)},
+		{Map{"$year": String("$created")}, I32(2024)},
+		{Map{"$month": String("$created")}, I32(3)},
+		{Map{"$dayOfMonth": String("$created")}, I32(5)},
+		{Map{"$hour": String("$created")}, I32(14)},
+		{Map{"$millisecond": String("$created")}, I32(250)},
+		{Map{"$dayOfWeek": String("$created")}, I32(3)},
+		{Map{"$add": Array{String("$created"), I32(1000)}}, Timestamp(created.UnixMilli() + 1000)},
+		{Map{"$subtract": Array{String("$created"), Timestamp(created.UnixMilli() - 5)}}, I64(5)},
+		{Map{"$type": String("$created")}, String("Timestamp")},
+	}
+
+	for _, tt := range tests {
+		got, err := Eval(tt.expr, doc)
+		if err != nil {
+			t.Errorf("Eval(%v) failed: %v", tt.expr, err)
+			continue
+		}
+		if !Equal(got, tt.want) {
+			t.Errorf("Eval(%v) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	var te *TypeError
+	if _, err := Eval(Map{"$year": String("$first")}, doc); !errors.As(err, &te) || te.Got != DataTypeSTRING {
+		t.Errorf("Expected TypeError, got %v", err)
+	}
+}
+
+func TestEvalConditions(t *testing.T) {
+	doc := Map{"qty": U16(20), "note": Null{}}
+
+	tests := []struct {
+		expr Value
+		want Value
+	}{
+		{Map{"$cond": Array{Map{"$gt": Array{String("$qty"), I32(10)}}, String("bulk"), String("retail")}}, String("bulk")},
+		{Map{"$cond": Map{"if": Map{"$lt": Array{String("$qty"), F64(10)}}, "then": I32(1), "else": I32(2)}}, I32(2)},
+		{Map{"$and": Array{String("$qty"), Bool(true)}}, Bool(true)},
+		{Map{"$or": Array{String("$note"), I32(0)}}, Bool(false)},
+		{Map{"$not": String("$missing")}, Bool(true)},
+		{Map{"$ifNull": Array{String("$note"), String("$missing"), String("none")}}, String("none")},
+		{Map{"$cmp": Array{String("$qty"), I64(20)}}, I32(0)},
+		{Map{"total": String("$qty"), "gone": String("$missing")}, Map{"total": U16(20)}},
+	}
+
+	for _, tt := range tests {
+		got, err := Eval(tt.expr, doc)
+		if err != nil {
+			t.Errorf("Eval(%v) failed: %v", tt.expr, err)
+			continue
+		}
+		if !Equal(got, tt.want) {
+			t.Errorf("Eval(%v) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	invalid := []Value{
+		Map{"$bogus": I32(1)},
+		Map{"$subtract": Array{I32(1)}},
+		Map{"$cond": Array{Bool(true), I32(1)}},
+		Map{"$cond": Map{"if": Bool(true), "then": I32(1)}},
+		String("$a..b"),
+	}
+
+	for _, expr := range invalid {
+		if _, err := CompileExpr(expr); err == nil {
+			t.Errorf("Expected compile error for %v", expr)
+		}
+	}
+}
+
+func TestExprInQueryAndPipeline(t *testing.T) {
+	docs := []Map{
+		{"sku": String("a"), "price": F64(2.5), "qty": I32(4), "budget": I32(5)},
+		{"sku": String("b"), "price": F64(1), "qty": I32(3), "budget": I32(5)},
+	}
+
+	m := MustCompile(Map{"$expr": Map{"$gt": Array{Map{"$multiply": Array{String("$price"), String("$qty")}}, String("$budget")}}})
+	if !m.Match(docs[0]) || m.Match(docs[1]) {
+		t.Error("Unexpected $expr match result")
+	}
+
+	result, err := Aggregate(docs, Array{
+		Map{"$addFields": Map{"total": Map{"$multiply": Array{String("$price"), String("$qty")}}}},
+		Map{"$group": Map{"_id": Null{}, "sum": Map{"$sum": String("$total")}, "qty": Map{"$sum": Map{"$add": Array{String("$qty"), I32(1)}}}}},
+	})
+	if err != nil {
+		t.Fatalf("Aggregate failed: %v", err)
+	}
+
+	if result[0]["sum"] != F64(13) || result[0]["qty"] != I32(9) {
+		t.Errorf("Unexpected result: %v", result)
+	}
+}
@@ -0,0 +1,211 @@
+package nson
+
+import (
+	"fmt"
+	"io"
+	"iter"
+	"maps"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// InferredSchema 从样本文档推断出的结构
+type InferredSchema struct {
+	Samples int            // 样本数量
+	Root    *InferredField // 根节点，Types 中只有 Map
+}
+
+// InferredField 一个路径上观察到的值
+type InferredField struct {
+	Count  int                       // 出现次数
+	Types  map[DataType]int          // 各类型出现的次数，多于一个表示联合类型
+	Min    Value                     // 数值的最小值，没有数值时为 nil
+	Max    Value                     // 数值的最大值
+	Fields map[string]*InferredField // 值为 Map 时的字段
+	Items  *InferredField            // 值为 Array 时的元素，所有元素合并统计
+}
+
+// InferSchema 遍历样本文档，统计每个路径上的类型、出现频率、数值范围和数组元素类型
+func InferSchema(samples iter.Seq[Map]) *InferredSchema {
+	s := &InferredSchema{Root: newInferredField()}
+
+	for doc := range samples {
+		s.Samples++
+		s.Root.observe(doc)
+	}
+
+	return s
+}
+
+func newInferredField() *InferredField {
+	return &InferredField{Types: map[DataType]int{}}
+}
+
+func (self *InferredField) observe(v Value) {
+	self.Count++
+	self.Types[v.DataType()]++
+
+	if _, ok := toNumber(v); ok {
+		if self.Min == nil || Compare(v, self.Min) < 0 {
+			self.Min = v
+		}
+		if self.Max == nil || Compare(v, self.Max) > 0 {
+			self.Max = v
+		}
+	}
+
+	switch x := v.(type) {
+	case Map:
+		if self.Fields == nil {
+			self.Fields = map[string]*InferredField{}
+		}
+		for k, item := range x {
+			field, has := self.Fields[k]
+			if !has {
+				field = newInferredField()
+				self.Fields[k] = field
+			}
+			field.observe(item)
+		}
+	case Array:
+		if self.Items == nil {
+			self.Items = newInferredField()
+		}
+		for _, item := range x {
+			self.Items.observe(item)
+		}
+	}
+}
+
+// Presence 返回字段在所属 Map 中出现的比例
+func (self *InferredField) Presence(parent *InferredField) float64 {
+	n := parent.Types[DataTypeMAP]
+	if n == 0 {
+		return 0
+	}
+
+	return float64(self.Count) / float64(n)
+}
+
+// SortedTypes 按出现次数从多到少返回观察到的类型
+func (self *InferredField) SortedTypes() []DataType {
+	types := slices.Collect(maps.Keys(self.Types))
+
+	slices.SortFunc(types, func(a, b DataType) int {
+		if c := self.Types[b] - self.Types[a]; c != 0 {
+			return c
+		}
+		return int(a) - int(b)
+	})
+
+	return types
+}
+
+// Schema 将推断结果转换为 Schema：每次都出现的字段为 Required，
+// 只观察到数值的路径带上 Min 和 Max
+func (self *InferredSchema) Schema() *Schema {
+	return self.Root.schema()
+}
+
+func (self *InferredField) schema() *Schema {
+	schema := &Schema{}
+
+	types := slices.Collect(maps.Keys(self.Types))
+	slices.Sort(types)
+	schema.Types = types
+
+	if self.Min != nil && !slices.ContainsFunc(types, func(dt DataType) bool { return !dt.IsNumeric() }) {
+		schema.Min = self.Min
+		schema.Max = self.Max
+	}
+
+	if self.Fields != nil {
+		schema.Fields = make(map[string]*Schema, len(self.Fields))
+		for name, field := range self.Fields {
+			s := field.schema()
+			s.Required = field.Count == self.Types[DataTypeMAP]
+			schema.Fields[name] = s
+		}
+	}
+
+	if self.Items != nil && self.Items.Count > 0 {
+		schema.Items = self.Items.schema()
+	}
+
+	return schema
+}
+
+// WriteReport 输出可读的报告，每行一个路径：
+//
+//	PATH      PRESENT        TYPES                 MIN   MAX
+//	temp      980/1000 98%   F32 (950), I32 (30)   -12   40.5
+//	tags[]    3400           String (3400)
+//
+// 数组元素的路径以 "[]" 结尾，PRESENT 为元素总数。
+func (self *InferredSchema) WriteReport(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "samples: %d\n", self.Samples)
+	fmt.Fprintln(tw, "PATH\tPRESENT\tTYPES\tMIN\tMAX")
+
+	if self.Root.Fields != nil {
+		self.Root.writeFields(tw, "")
+	}
+
+	return tw.Flush()
+}
+
+func (self *InferredSchema) String() string {
+	var b strings.Builder
+	self.WriteReport(&b)
+	return b.String()
+}
+
+func (self *InferredField) writeFields(w io.Writer, path string) {
+	parents := self.Types[DataTypeMAP]
+
+	for _, name := range slices.Sorted(maps.Keys(self.Fields)) {
+		field := self.Fields[name]
+		p := childPath(path, name)
+
+		present := fmt.Sprintf("%d/%d %.0f%%", field.Count, parents, field.Presence(self)*100)
+		field.writeLine(w, p, present)
+	}
+}
+
+func (self *InferredField) writeLine(w io.Writer, path, present string) {
+	types := make([]string, 0, len(self.Types))
+	for _, dt := range self.SortedTypes() {
+		types = append(types, fmt.Sprintf("%v (%d)", dt, self.Types[dt]))
+	}
+
+	min, max := "", ""
+	if self.Min != nil {
+		min, max = numberString(self.Min), numberString(self.Max)
+	}
+
+	fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", path, present, strings.Join(types, ", "), min, max)
+
+	if self.Fields != nil {
+		self.writeFields(w, path)
+	}
+
+	if self.Items != nil && self.Items.Count > 0 {
+		self.Items.writeLine(w, path+"[]", fmt.Sprint(self.Items.Count))
+	}
+}
+
+// numberString 返回不带类型名称的数值
+func numberString(v Value) string {
+	n, _ := toNumber(v)
+
+	switch n.kind {
+	case numberInt:
+		return fmt.Sprint(n.i)
+	case numberUint:
+		return fmt.Sprint(n.u)
+	default:
+		return fmt.Sprint(n.f)
+	}
+}
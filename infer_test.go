@@ -0,0 +1,93 @@
+package nson
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func deviceSamples() []Map {
+	return []Map{
+		{"id": String("a"), "temp": F32(21.5), "tags": Array{String("x")}, "meta": Map{"rev": U8(1)}},
+		{"id": String("b"), "temp": I32(-3), "tags": Array{String("y"), I32(2)}},
+		{"id": String("c"), "temp": Null{}, "tags": Array{}, "meta": Map{"rev": U8(7), "fw": String("1.0")}},
+		{"id": String("d"), "temp": U16(40)},
+	}
+}
+
+func TestInferSchema(t *testing.T) {
+	inferred := InferSchema(slices.Values(deviceSamples()))
+
+	if inferred.Samples != 4 {
+		t.Fatalf("Expected 4 samples, got %d", inferred.Samples)
+	}
+
+	temp := inferred.Root.Fields["temp"]
+	if temp.Count != 4 || len(temp.Types) != 4 || temp.Min != I32(-3) || temp.Max != U16(40) {
+		t.Errorf("Unexpected temp: %+v", temp)
+	}
+
+	tags := inferred.Root.Fields["tags"]
+	if tags.Presence(inferred.Root) != 0.75 || tags.Items.Count != 3 || tags.Items.Types[DataTypeSTRING] != 2 {
+		t.Errorf("Unexpected tags: %+v", tags)
+	}
+
+	fw := inferred.Root.Fields["meta"].Fields["fw"]
+	if fw.Presence(inferred.Root.Fields["meta"]) != 0.5 {
+		t.Errorf("Unexpected fw presence: %v", fw.Presence(inferred.Root.Fields["meta"]))
+	}
+
+	schema := inferred.Schema()
+	if !schema.Fields["id"].Required || schema.Fields["tags"].Required || !schema.Fields["meta"].Fields["rev"].Required {
+		t.Errorf("Unexpected required flags: %v", schema.Map())
+	}
+	if schema.Fields["temp"].Min != nil {
+		t.Errorf("Expected no range for union with Null, got %v", schema.Fields["temp"].Min)
+	}
+	if rev := schema.Fields["meta"].Fields["rev"]; rev.Min != U8(1) || rev.Max != U8(7) {
+		t.Errorf("Unexpected rev range: %v", rev.Map())
+	}
+	if !slices.Equal(schema.Fields["tags"].Items.Types, []DataType{DataTypeI32, DataTypeSTRING}) {
+		t.Errorf("Unexpected item types: %v", schema.Fields["tags"].Items.Types)
+	}
+
+	for _, doc := range deviceSamples() {
+		if errs := schema.Validate(doc); errs != nil {
+			t.Errorf("Inferred schema rejects sample %v: %v", doc, errs)
+		}
+	}
+}
+
+func TestInferSchemaReport(t *testing.T) {
+	report := InferSchema(slices.Values(deviceSamples())).String()
+
+	lines := strings.Split(strings.TrimSpace(report), "\n")
+	want := []string{
+		"samples: 4",
+		"PATH",
+		"id",
+		"meta",
+		"meta.fw",
+		"meta.rev",
+		"tags",
+		"tags[]",
+		"temp",
+	}
+
+	if len(lines) != len(want) {
+		t.Fatalf("Unexpected report:\n%v", report)
+	}
+
+	for i, prefix := range want {
+		if !strings.HasPrefix(lines[i], prefix) {
+			t.Errorf("Line %d: expected prefix %q, got %q", i, prefix, lines[i])
+		}
+	}
+
+	if !strings.Contains(lines[8], "4/4 100%") || !strings.Contains(lines[8], "-3") || !strings.Contains(lines[8], "40") {
+		t.Errorf("Unexpected temp line: %q", lines[8])
+	}
+	if !strings.Contains(lines[4], "1/2 50%") {
+		t.Errorf("Unexpected meta.fw line: %q", lines[4])
+	}
+}
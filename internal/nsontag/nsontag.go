@@ -0,0 +1,129 @@
+// Package nsontag 实现 nson tag 的解析和同名字段的遮蔽规则，
+// 由反射的编解码和 nsongen -marshal 共用，保证两者的规则一致
+package nsontag
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options nson tag 中名称之后的选项
+type Options struct {
+	OmitEmpty bool // 零值时不编码
+	Remain    bool // 收集没有对应字段的键，字段类型必须为 nson.Map
+	Inline    bool // 将结构体的字段或 map 的键展开到外层
+	Required  bool // Unmarshal 时必须存在
+
+	HasDefault bool   // 有 default 选项
+	Default    string // default= 之后的值，不能包含逗号
+
+	Type string // type= 之后的 NSON 类型名称，如 "i64"
+}
+
+// Parse 解析 nson tag，如 "name,omitempty"、"port,default=8080" 或 "count,type=i64"，
+// 忽略不认识的选项
+func Parse(tag string) (string, Options) {
+	name, rest, _ := strings.Cut(tag, ",")
+
+	var opts Options
+	for rest != "" {
+		var opt string
+		opt, rest, _ = strings.Cut(rest, ",")
+
+		switch opt {
+		case "omitempty":
+			opts.OmitEmpty = true
+		case "remain":
+			opts.Remain = true
+		case "inline":
+			opts.Inline = true
+		case "required":
+			opts.Required = true
+		default:
+			if def, ok := strings.CutPrefix(opt, "default="); ok {
+				opts.HasDefault, opts.Default = true, def
+			} else if typ, ok := strings.CutPrefix(opt, "type="); ok {
+				opts.Type = typ
+			}
+		}
+	}
+
+	return name, opts
+}
+
+// Field 遮蔽规则需要的字段信息
+type Field struct {
+	Name   string // Go 字段名，用于错误信息
+	Key    string // NSON 中的键
+	Depth  int    // 嵌入和内联的层级
+	Tagged bool   // tag 中指定了名称
+}
+
+// Dominant 按 Go 的字段遮蔽规则处理同键的字段：层级浅的字段优先，
+// 同一层级中只有一个字段在 tag 中指定了名称时它优先，否则返回错误。
+// 结果保持字段原来的顺序
+func Dominant[T any](fields []T, info func(T) Field) ([]T, error) {
+	infos := make([]Field, len(fields))
+	byKey := make(map[string][]int, len(fields))
+	for i, f := range fields {
+		infos[i] = info(f)
+		byKey[infos[i].Key] = append(byKey[infos[i].Key], i)
+	}
+
+	result := make([]T, 0, len(fields))
+
+	for i, f := range infos {
+		group := byKey[f.Key]
+
+		// 同键字段只在第一次出现时处理
+		if group[0] != i {
+			continue
+		}
+
+		var dominant []int
+		for _, j := range group {
+			switch {
+			case len(dominant) == 0 || infos[j].Depth < infos[dominant[0]].Depth:
+				dominant = []int{j}
+			case infos[j].Depth == infos[dominant[0]].Depth:
+				dominant = append(dominant, j)
+			}
+		}
+
+		if len(dominant) > 1 {
+			var tagged []int
+			for _, j := range dominant {
+				if infos[j].Tagged {
+					tagged = append(tagged, j)
+				}
+			}
+			if len(tagged) != 1 {
+				if len(tagged) > 1 {
+					dominant = tagged
+				}
+				return nil, fmt.Errorf("duplicate key %q in fields %s and %s", f.Key, infos[dominant[0]].Name, infos[dominant[1]].Name)
+			}
+			dominant = tagged
+		}
+
+		result = append(result, fields[dominant[0]])
+	}
+
+	return result, nil
+}
+
+// ParseMillis 解析毫秒时间戳或 RFC 3339 格式的时间
+func ParseMillis(s string) (int64, error) {
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return ms, nil
+	}
+
+	tm, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return 0, fmt.Errorf("expected milliseconds or RFC 3339 time, got %q", s)
+	}
+
+	return tm.UnixMilli(), nil
+}
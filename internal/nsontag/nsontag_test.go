@@ -0,0 +1,62 @@
+package nsontag
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	name, opts := Parse("port,omitempty,required,default=8080,type=u16,unknown")
+	want := Options{OmitEmpty: true, Required: true, HasDefault: true, Default: "8080", Type: "u16"}
+	if name != "port" || opts != want {
+		t.Errorf("Unexpected result: %q %+v", name, opts)
+	}
+
+	name, opts = Parse(",inline,remain")
+	if name != "" || opts != (Options{Inline: true, Remain: true}) {
+		t.Errorf("Unexpected result: %q %+v", name, opts)
+	}
+}
+
+func TestDominant(t *testing.T) {
+	id := func(f Field) Field { return f }
+
+	fields := []Field{
+		{Name: "A", Key: "a", Depth: 1},
+		{Name: "B", Key: "b", Depth: 1},
+		{Name: "A2", Key: "a", Depth: 0},
+		{Name: "B2", Key: "b", Depth: 1, Tagged: true},
+		{Name: "C", Key: "c", Depth: 2},
+	}
+
+	got, err := Dominant(fields, id)
+	if err != nil {
+		t.Fatalf("Dominant failed: %v", err)
+	}
+
+	names := make([]string, len(got))
+	for i, f := range got {
+		names[i] = f.Name
+	}
+	if !slices.Equal(names, []string{"A2", "B2", "C"}) {
+		t.Errorf("Unexpected fields: %v", names)
+	}
+
+	_, err = Dominant([]Field{{Name: "X", Key: "x"}, {Name: "Y", Key: "x"}}, id)
+	if err == nil || !strings.Contains(err.Error(), `duplicate key "x" in fields X and Y`) {
+		t.Errorf("Expected duplicate key error, got %v", err)
+	}
+}
+
+func TestParseMillis(t *testing.T) {
+	if ms, err := ParseMillis("1500"); err != nil || ms != 1500 {
+		t.Errorf("Unexpected result: %v %v", ms, err)
+	}
+	if ms, err := ParseMillis("1970-01-01T00:00:01.5Z"); err != nil || ms != 1500 {
+		t.Errorf("Unexpected result: %v %v", ms, err)
+	}
+	if _, err := ParseMillis("soon"); err == nil {
+		t.Error("Expected error")
+	}
+}
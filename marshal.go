@@ -1,13 +1,49 @@
 package nson
 
 import (
+	"bytes"
+	"encoding"
 	"fmt"
 	"reflect"
 	"time"
 )
 
-// Marshal 将结构体序列化为 nson.Map
+// Marshaler 由需要自定义编码的类型实现，如枚举或 net.IP 的包装类型。
+// 返回 nil 时编码为 Null。结构体从嵌入字段提升的方法不算在内
+type Marshaler interface {
+	MarshalNSON() (Value, error)
+}
+
+// MapMarshaler 由 nsongen -marshal 为结构体生成，Marshal 会直接调用而不使用反射。
+// 生成的方法只实现默认规则，MarshalOptions 不为零值时不会调用
+type MapMarshaler interface {
+	MarshalNSON() (Map, error)
+}
+
+// MarshalOptions 序列化选项，零值与 Marshal 相同
+type MarshalOptions struct {
+	// Naming 没有在 tag 中指定名称的字段的键名规则，默认使用字段名
+	Naming *Naming
+}
+
+// Marshal 将结构体序列化为 nson.Map，其他类型使用 MarshalValue
 func Marshal(v any) (Map, error) {
+	return MarshalOptions{}.Marshal(v)
+}
+
+// MarshalValue 将任意支持的 Go 值序列化为 nson.Value，如切片、map 或标量，
+// 规则与结构体字段相同，nil 为 Null
+func MarshalValue(v any) (Value, error) {
+	return MarshalOptions{}.MarshalValue(v)
+}
+
+// MarshalBytes 将任意支持的 Go 值编码为带类型标记的字节，与 EncodeValue 的输出相同
+func MarshalBytes(v any) ([]byte, error) {
+	return MarshalOptions{}.MarshalBytes(v)
+}
+
+// Marshal 按选项将结构体序列化为 nson.Map
+func (self MarshalOptions) Marshal(v any) (Map, error) {
 	rv := reflect.ValueOf(v)
 
 	// 处理指针
@@ -22,21 +58,110 @@ func Marshal(v any) (Map, error) {
 		return nil, fmt.Errorf("expected struct, got %v", rv.Kind())
 	}
 
-	return marshalStruct(rv)
+	c := codecOf(rv.Type())
+
+	if m, ok := self.mapMarshaler(rv, c); ok {
+		return m.MarshalNSON()
+	}
+
+	if m, ok := implementer[Marshaler](rv, c.marshaler); ok {
+		val, err := callMarshaler(m)
+		if err != nil {
+			return nil, err
+		}
+		if m, ok := val.(Map); ok {
+			return m, nil
+		}
+		return nil, fmt.Errorf("expected Map from MarshalNSON, got %T", val)
+	}
+
+	return self.marshalStruct(rv)
+}
+
+// MarshalValue 按选项将任意支持的 Go 值序列化为 nson.Value
+func (self MarshalOptions) MarshalValue(v any) (Value, error) {
+	if v == nil {
+		return Null{}, nil
+	}
+
+	return self.marshalValue(reflect.ValueOf(v))
+}
+
+// mapAppender 由 nsongen -marshal 为结构体生成，MarshalBytes 会直接调用 AppendNSON
+type mapAppender interface {
+	MapMarshaler
+	AppendNSON(buf []byte) ([]byte, error)
+}
+
+// MarshalBytes 按选项将任意支持的 Go 值编码为带类型标记的字节
+func (self MarshalOptions) MarshalBytes(v any) ([]byte, error) {
+	if v != nil && self == (MarshalOptions{}) {
+		rv := reflect.ValueOf(v)
+		for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+			if codecOf(rv.Type()).marshaler != implNone {
+				break
+			}
+			rv = rv.Elem()
+		}
+
+		if rv.Kind() == reflect.Struct {
+			if a, ok := implementer[mapAppender](rv, codecOf(rv.Type()).mapAppender); ok {
+				return a.AppendNSON([]byte{byte(DataTypeMAP)})
+			}
+		}
+	}
+
+	val, err := self.MarshalValue(v)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	if err := EncodeValue(buf, val); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// callMarshaler 调用 MarshalNSON，nil 结果视为 Null
+func callMarshaler(m Marshaler) (Value, error) {
+	val, err := m.MarshalNSON()
+	if err != nil {
+		return nil, err
+	}
+
+	if val == nil {
+		return Null{}, nil
+	}
+
+	return val, nil
+}
+
+// mapMarshaler 只在默认选项下使用 MapMarshaler
+func (self MarshalOptions) mapMarshaler(rv reflect.Value, c *typeCodec) (MapMarshaler, bool) {
+	if self != (MarshalOptions{}) {
+		return nil, false
+	}
+
+	return implementer[MapMarshaler](rv, c.mapMarshaler)
 }
 
 // marshalStruct 将结构体序列化为 Map
-func marshalStruct(rv reflect.Value) (Map, error) {
+func (self MarshalOptions) marshalStruct(rv reflect.Value) (Map, error) {
 	t := rv.Type()
-	cache := getStructCache(t)
+	cache := getStructCache(t, self.Naming)
+	if cache.err != nil {
+		return nil, cache.err
+	}
 
 	m := make(Map, len(cache.fields))
 
 	for _, field := range cache.fields {
-		fv := rv
-		// 通过索引路径获取字段值
-		for _, idx := range field.indices {
-			fv = fv.Field(idx)
+		// 通过索引路径获取字段值，内联的指针为 nil 时跳过
+		fv, ok := fieldByIndex(rv, field.indices)
+		if !ok {
+			continue
 		}
 
 		// 处理 omitempty
@@ -44,7 +169,7 @@ func marshalStruct(rv reflect.Value) (Map, error) {
 			continue
 		}
 
-		val, err := marshalValue(fv)
+		val, err := self.marshalField(fv, field.wire)
 		if err != nil {
 			return nil, fmt.Errorf("field %s: %w", field.name, err)
 		}
@@ -54,19 +179,123 @@ func marshalStruct(rv reflect.Value) (Map, error) {
 		}
 	}
 
+	// remain 字段或内联的 map 中与其他字段同名的键被忽略
+	if cache.remain != nil {
+		if fv, ok := fieldByIndex(rv, cache.remain.indices); ok {
+			rest, err := self.marshalMap(fv)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", cache.remain.name, err)
+			}
+			for key, val := range rest {
+				if !cache.names[key] {
+					m[key] = val
+				}
+			}
+		}
+	}
+
 	return m, nil
 }
 
-// marshalValue 将 reflect.Value 转换为 nson.Value
-func marshalValue(rv reflect.Value) (Value, error) {
-	// 处理指针
-	for rv.Kind() == reflect.Pointer {
+// marshalField 序列化字段值，wire 为字段的 type 选项
+func (self MarshalOptions) marshalField(fv reflect.Value, wire DataType) (Value, error) {
+	if wire != 0 {
+		return marshalWire(fv, wire)
+	}
+
+	return self.marshalValue(fv)
+}
+
+// fieldByIndex 通过索引路径获取字段值，路径上有 nil 指针时返回 false
+func fieldByIndex(rv reflect.Value, indices []int) (reflect.Value, bool) {
+	for _, idx := range indices {
+		if rv.Kind() == reflect.Pointer {
+			if rv.IsNil() {
+				return reflect.Value{}, false
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(idx)
+	}
+
+	return rv, true
+}
+
+// fieldByIndexAlloc 通过索引路径获取字段值，为路径上的 nil 指针分配内存，无法分配时返回 false
+func fieldByIndexAlloc(rv reflect.Value, indices []int) (reflect.Value, bool) {
+	for _, idx := range indices {
+		if rv.Kind() == reflect.Pointer {
+			if rv.IsNil() {
+				if !rv.CanSet() {
+					return reflect.Value{}, false
+				}
+				rv.Set(reflect.New(rv.Type().Elem()))
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(idx)
+	}
+
+	return rv, true
+}
+
+// marshalValue 将 reflect.Value 转换为 nson.Value，依次检查：
+//
+//  1. Marshaler，nil 指针编码为 Null
+//  2. time.Time 编码为 Timestamp，Id 编码为 Id
+//  3. MapMarshaler，只在默认选项下使用
+//  4. encoding.TextMarshaler 编码为 String
+//  5. encoding.BinaryMarshaler 编码为 Binary
+//  6. 按 Kind 编码
+func (self MarshalOptions) marshalValue(rv reflect.Value) (Value, error) {
+	// 处理自定义编码和指针，每层只查一次类型的 typeCodec
+	var c *typeCodec
+	for {
+		c = codecOf(rv.Type())
+		if m, ok := implementer[Marshaler](rv, c.marshaler); ok {
+			return callMarshaler(m)
+		}
+
+		if rv.Kind() != reflect.Pointer {
+			break
+		}
+
 		if rv.IsNil() {
 			return Null{}, nil
 		}
 		rv = rv.Elem()
 	}
 
+	switch rv.Type() {
+	case reflect.TypeFor[time.Time]():
+		// 转换为毫秒时间戳
+		return Timestamp(rv.Interface().(time.Time).UnixMilli()), nil
+	case reflect.TypeFor[Id]():
+		var id Id
+		reflect.Copy(reflect.ValueOf(&id).Elem(), rv)
+		return id, nil
+	}
+
+	if m, ok := self.mapMarshaler(rv, c); ok {
+		return m.MarshalNSON()
+	}
+
+	if m, ok := implementer[encoding.TextMarshaler](rv, c.textMarshaler); ok {
+		text, err := m.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return String(text), nil
+	}
+
+	if m, ok := implementer[encoding.BinaryMarshaler](rv, c.binaryMarshaler); ok {
+		data, err := m.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		return Binary(data), nil
+	}
+
 	switch rv.Kind() {
 	case reflect.Bool:
 		return Bool(rv.Bool()), nil
@@ -105,32 +334,20 @@ func marshalValue(rv reflect.Value) (Value, error) {
 		return String(rv.String()), nil
 
 	case reflect.Slice:
-		if rv.Type().Elem().Kind() == reflect.Uint8 {
+		if isByteSlice(rv.Type()) {
 			// []byte
 			return Binary(rv.Bytes()), nil
 		}
-		return marshalSlice(rv)
+		return self.marshalSlice(rv)
 
 	case reflect.Array:
-		// 检查是否是 nson.Id 类型（[12]byte）
-		if rv.Type() == reflect.TypeFor[Id]() {
-			var id Id
-			reflect.Copy(reflect.ValueOf(&id).Elem(), rv)
-			return id, nil
-		}
-		return marshalArray(rv)
+		return self.marshalArray(rv)
 
 	case reflect.Struct:
-		// 检查是否是 time.Time 类型
-		if rv.Type() == reflect.TypeFor[time.Time]() {
-			t := rv.Interface().(time.Time)
-			// 转换为毫秒时间戳
-			return Timestamp(t.UnixMilli()), nil
-		}
-		return marshalStruct(rv)
+		return self.marshalStruct(rv)
 
 	case reflect.Map:
-		return marshalMap(rv)
+		return self.marshalMap(rv)
 
 	case reflect.Interface:
 		if rv.IsNil() {
@@ -143,7 +360,7 @@ func marshalValue(rv reflect.Value) (Value, error) {
 				return val, nil
 			}
 		}
-		return marshalValue(rv.Elem())
+		return self.marshalValue(rv.Elem())
 
 	default:
 		return nil, fmt.Errorf("unsupported type: %v", rv.Type())
@@ -151,11 +368,11 @@ func marshalValue(rv reflect.Value) (Value, error) {
 }
 
 // marshalSlice 序列化切片
-func marshalSlice(rv reflect.Value) (Array, error) {
+func (self MarshalOptions) marshalSlice(rv reflect.Value) (Array, error) {
 	arr := make(Array, 0, rv.Len())
 
 	for i := 0; i < rv.Len(); i++ {
-		val, err := marshalValue(rv.Index(i))
+		val, err := self.marshalValue(rv.Index(i))
 		if err != nil {
 			return nil, fmt.Errorf("index %d: %w", i, err)
 		}
@@ -166,11 +383,11 @@ func marshalSlice(rv reflect.Value) (Array, error) {
 }
 
 // marshalArray 序列化数组
-func marshalArray(rv reflect.Value) (Array, error) {
+func (self MarshalOptions) marshalArray(rv reflect.Value) (Array, error) {
 	arr := make(Array, 0, rv.Len())
 
 	for i := 0; i < rv.Len(); i++ {
-		val, err := marshalValue(rv.Index(i))
+		val, err := self.marshalValue(rv.Index(i))
 		if err != nil {
 			return nil, fmt.Errorf("index %d: %w", i, err)
 		}
@@ -181,7 +398,7 @@ func marshalArray(rv reflect.Value) (Array, error) {
 }
 
 // marshalMap 序列化 map
-func marshalMap(rv reflect.Value) (Map, error) {
+func (self MarshalOptions) marshalMap(rv reflect.Value) (Map, error) {
 	if rv.Type().Key().Kind() != reflect.String {
 		return nil, fmt.Errorf("map key must be string")
 	}
@@ -191,7 +408,7 @@ func marshalMap(rv reflect.Value) (Map, error) {
 	iter := rv.MapRange()
 	for iter.Next() {
 		key := iter.Key().String()
-		val, err := marshalValue(iter.Value())
+		val, err := self.marshalValue(iter.Value())
 		if err != nil {
 			return nil, fmt.Errorf("key %s: %w", key, err)
 		}
@@ -0,0 +1,345 @@
+package nson_test
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	nson "github.com/danclive/nson-go"
+)
+
+// Color 编码为名称字符串，零值编码为 Null
+type Color uint8
+
+const (
+	Red Color = iota + 1
+	Green
+)
+
+var colorNames = map[Color]string{Red: "red", Green: "green"}
+
+func (self Color) MarshalNSON() (nson.Value, error) {
+	if self == 0 {
+		return nil, nil
+	}
+
+	name, ok := colorNames[self]
+	if !ok {
+		return nil, fmt.Errorf("unknown color %d", self)
+	}
+	return nson.String(name), nil
+}
+
+func (self *Color) UnmarshalNSON(v nson.Value) error {
+	if _, ok := v.(nson.Null); ok {
+		*self = 0
+		return nil
+	}
+
+	s, ok := v.(nson.String)
+	if !ok {
+		return fmt.Errorf("expected String, got %T", v)
+	}
+	for c, name := range colorNames {
+		if name == string(s) {
+			*self = c
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown color %q", string(s))
+}
+
+// Point 编码为 [x, y]
+type Point struct {
+	X, Y int32
+}
+
+func (self *Point) MarshalNSON() (nson.Value, error) {
+	return nson.Array{nson.I32(self.X), nson.I32(self.Y)}, nil
+}
+
+func (self *Point) UnmarshalNSON(v nson.Value) error {
+	arr, ok := v.(nson.Array)
+	if !ok || len(arr) != 2 {
+		return fmt.Errorf("expected [x, y], got %v", v)
+	}
+	x, _ := arr[0].(nson.I32)
+	y, _ := arr[1].(nson.I32)
+	self.X, self.Y = int32(x), int32(y)
+	return nil
+}
+
+type Palette struct {
+	Point
+	Main    Color            `nson:"main"`
+	Accent  *Color           `nson:"accent"`
+	Colors  []Color          `nson:"colors"`
+	ByName  map[string]Color `nson:"by_name"`
+	Corners map[string]Point `nson:"corners"`
+	Unset   Color            `nson:"unset"`
+}
+
+// WrappedReading 嵌入了带生成方法的 Reading，提升的方法不会代替整个结构体的编码
+type WrappedReading struct {
+	Reading
+	Source string `nson:"source"`
+}
+
+func TestCustomMarshaler(t *testing.T) {
+	accent := Green
+	p := Palette{
+		Point:   Point{1, 2},
+		Main:    Red,
+		Accent:  &accent,
+		Colors:  []Color{Green, Red},
+		ByName:  map[string]Color{"x": Green},
+		Corners: map[string]Point{"tl": {0, 9}},
+	}
+
+	m, err := nson.Marshal(p)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	want := nson.Map{
+		"Point":   nson.Array{nson.I32(1), nson.I32(2)},
+		"main":    nson.String("red"),
+		"accent":  nson.String("green"),
+		"colors":  nson.Array{nson.String("green"), nson.String("red")},
+		"by_name": nson.Map{"x": nson.String("green")},
+		"corners": nson.Map{"tl": nson.Array{nson.I32(0), nson.I32(9)}},
+		"unset":   nson.Null{},
+	}
+
+	if !nson.Equal(m, want) {
+		t.Errorf("Marshal mismatch:\n got: %v\nwant: %v", m, want)
+	}
+
+	var got Palette
+	got.Unset = Green
+	if err := nson.Unmarshal(m, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if got.Point != p.Point || got.Main != Red || *got.Accent != Green || got.Colors[0] != Green ||
+		got.ByName["x"] != Green || got.Corners["tl"] != (Point{0, 9}) {
+		t.Errorf("Unmarshal mismatch: %+v", got)
+	}
+	if got.Unset != 0 {
+		t.Error("Null should be passed to UnmarshalNSON")
+	}
+}
+
+func TestCustomMarshalerErrors(t *testing.T) {
+	if _, err := nson.Marshal(Palette{Main: 9}); err == nil || !strings.Contains(err.Error(), "field Main: unknown color 9") {
+		t.Errorf("Expected marshal error, got %v", err)
+	}
+
+	var p Palette
+	err := nson.Unmarshal(nson.Map{"colors": nson.Array{nson.String("blue")}}, &p)
+	if err == nil || !strings.Contains(err.Error(), `field Colors: index 0: unknown color "blue"`) {
+		t.Errorf("Expected unmarshal error, got %v", err)
+	}
+}
+
+func TestPromotedMarshalerIgnored(t *testing.T) {
+	w := WrappedReading{Reading: Reading{Value: 1.5}, Source: "s"}
+
+	m, err := nson.Marshal(w)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	if m["source"] != nson.String("s") || m["value"] != nson.F32(1.5) {
+		t.Errorf("Expected flattened fields, got %v", m)
+	}
+
+	var got WrappedReading
+	if err := nson.Unmarshal(m, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if got.Source != "s" || got.Value != 1.5 {
+		t.Errorf("Unmarshal mismatch: %+v", got)
+	}
+}
+
+// Tinted 嵌入了值接收者实现 Marshaler 的 Color，*Tinted 也得到提升的 UnmarshalNSON
+type Tinted struct {
+	Color
+	Name string `nson:"name"`
+}
+
+// Anchored 嵌入了指针接收者实现 Marshaler 的 *Point
+type Anchored struct {
+	*Point
+	Name string `nson:"name"`
+}
+
+func TestPromotedMarshalerReceivers(t *testing.T) {
+	m, err := nson.Marshal(Tinted{Color: Green, Name: "n"})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := nson.Map{"Color": nson.String("green"), "name": nson.String("n")}
+	if !nson.Equal(m, want) {
+		t.Errorf("Expected %v, got %v", want, m)
+	}
+
+	var tinted Tinted
+	if err := nson.Unmarshal(want, &tinted); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if tinted.Color != Green || tinted.Name != "n" {
+		t.Errorf("Unmarshal mismatch: %+v", tinted)
+	}
+
+	m, err = nson.Marshal(Anchored{Point: &Point{1, 2}, Name: "n"})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want = nson.Map{"Point": nson.Array{nson.I32(1), nson.I32(2)}, "name": nson.String("n")}
+	if !nson.Equal(m, want) {
+		t.Errorf("Expected %v, got %v", want, m)
+	}
+
+	var anchored Anchored
+	if err := nson.Unmarshal(want, &anchored); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if anchored.Point == nil || *anchored.Point != (Point{1, 2}) || anchored.Name != "n" {
+		t.Errorf("Unmarshal mismatch: %+v", anchored)
+	}
+
+	// 值和指针都不使用提升的方法
+	for _, v := range []any{Tinted{}, &Tinted{}, Anchored{}, &Anchored{}} {
+		if _, ok := v.(nson.Marshaler); !ok {
+			t.Fatalf("%T should have a promoted MarshalNSON", v)
+		}
+		if _, err := nson.Marshal(v); err != nil {
+			t.Errorf("Marshal(%T) failed: %v", v, err)
+		}
+	}
+}
+
+// Recolored 嵌入了 Color 但声明了自己的 MarshalNSON 和 UnmarshalNSON，使用自己的方法
+type Recolored struct {
+	Color
+	Name string
+}
+
+func (self Recolored) MarshalNSON() (nson.Value, error) {
+	return nson.String(self.Name), nil
+}
+
+func (self *Recolored) UnmarshalNSON(v nson.Value) error {
+	s, ok := v.(nson.String)
+	if !ok {
+		return fmt.Errorf("expected String, got %T", v)
+	}
+	self.Name = string(s)
+	return nil
+}
+
+// Moved 嵌入了 *Point 并以指针接收者覆盖 MarshalNSON
+type Moved struct {
+	*Point
+	Label string
+}
+
+func (self *Moved) MarshalNSON() (nson.Value, error) {
+	return nson.String("moved:" + self.Label), nil
+}
+
+func TestOverriddenMarshaler(t *testing.T) {
+	type Holder struct {
+		R  Recolored `nson:"r"`
+		M  Moved     `nson:"m"`
+		T  Tinted    `nson:"t"`
+		RP *Recolored
+	}
+
+	h := Holder{
+		R:  Recolored{Color: Red, Name: "own"},
+		M:  Moved{Point: &Point{1, 2}, Label: "x"},
+		T:  Tinted{Color: Red, Name: "n"},
+		RP: &Recolored{Name: "ptr"},
+	}
+
+	m, err := nson.Marshal(h)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	want := nson.Map{
+		"r":  nson.String("own"),
+		"m":  nson.String("moved:x"),
+		"t":  nson.Map{"Color": nson.String("red"), "name": nson.String("n")},
+		"RP": nson.String("ptr"),
+	}
+	if !nson.Equal(m, want) {
+		t.Errorf("Expected %v, got %v", want, m)
+	}
+
+	var got Holder
+	if err := nson.Unmarshal(nson.Map{"r": nson.String("back"), "RP": nson.String("p")}, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if got.R.Name != "back" || got.RP == nil || got.RP.Name != "p" {
+		t.Errorf("Unmarshal mismatch: %+v", got)
+	}
+}
+
+// Terminal 混合了普通字段和自定义编码的字段，用于衡量每个值的接口检查开销
+type Terminal struct {
+	Id      nson.Id   `nson:"id"`
+	Name    string    `nson:"name"`
+	Addr    net.IP    `nson:"addr"`
+	Port    uint16    `nson:"port"`
+	Color   Color     `nson:"color"`
+	Corner  Point     `nson:"corner"`
+	Tags    []string  `nson:"tags"`
+	Created time.Time `nson:"created"`
+}
+
+func BenchmarkMarshalCustom(b *testing.B) {
+	d := Terminal{
+		Id:      nson.NewId(),
+		Name:    "sensor",
+		Addr:    net.IPv4(10, 0, 0, 1),
+		Port:    8080,
+		Color:   Green,
+		Corner:  Point{1, 2},
+		Tags:    []string{"a", "b"},
+		Created: time.UnixMilli(1700000000000),
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := nson.Marshal(d); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnmarshalCustom(b *testing.B) {
+	m := nson.Map{
+		"id":      nson.NewId(),
+		"name":    nson.String("sensor"),
+		"addr":    nson.String("10.0.0.1"),
+		"port":    nson.U16(8080),
+		"color":   nson.String("green"),
+		"corner":  nson.Array{nson.I32(1), nson.I32(2)},
+		"tags":    nson.Array{nson.String("a"), nson.String("b")},
+		"created": nson.Timestamp(1700000000000),
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var d Terminal
+		if err := nson.Unmarshal(m, &d); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package nson_test
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	nson "github.com/danclive/nson-go"
+)
+
+type ServerConfig struct {
+	Host    string        `nson:"host,required"`
+	Port    uint16        `nson:"port,omitempty,default=8080"`
+	Timeout time.Duration `nson:"timeout,default=1m30s"`
+	Bind    netip.Addr    `nson:"bind,default=127.0.0.1"`
+	Version Version       `nson:"version,default=1.2"`
+	Tags    []string      `nson:"tags"`
+}
+
+func TestRequiredAndDefault(t *testing.T) {
+	var c ServerConfig
+	if err := nson.Unmarshal(nson.Map{"host": nson.String("h"), "timeout": nson.I64(5)}, &c); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if c.Host != "h" || c.Port != 8080 || c.Timeout != 5 || c.Bind != netip.MustParseAddr("127.0.0.1") || c.Version != (Version{1, 2}) {
+		t.Errorf("Unexpected result: %+v", c)
+	}
+
+	c = ServerConfig{}
+	if err := nson.Unmarshal(nson.Map{"host": nson.String("h")}, &c); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if c.Timeout != 90*time.Second {
+		t.Errorf("Expected default duration, got %v", c.Timeout)
+	}
+
+	// Null 表示键存在，不使用默认值
+	c = ServerConfig{}
+	if err := nson.Unmarshal(nson.Map{"host": nson.Null{}, "port": nson.Null{}}, &c); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if c.Port != 0 {
+		t.Errorf("Expected no default for Null, got %v", c.Port)
+	}
+
+	err := nson.Unmarshal(nson.Map{"port": nson.U16(1)}, &c)
+	if err == nil || err.Error() != "missing required fields: host" {
+		t.Errorf("Expected missing field error, got %v", err)
+	}
+
+	// 类型错误优先于缺少的字段
+	err = nson.Unmarshal(nson.Map{"port": nson.I32(1)}, &c)
+	if err == nil || !strings.Contains(err.Error(), "field Port: expected U16") {
+		t.Errorf("Expected type error, got %v", err)
+	}
+}
+
+func TestInvalidDefault(t *testing.T) {
+	tests := map[string]struct {
+		v    any
+		want string
+	}{
+		"overflow": {&struct {
+			N int8 `nson:"n,default=300"`
+		}{}, `field N: invalid default "300"`},
+		"duration": {&struct {
+			D time.Duration `nson:"d,default=5"`
+		}{}, `field D: invalid default "5"`},
+		"time": {&struct {
+			T time.Time `nson:"t,default=yesterday"`
+		}{}, "expected milliseconds or RFC 3339 time"},
+		"slice": {&struct {
+			S []int `nson:"s,default=1"`
+		}{}, "unsupported type []int"},
+	}
+
+	for name, tt := range tests {
+		err := nson.Unmarshal(nson.Map{}, tt.v)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%v: expected error %q, got %v", name, tt.want, err)
+		}
+	}
+}
+
+func TestRequiredAndDefaultSchema(t *testing.T) {
+	schema, err := nson.SchemaFor[ServerConfig]()
+	if err != nil {
+		t.Fatalf("SchemaFor failed: %v", err)
+	}
+
+	for key, want := range map[string]bool{"host": true, "port": false, "timeout": false, "tags": true} {
+		if schema.Fields[key].Required != want {
+			t.Errorf("%v: expected Required %v", key, want)
+		}
+	}
+}
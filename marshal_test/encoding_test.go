@@ -0,0 +1,141 @@
+package nson_test
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	nson "github.com/danclive/nson-go"
+)
+
+// Version 实现 TextMarshaler，编码为 "1.2"
+type Version struct {
+	Major, Minor int
+}
+
+func (self Version) MarshalText() ([]byte, error) {
+	return fmt.Appendf(nil, "%d.%d", self.Major, self.Minor), nil
+}
+
+func (self *Version) UnmarshalText(text []byte) error {
+	_, err := fmt.Sscanf(string(text), "%d.%d", &self.Major, &self.Minor)
+	return err
+}
+
+// Checksum 实现 BinaryMarshaler，编码为大端序的 4 字节
+type Checksum uint32
+
+func (self Checksum) MarshalBinary() ([]byte, error) {
+	return binary.BigEndian.AppendUint32(nil, uint32(self)), nil
+}
+
+func (self *Checksum) UnmarshalBinary(data []byte) error {
+	if len(data) != 4 {
+		return fmt.Errorf("invalid checksum length %d", len(data))
+	}
+	*self = Checksum(binary.BigEndian.Uint32(data))
+	return nil
+}
+
+type Endpoint struct {
+	Addr    netip.Addr   `nson:"addr"`
+	IP      net.IP       `nson:"ip"`
+	Total   *big.Int     `nson:"total"`
+	URL     url.URL      `nson:"url"`
+	Version Version      `nson:"version"`
+	Sum     Checksum     `nson:"sum"`
+	Hosts   []netip.Addr `nson:"hosts"`
+	Seen    time.Time    `nson:"seen"`
+}
+
+func TestEncodingInterfaces(t *testing.T) {
+	u, _ := url.Parse("https://example.com/a?b=1")
+	e := Endpoint{
+		Addr:    netip.MustParseAddr("10.0.0.1"),
+		IP:      net.ParseIP("::1"),
+		Total:   new(big.Int).Lsh(big.NewInt(1), 100),
+		URL:     *u,
+		Version: Version{1, 2},
+		Sum:     0xdeadbeef,
+		Hosts:   []netip.Addr{netip.MustParseAddr("::2")},
+		Seen:    time.UnixMilli(1700000000000),
+	}
+
+	m, err := nson.Marshal(e)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	want := nson.Map{
+		"addr":    nson.String("10.0.0.1"),
+		"ip":      nson.String("::1"),
+		"total":   nson.String("1267650600228229401496703205376"),
+		"url":     nson.Binary("https://example.com/a?b=1"),
+		"version": nson.String("1.2"),
+		"sum":     nson.Binary{0xde, 0xad, 0xbe, 0xef},
+		"hosts":   nson.Array{nson.String("::2")},
+		"seen":    nson.Timestamp(1700000000000),
+	}
+
+	if !nson.Equal(m, want) {
+		t.Errorf("Marshal mismatch:\n got: %v\nwant: %v", m, want)
+	}
+
+	var got Endpoint
+	if err := nson.Unmarshal(m, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if got.Addr != e.Addr || !got.IP.Equal(e.IP) || got.Total.Cmp(e.Total) != 0 || got.URL.String() != u.String() ||
+		got.Version != e.Version || got.Sum != e.Sum || got.Hosts[0] != e.Hosts[0] || !got.Seen.Equal(e.Seen) {
+		t.Errorf("Unmarshal mismatch: %+v", got)
+	}
+}
+
+func TestEncodingInterfacesErrors(t *testing.T) {
+	tests := map[string]struct {
+		m    nson.Map
+		want string
+	}{
+		"text":        {nson.Map{"addr": nson.I32(1)}, "field Addr: expected String or Binary, got nson.I32"},
+		"text only":   {nson.Map{"version": nson.Binary("1.2")}, "field Version: expected String, got nson.Binary"},
+		"binary only": {nson.Map{"sum": nson.String("x")}, "field Sum: expected Binary, got nson.String"},
+		"invalid":     {nson.Map{"sum": nson.Binary{1}}, "field Sum: invalid checksum length 1"},
+	}
+
+	for name, tt := range tests {
+		var e Endpoint
+		err := nson.Unmarshal(tt.m, &e)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%v: expected error %q, got %v", name, tt.want, err)
+		}
+	}
+}
+
+func TestEncodingInterfacesSchema(t *testing.T) {
+	schema, err := nson.SchemaFor[Endpoint]()
+	if err != nil {
+		t.Fatalf("SchemaFor failed: %v", err)
+	}
+
+	wantTypes := map[string]nson.DataType{
+		"addr":    nson.DataTypeSTRING,
+		"ip":      nson.DataTypeSTRING,
+		"url":     nson.DataTypeBINARY,
+		"version": nson.DataTypeSTRING,
+		"sum":     nson.DataTypeBINARY,
+		"seen":    nson.DataTypeTIMESTAMP,
+	}
+
+	for key, dt := range wantTypes {
+		if types := schema.Fields[key].Types; len(types) != 1 || types[0] != dt {
+			t.Errorf("%v: expected %v, got %v", key, dt, types)
+		}
+	}
+}
@@ -0,0 +1,1088 @@
+// Code generated by nsongen -marshal. DO NOT EDIT.
+
+package nson_test
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	nson "github.com/danclive/nson-go"
+)
+
+// MarshalNSON 实现 nson.MapMarshaler
+func (self Sensor) MarshalNSON() (nson.Map, error) {
+	m := make(nson.Map, 30)
+	m["_id"] = self.GenBase.Id
+	m["created"] = nson.Timestamp(self.GenBase.Created.UnixMilli())
+	v1, err2 := self.Point.MarshalNSON()
+	if err2 != nil {
+		return nil, fmt.Errorf("field Point: %w", err2)
+	}
+	if v1 == nil {
+		m["Point"] = nson.Null{}
+	} else {
+		m["Point"] = v1
+	}
+	m["name"] = nson.String(self.Name)
+	m["port"] = nson.U16(self.Port)
+	if self.Count != 0 {
+		m["count"] = nson.I32(self.Count)
+	}
+	m["total"] = nson.I64(self.Total)
+	m["ratio"] = nson.F64(self.Ratio)
+	if self.Enabled {
+		m["enabled"] = nson.Bool(self.Enabled)
+	}
+	m["level"] = nson.U8(self.Level)
+	if len(self.Payload) != 0 {
+		m["payload"] = nson.Binary(self.Payload)
+	}
+	arr3 := make(nson.Array, len(self.Tags))
+	for i4 := range self.Tags {
+		arr3[i4] = nson.String(self.Tags[i4])
+	}
+	m["tags"] = arr3
+	arr5 := make(nson.Array, len(self.Matrix))
+	for i6 := range self.Matrix {
+		arr7 := make(nson.Array, len(self.Matrix[i6]))
+		for i8 := range self.Matrix[i6] {
+			arr7[i8] = nson.I8(self.Matrix[i6][i8])
+		}
+		arr5[i6] = arr7
+	}
+	m["matrix"] = arr5
+	if len(self.Labels) != 0 {
+		m9 := make(nson.Map, len(self.Labels))
+		for k10, v11 := range self.Labels {
+			m9[string(k10)] = nson.String(v11)
+		}
+		m["labels"] = m9
+	}
+	m12 := make(nson.Map, len(self.Scores))
+	for k13, v14 := range self.Scores {
+		m12[string(k13)] = nson.F32(v14)
+	}
+	m["scores"] = m12
+	if self.Note == nil {
+		m["note"] = nson.Null{}
+	} else {
+		m["note"] = nson.String((*self.Note))
+	}
+	if self.Extra == nil {
+		m["extra"] = nson.Null{}
+	} else {
+		m["extra"] = self.Extra
+	}
+	if len(self.Attrs) != 0 {
+		m15 := make(nson.Map, len(self.Attrs))
+		for k16, v17 := range self.Attrs {
+			if v17 == nil {
+				m15[string(k16)] = nson.Null{}
+			} else {
+				m15[string(k16)] = v17
+			}
+		}
+		m["attrs"] = m15
+	}
+	if len(self.Raw) != 0 {
+		arr18 := make(nson.Array, len(self.Raw))
+		for i19 := range self.Raw {
+			if self.Raw[i19] == nil {
+				arr18[i19] = nson.Null{}
+			} else {
+				arr18[i19] = self.Raw[i19]
+			}
+		}
+		m["raw"] = arr18
+	}
+	if self.Last == nil {
+		m["last"] = nson.Null{}
+	} else {
+		m20, err21 := (*self.Last).MarshalNSON()
+		if err21 != nil {
+			return nil, fmt.Errorf("field Last: %w", err21)
+		}
+		m["last"] = m20
+	}
+	arr22 := make(nson.Array, len(self.Readings))
+	for i23 := range self.Readings {
+		m24, err25 := self.Readings[i23].MarshalNSON()
+		if err25 != nil {
+			return nil, fmt.Errorf("field Readings: index %d: %w", i23, err25)
+		}
+		arr22[i23] = m24
+	}
+	m["readings"] = arr22
+	if self.Seen != nil {
+		if self.Seen == nil {
+			m["seen"] = nson.Null{}
+		} else {
+			m["seen"] = nson.Timestamp((*self.Seen).UnixMilli())
+		}
+	}
+	if self.Shade != 0 {
+		v26, err27 := self.Shade.MarshalNSON()
+		if err27 != nil {
+			return nil, fmt.Errorf("field Shade: %w", err27)
+		}
+		if v26 == nil {
+			m["shade"] = nson.Null{}
+		} else {
+			m["shade"] = v26
+		}
+	}
+	arr28 := make(nson.Array, len(self.Palette))
+	for i29 := range self.Palette {
+		v30, err31 := self.Palette[i29].MarshalNSON()
+		if err31 != nil {
+			return nil, fmt.Errorf("field Palette: index %d: %w", i29, err31)
+		}
+		if v30 == nil {
+			arr28[i29] = nson.Null{}
+		} else {
+			arr28[i29] = v30
+		}
+	}
+	m["palette"] = arr28
+	data32, err33 := self.Version.MarshalText()
+	if err33 != nil {
+		return nil, fmt.Errorf("field Version: %w", err33)
+	}
+	m["version"] = nson.String(data32)
+	if self.Sum != 0 {
+		data34, err35 := self.Sum.MarshalBinary()
+		if err35 != nil {
+			return nil, fmt.Errorf("field Sum: %w", err35)
+		}
+		m["sum"] = nson.Binary(data34)
+	}
+	m["owner"] = nson.String(self.Meta.Owner)
+	if self.Loc != nil {
+		m["lat"] = nson.F64(self.Loc.Lat)
+	}
+	if self.Loc != nil {
+		m["lng"] = nson.F64(self.Loc.Lng)
+	}
+	m["Untagged"] = nson.U32(self.Untagged)
+	for k, v := range self.Rest {
+		switch k {
+		case "_id", "created", "Point", "name", "port", "count", "total", "ratio", "enabled", "level", "payload", "tags", "matrix", "labels", "scores", "note", "extra", "attrs", "raw", "last", "readings", "seen", "shade", "palette", "version", "sum", "owner", "lat", "lng", "Untagged":
+			continue
+		}
+		if v == nil {
+			m[string(k)] = nson.Null{}
+		} else {
+			m[string(k)] = v
+		}
+	}
+	return m, nil
+}
+
+// AppendNSON 将编码后的 Map 追加到 buf，结果与 nson.EncodeMap 相同
+func (self Sensor) AppendNSON(buf []byte) ([]byte, error) {
+	var err error
+	var start int
+	buf, start = nson.AppendMapStart(buf)
+	buf = append(buf, "\x04_id"...)
+	buf = nson.AppendId(buf, self.GenBase.Id)
+	buf = append(buf, "\bcreated"...)
+	buf = nson.AppendTimestamp(buf, nson.Timestamp(self.GenBase.Created.UnixMilli()))
+	buf = append(buf, "\x06Point"...)
+	v36, err37 := self.Point.MarshalNSON()
+	if err37 != nil {
+		return nil, fmt.Errorf("field Point: %w", err37)
+	}
+	if v36 == nil {
+		buf = nson.AppendNull(buf)
+	} else if buf, err = nson.AppendValue(buf, v36); err != nil {
+		return nil, fmt.Errorf("field Point: %w", err)
+	}
+	buf = append(buf, "\x05name"...)
+	buf = nson.AppendString(buf, nson.String(self.Name))
+	buf = append(buf, "\x05port"...)
+	buf = nson.AppendU16(buf, nson.U16(self.Port))
+	if self.Count != 0 {
+		buf = append(buf, "\x06count"...)
+		buf = nson.AppendI32(buf, nson.I32(self.Count))
+	}
+	buf = append(buf, "\x06total"...)
+	buf = nson.AppendI64(buf, nson.I64(self.Total))
+	buf = append(buf, "\x06ratio"...)
+	buf = nson.AppendF64(buf, nson.F64(self.Ratio))
+	if self.Enabled {
+		buf = append(buf, "\benabled"...)
+		buf = nson.AppendBool(buf, nson.Bool(self.Enabled))
+	}
+	buf = append(buf, "\x06level"...)
+	buf = nson.AppendU8(buf, nson.U8(self.Level))
+	if len(self.Payload) != 0 {
+		buf = append(buf, "\bpayload"...)
+		buf = nson.AppendBinary(buf, nson.Binary(self.Payload))
+	}
+	buf = append(buf, "\x05tags"...)
+	buf = append(buf, byte(nson.DataTypeARRAY))
+	var start38 int
+	buf, start38 = nson.AppendArrayStart(buf)
+	for i39 := range self.Tags {
+		buf = nson.AppendString(buf, nson.String(self.Tags[i39]))
+	}
+	buf = nson.AppendArrayEnd(buf, start38)
+	buf = append(buf, "\amatrix"...)
+	buf = append(buf, byte(nson.DataTypeARRAY))
+	var start40 int
+	buf, start40 = nson.AppendArrayStart(buf)
+	for i41 := range self.Matrix {
+		buf = append(buf, byte(nson.DataTypeARRAY))
+		var start42 int
+		buf, start42 = nson.AppendArrayStart(buf)
+		for i43 := range self.Matrix[i41] {
+			buf = nson.AppendI8(buf, nson.I8(self.Matrix[i41][i43]))
+		}
+		buf = nson.AppendArrayEnd(buf, start42)
+	}
+	buf = nson.AppendArrayEnd(buf, start40)
+	if len(self.Labels) != 0 {
+		buf = append(buf, "\alabels"...)
+		buf = append(buf, byte(nson.DataTypeMAP))
+		var start44 int
+		buf, start44 = nson.AppendMapStart(buf)
+		for k45, v46 := range self.Labels {
+			if buf, err = nson.AppendKey(buf, string(k45)); err != nil {
+				return nil, fmt.Errorf("field Labels: key %s: %w", k45, err)
+			}
+			buf = nson.AppendString(buf, nson.String(v46))
+		}
+		buf = nson.AppendMapEnd(buf, start44)
+	}
+	buf = append(buf, "\ascores"...)
+	buf = append(buf, byte(nson.DataTypeMAP))
+	var start47 int
+	buf, start47 = nson.AppendMapStart(buf)
+	for k48, v49 := range self.Scores {
+		if buf, err = nson.AppendKey(buf, string(k48)); err != nil {
+			return nil, fmt.Errorf("field Scores: key %s: %w", k48, err)
+		}
+		buf = nson.AppendF32(buf, nson.F32(v49))
+	}
+	buf = nson.AppendMapEnd(buf, start47)
+	buf = append(buf, "\x05note"...)
+	if self.Note == nil {
+		buf = nson.AppendNull(buf)
+	} else {
+		buf = nson.AppendString(buf, nson.String((*self.Note)))
+	}
+	buf = append(buf, "\x06extra"...)
+	if self.Extra == nil {
+		buf = nson.AppendNull(buf)
+	} else {
+		if buf, err = nson.AppendValue(buf, self.Extra); err != nil {
+			return nil, fmt.Errorf("field Extra: %w", err)
+		}
+	}
+	if len(self.Attrs) != 0 {
+		buf = append(buf, "\x06attrs"...)
+		buf = append(buf, byte(nson.DataTypeMAP))
+		var start50 int
+		buf, start50 = nson.AppendMapStart(buf)
+		for k51, v52 := range self.Attrs {
+			if buf, err = nson.AppendKey(buf, string(k51)); err != nil {
+				return nil, fmt.Errorf("field Attrs: key %s: %w", k51, err)
+			}
+			if v52 == nil {
+				buf = nson.AppendNull(buf)
+			} else {
+				if buf, err = nson.AppendValue(buf, v52); err != nil {
+					return nil, fmt.Errorf("field Attrs: key %s: %w", k51, err)
+				}
+			}
+		}
+		buf = nson.AppendMapEnd(buf, start50)
+	}
+	if len(self.Raw) != 0 {
+		buf = append(buf, "\x04raw"...)
+		buf = append(buf, byte(nson.DataTypeARRAY))
+		var start53 int
+		buf, start53 = nson.AppendArrayStart(buf)
+		for i54 := range self.Raw {
+			if self.Raw[i54] == nil {
+				buf = nson.AppendNull(buf)
+			} else {
+				if buf, err = nson.AppendValue(buf, self.Raw[i54]); err != nil {
+					return nil, fmt.Errorf("field Raw: index %d: %w", i54, err)
+				}
+			}
+		}
+		buf = nson.AppendArrayEnd(buf, start53)
+	}
+	buf = append(buf, "\x05last"...)
+	if self.Last == nil {
+		buf = nson.AppendNull(buf)
+	} else {
+		buf = append(buf, byte(nson.DataTypeMAP))
+		if buf, err = (*self.Last).AppendNSON(buf); err != nil {
+			return nil, fmt.Errorf("field Last: %w", err)
+		}
+	}
+	buf = append(buf, "\treadings"...)
+	buf = append(buf, byte(nson.DataTypeARRAY))
+	var start55 int
+	buf, start55 = nson.AppendArrayStart(buf)
+	for i56 := range self.Readings {
+		buf = append(buf, byte(nson.DataTypeMAP))
+		if buf, err = self.Readings[i56].AppendNSON(buf); err != nil {
+			return nil, fmt.Errorf("field Readings: index %d: %w", i56, err)
+		}
+	}
+	buf = nson.AppendArrayEnd(buf, start55)
+	if self.Seen != nil {
+		buf = append(buf, "\x05seen"...)
+		if self.Seen == nil {
+			buf = nson.AppendNull(buf)
+		} else {
+			buf = nson.AppendTimestamp(buf, nson.Timestamp((*self.Seen).UnixMilli()))
+		}
+	}
+	if self.Shade != 0 {
+		buf = append(buf, "\x06shade"...)
+		v57, err58 := self.Shade.MarshalNSON()
+		if err58 != nil {
+			return nil, fmt.Errorf("field Shade: %w", err58)
+		}
+		if v57 == nil {
+			buf = nson.AppendNull(buf)
+		} else if buf, err = nson.AppendValue(buf, v57); err != nil {
+			return nil, fmt.Errorf("field Shade: %w", err)
+		}
+	}
+	buf = append(buf, "\bpalette"...)
+	buf = append(buf, byte(nson.DataTypeARRAY))
+	var start59 int
+	buf, start59 = nson.AppendArrayStart(buf)
+	for i60 := range self.Palette {
+		v61, err62 := self.Palette[i60].MarshalNSON()
+		if err62 != nil {
+			return nil, fmt.Errorf("field Palette: index %d: %w", i60, err62)
+		}
+		if v61 == nil {
+			buf = nson.AppendNull(buf)
+		} else if buf, err = nson.AppendValue(buf, v61); err != nil {
+			return nil, fmt.Errorf("field Palette: index %d: %w", i60, err)
+		}
+	}
+	buf = nson.AppendArrayEnd(buf, start59)
+	buf = append(buf, "\bversion"...)
+	data63, err64 := self.Version.MarshalText()
+	if err64 != nil {
+		return nil, fmt.Errorf("field Version: %w", err64)
+	}
+	buf = nson.AppendString(buf, nson.String(data63))
+	if self.Sum != 0 {
+		buf = append(buf, "\x04sum"...)
+		data65, err66 := self.Sum.MarshalBinary()
+		if err66 != nil {
+			return nil, fmt.Errorf("field Sum: %w", err66)
+		}
+		buf = nson.AppendBinary(buf, nson.Binary(data65))
+	}
+	buf = append(buf, "\x06owner"...)
+	buf = nson.AppendString(buf, nson.String(self.Meta.Owner))
+	if self.Loc != nil {
+		buf = append(buf, "\x04lat"...)
+		buf = nson.AppendF64(buf, nson.F64(self.Loc.Lat))
+	}
+	if self.Loc != nil {
+		buf = append(buf, "\x04lng"...)
+		buf = nson.AppendF64(buf, nson.F64(self.Loc.Lng))
+	}
+	buf = append(buf, "\tUntagged"...)
+	buf = nson.AppendU32(buf, nson.U32(self.Untagged))
+	for k, v := range self.Rest {
+		switch k {
+		case "_id", "created", "Point", "name", "port", "count", "total", "ratio", "enabled", "level", "payload", "tags", "matrix", "labels", "scores", "note", "extra", "attrs", "raw", "last", "readings", "seen", "shade", "palette", "version", "sum", "owner", "lat", "lng", "Untagged":
+			continue
+		}
+		if buf, err = nson.AppendKey(buf, string(k)); err != nil {
+			return nil, fmt.Errorf("field Rest: key %s: %w", k, err)
+		}
+		if v == nil {
+			buf = nson.AppendNull(buf)
+		} else {
+			if buf, err = nson.AppendValue(buf, v); err != nil {
+				return nil, fmt.Errorf("field Rest: key %s: %w", k, err)
+			}
+		}
+	}
+	return nson.AppendMapEnd(buf, start), nil
+}
+
+// UnmarshalNSON 实现 nson.MapUnmarshaler
+func (self *Sensor) UnmarshalNSON(m nson.Map) error {
+	if v67, has := m["_id"]; has {
+		switch x68 := v67.(type) {
+		case nson.Null:
+		case nson.Id:
+			self.GenBase.Id = x68
+		default:
+			return fmt.Errorf("field Id: expected nson.Id, got %T", v67)
+		}
+	}
+	if v69, has := m["created"]; has {
+		switch x70 := v69.(type) {
+		case nson.Null:
+		case nson.Timestamp:
+			self.GenBase.Created = time.UnixMilli(int64(x70))
+		default:
+			return fmt.Errorf("field Created: expected Timestamp for time.Time, got %T", v69)
+		}
+	}
+	if v71, has := m["Point"]; has {
+		if err72 := self.Point.UnmarshalNSON(v71); err72 != nil {
+			return fmt.Errorf("field Point: %w", err72)
+		}
+	}
+	if v73, has := m["name"]; has {
+		switch x74 := v73.(type) {
+		case nson.Null:
+		case nson.String:
+			self.Name = string(x74)
+		default:
+			return fmt.Errorf("field Name: expected String, got %T", v73)
+		}
+	}
+	if v75, has := m["port"]; has {
+		switch x76 := v75.(type) {
+		case nson.Null:
+		case nson.U16:
+			self.Port = uint16(x76)
+		default:
+			return fmt.Errorf("field Port: expected U16, got %T", v75)
+		}
+	}
+	if v77, has := m["count"]; has {
+		switch x78 := v77.(type) {
+		case nson.Null:
+		case nson.I32:
+			self.Count = int(x78)
+		default:
+			return fmt.Errorf("field Count: expected I32, got %T", v77)
+		}
+	}
+	if v79, has := m["total"]; has {
+		switch x80 := v79.(type) {
+		case nson.Null:
+		case nson.I64:
+			self.Total = int64(x80)
+		case nson.Timestamp:
+			self.Total = int64(x80)
+		default:
+			return fmt.Errorf("field Total: expected I64 or Timestamp, got %T", v79)
+		}
+	}
+	if v81, has := m["ratio"]; has {
+		switch x82 := v81.(type) {
+		case nson.Null:
+		case nson.F64:
+			self.Ratio = float64(x82)
+		default:
+			return fmt.Errorf("field Ratio: expected F64, got %T", v81)
+		}
+	}
+	if v83, has := m["enabled"]; has {
+		switch x84 := v83.(type) {
+		case nson.Null:
+		case nson.Bool:
+			self.Enabled = bool(x84)
+		default:
+			return fmt.Errorf("field Enabled: expected Bool, got %T", v83)
+		}
+	}
+	if v85, has := m["level"]; has {
+		switch x86 := v85.(type) {
+		case nson.Null:
+		case nson.U8:
+			self.Level = Level(x86)
+		default:
+			return fmt.Errorf("field Level: expected U8, got %T", v85)
+		}
+	}
+	if v87, has := m["payload"]; has {
+		switch x88 := v87.(type) {
+		case nson.Null:
+		case nson.Binary:
+			self.Payload = []byte(x88)
+		default:
+			return fmt.Errorf("field Payload: expected Binary, got %T", v87)
+		}
+	}
+	if v89, has := m["tags"]; has {
+		switch x90 := v89.(type) {
+		case nson.Null:
+		case nson.Array:
+			s91 := make([]string, len(x90))
+			for i92, item93 := range x90 {
+				switch x94 := item93.(type) {
+				case nson.Null:
+				case nson.String:
+					s91[i92] = string(x94)
+				default:
+					return fmt.Errorf("field Tags: index %d: expected String, got %T", i92, item93)
+				}
+			}
+			self.Tags = s91
+		default:
+			return fmt.Errorf("field Tags: expected Array, got %T", v89)
+		}
+	}
+	if v95, has := m["matrix"]; has {
+		switch x96 := v95.(type) {
+		case nson.Null:
+		case nson.Array:
+			if len(x96) != len(self.Matrix) {
+				return fmt.Errorf("field Matrix: array length mismatch: expected %d, got %d", len(self.Matrix), len(x96))
+			}
+			for i97, item98 := range x96 {
+				switch x99 := item98.(type) {
+				case nson.Null:
+				case nson.Array:
+					s100 := make([]int8, len(x99))
+					for i101, item102 := range x99 {
+						switch x103 := item102.(type) {
+						case nson.Null:
+						case nson.I8:
+							s100[i101] = int8(x103)
+						default:
+							return fmt.Errorf("field Matrix: index %d: index %d: expected I8, got %T", i97, i101, item102)
+						}
+					}
+					self.Matrix[i97] = s100
+				default:
+					return fmt.Errorf("field Matrix: index %d: expected Array, got %T", i97, item98)
+				}
+			}
+		default:
+			return fmt.Errorf("field Matrix: expected Array, got %T", v95)
+		}
+	}
+	if v104, has := m["labels"]; has {
+		switch x105 := v104.(type) {
+		case nson.Null:
+		case nson.Map:
+			m106 := make(Labels, len(x105))
+			for k107, item108 := range x105 {
+				var e109 string
+				switch x110 := item108.(type) {
+				case nson.Null:
+				case nson.String:
+					e109 = string(x110)
+				default:
+					return fmt.Errorf("field Labels: key %s: expected String, got %T", k107, item108)
+				}
+				m106[string(k107)] = e109
+			}
+			self.Labels = m106
+		default:
+			return fmt.Errorf("field Labels: expected Map, got %T", v104)
+		}
+	}
+	if v111, has := m["scores"]; has {
+		switch x112 := v111.(type) {
+		case nson.Null:
+		case nson.Map:
+			m113 := make(map[string]float32, len(x112))
+			for k114, item115 := range x112 {
+				var e116 float32
+				switch x117 := item115.(type) {
+				case nson.Null:
+				case nson.F32:
+					e116 = float32(x117)
+				default:
+					return fmt.Errorf("field Scores: key %s: expected F32, got %T", k114, item115)
+				}
+				m113[string(k114)] = e116
+			}
+			self.Scores = m113
+		default:
+			return fmt.Errorf("field Scores: expected Map, got %T", v111)
+		}
+	}
+	if v118, has := m["note"]; has {
+		if _, ok := v118.(nson.Null); ok {
+			self.Note = nil
+		} else {
+			if self.Note == nil {
+				self.Note = new(string)
+			}
+			switch x119 := v118.(type) {
+			case nson.Null:
+			case nson.String:
+				(*self.Note) = string(x119)
+			default:
+				return fmt.Errorf("field Note: expected String, got %T", v118)
+			}
+		}
+	}
+	if v120, has := m["extra"]; has {
+		if _, ok := v120.(nson.Null); !ok {
+			self.Extra = v120
+		}
+	}
+	if v121, has := m["attrs"]; has {
+		switch x122 := v121.(type) {
+		case nson.Null:
+		case nson.Map:
+			self.Attrs = x122
+		default:
+			return fmt.Errorf("field Attrs: expected Map, got %T", v121)
+		}
+	}
+	if v123, has := m["raw"]; has {
+		switch x124 := v123.(type) {
+		case nson.Null:
+		case nson.Array:
+			s125 := make(nson.Array, len(x124))
+			for i126, item127 := range x124 {
+				if _, ok := item127.(nson.Null); !ok {
+					s125[i126] = item127
+				}
+			}
+			self.Raw = s125
+		default:
+			return fmt.Errorf("field Raw: expected Array, got %T", v123)
+		}
+	}
+	if v128, has := m["last"]; has {
+		if _, ok := v128.(nson.Null); ok {
+			self.Last = nil
+		} else {
+			if self.Last == nil {
+				self.Last = new(Reading)
+			}
+			switch x129 := v128.(type) {
+			case nson.Null:
+			case nson.Map:
+				if err130 := (*self.Last).UnmarshalNSON(x129); err130 != nil {
+					return fmt.Errorf("field Last: %w", err130)
+				}
+			default:
+				return fmt.Errorf("field Last: expected Map, got %T", v128)
+			}
+		}
+	}
+	if v131, has := m["readings"]; has {
+		switch x132 := v131.(type) {
+		case nson.Null:
+		case nson.Array:
+			s133 := make([]Reading, len(x132))
+			for i134, item135 := range x132 {
+				switch x136 := item135.(type) {
+				case nson.Null:
+				case nson.Map:
+					if err137 := s133[i134].UnmarshalNSON(x136); err137 != nil {
+						return fmt.Errorf("field Readings: index %d: %w", i134, err137)
+					}
+				default:
+					return fmt.Errorf("field Readings: index %d: expected Map, got %T", i134, item135)
+				}
+			}
+			self.Readings = s133
+		default:
+			return fmt.Errorf("field Readings: expected Array, got %T", v131)
+		}
+	}
+	if v138, has := m["seen"]; has {
+		if _, ok := v138.(nson.Null); ok {
+			self.Seen = nil
+		} else {
+			if self.Seen == nil {
+				self.Seen = new(time.Time)
+			}
+			switch x139 := v138.(type) {
+			case nson.Null:
+			case nson.Timestamp:
+				(*self.Seen) = time.UnixMilli(int64(x139))
+			default:
+				return fmt.Errorf("field Seen: expected Timestamp for time.Time, got %T", v138)
+			}
+		}
+	}
+	if v140, has := m["shade"]; has {
+		if err141 := self.Shade.UnmarshalNSON(v140); err141 != nil {
+			return fmt.Errorf("field Shade: %w", err141)
+		}
+	}
+	if v142, has := m["palette"]; has {
+		switch x143 := v142.(type) {
+		case nson.Null:
+		case nson.Array:
+			s144 := make([]Color, len(x143))
+			for i145, item146 := range x143 {
+				if err147 := s144[i145].UnmarshalNSON(item146); err147 != nil {
+					return fmt.Errorf("field Palette: index %d: %w", i145, err147)
+				}
+			}
+			self.Palette = s144
+		default:
+			return fmt.Errorf("field Palette: expected Array, got %T", v142)
+		}
+	}
+	if v148, has := m["version"]; has {
+		switch x149 := v148.(type) {
+		case nson.Null:
+		case nson.String:
+			if err150 := self.Version.UnmarshalText([]byte(x149)); err150 != nil {
+				return fmt.Errorf("field Version: %w", err150)
+			}
+		default:
+			return fmt.Errorf("field Version: expected String, got %T", v148)
+		}
+	}
+	if v151, has := m["sum"]; has {
+		switch x152 := v151.(type) {
+		case nson.Null:
+		case nson.Binary:
+			if err153 := self.Sum.UnmarshalBinary([]byte(x152)); err153 != nil {
+				return fmt.Errorf("field Sum: %w", err153)
+			}
+		default:
+			return fmt.Errorf("field Sum: expected Binary, got %T", v151)
+		}
+	}
+	if v154, has := m["owner"]; has {
+		switch x155 := v154.(type) {
+		case nson.Null:
+		case nson.String:
+			self.Meta.Owner = string(x155)
+		default:
+			return fmt.Errorf("field Owner: expected String, got %T", v154)
+		}
+	}
+	if v156, has := m["lat"]; has {
+		if self.Loc == nil {
+			self.Loc = new(Location)
+		}
+		switch x157 := v156.(type) {
+		case nson.Null:
+		case nson.F64:
+			self.Loc.Lat = float64(x157)
+		default:
+			return fmt.Errorf("field Lat: expected F64, got %T", v156)
+		}
+	}
+	if v158, has := m["lng"]; has {
+		if self.Loc == nil {
+			self.Loc = new(Location)
+		}
+		switch x159 := v158.(type) {
+		case nson.Null:
+		case nson.F64:
+			self.Loc.Lng = float64(x159)
+		default:
+			return fmt.Errorf("field Lng: expected F64, got %T", v158)
+		}
+	}
+	if v160, has := m["Untagged"]; has {
+		switch x161 := v160.(type) {
+		case nson.Null:
+		case nson.U32:
+			self.Untagged = uint32(x161)
+		default:
+			return fmt.Errorf("field Untagged: expected U32, got %T", v160)
+		}
+	}
+	var rest nson.Map
+	for k, v := range m {
+		switch k {
+		case "_id", "created", "Point", "name", "port", "count", "total", "ratio", "enabled", "level", "payload", "tags", "matrix", "labels", "scores", "note", "extra", "attrs", "raw", "last", "readings", "seen", "shade", "palette", "version", "sum", "owner", "lat", "lng", "Untagged":
+			continue
+		}
+		if rest == nil {
+			rest = make(nson.Map)
+		}
+		rest[k] = v
+	}
+	if rest != nil {
+		self.Rest = rest
+	}
+	return nil
+}
+
+// MarshalNSON 实现 nson.MapMarshaler
+func (self Settings) MarshalNSON() (nson.Map, error) {
+	m := make(nson.Map, 1)
+	m["version"] = nson.I32(self.Version)
+	for k, v := range self.Values {
+		switch k {
+		case "version":
+			continue
+		}
+		m[string(k)] = nson.I64(v)
+	}
+	return m, nil
+}
+
+// AppendNSON 将编码后的 Map 追加到 buf，结果与 nson.EncodeMap 相同
+func (self Settings) AppendNSON(buf []byte) ([]byte, error) {
+	var err error
+	var start int
+	buf, start = nson.AppendMapStart(buf)
+	buf = append(buf, "\bversion"...)
+	buf = nson.AppendI32(buf, nson.I32(self.Version))
+	for k, v := range self.Values {
+		switch k {
+		case "version":
+			continue
+		}
+		if buf, err = nson.AppendKey(buf, string(k)); err != nil {
+			return nil, fmt.Errorf("field Values: key %s: %w", k, err)
+		}
+		buf = nson.AppendI64(buf, nson.I64(v))
+	}
+	return nson.AppendMapEnd(buf, start), nil
+}
+
+// UnmarshalNSON 实现 nson.MapUnmarshaler
+func (self *Settings) UnmarshalNSON(m nson.Map) error {
+	if v162, has := m["version"]; has {
+		switch x163 := v162.(type) {
+		case nson.Null:
+		case nson.I32:
+			self.Version = int32(x163)
+		default:
+			return fmt.Errorf("field Version: expected I32, got %T", v162)
+		}
+	}
+	var rest map[string]int64
+	for k, v := range m {
+		switch k {
+		case "version":
+			continue
+		}
+		if rest == nil {
+			rest = make(map[string]int64)
+		}
+		var e164 int64
+		switch x165 := v.(type) {
+		case nson.Null:
+		case nson.I64:
+			e164 = int64(x165)
+		case nson.Timestamp:
+			e164 = int64(x165)
+		default:
+			return fmt.Errorf("field Values: key %s: expected I64 or Timestamp, got %T", k, v)
+		}
+		rest[string(k)] = e164
+	}
+	if rest != nil {
+		self.Values = rest
+	}
+	return nil
+}
+
+// MarshalNSON 实现 nson.MapMarshaler
+func (self Job) MarshalNSON() (nson.Map, error) {
+	m := make(nson.Map, 9)
+	m["name"] = nson.String(self.Name)
+	m["owner"] = nson.String(self.Owner)
+	m["mode"] = nson.String(self.Mode)
+	if self.Retries == nil {
+		m["retries"] = nson.Null{}
+	} else {
+		m["retries"] = nson.I8((*self.Retries))
+	}
+	m["limit"] = nson.U32(self.Limit)
+	m["weight"] = nson.F32(self.Weight)
+	m["strict"] = nson.Bool(self.Strict)
+	m["start"] = nson.Timestamp(self.Start.UnixMilli())
+	m["deadline"] = nson.U64(self.Deadline)
+	return m, nil
+}
+
+// AppendNSON 将编码后的 Map 追加到 buf，结果与 nson.EncodeMap 相同
+func (self Job) AppendNSON(buf []byte) ([]byte, error) {
+	var start int
+	buf, start = nson.AppendMapStart(buf)
+	buf = append(buf, "\x05name"...)
+	buf = nson.AppendString(buf, nson.String(self.Name))
+	buf = append(buf, "\x06owner"...)
+	buf = nson.AppendString(buf, nson.String(self.Owner))
+	buf = append(buf, "\x05mode"...)
+	buf = nson.AppendString(buf, nson.String(self.Mode))
+	buf = append(buf, "\bretries"...)
+	if self.Retries == nil {
+		buf = nson.AppendNull(buf)
+	} else {
+		buf = nson.AppendI8(buf, nson.I8((*self.Retries)))
+	}
+	buf = append(buf, "\x06limit"...)
+	buf = nson.AppendU32(buf, nson.U32(self.Limit))
+	buf = append(buf, "\aweight"...)
+	buf = nson.AppendF32(buf, nson.F32(self.Weight))
+	buf = append(buf, "\astrict"...)
+	buf = nson.AppendBool(buf, nson.Bool(self.Strict))
+	buf = append(buf, "\x06start"...)
+	buf = nson.AppendTimestamp(buf, nson.Timestamp(self.Start.UnixMilli()))
+	buf = append(buf, "\tdeadline"...)
+	buf = nson.AppendU64(buf, nson.U64(self.Deadline))
+	return nson.AppendMapEnd(buf, start), nil
+}
+
+// UnmarshalNSON 实现 nson.MapUnmarshaler
+func (self *Job) UnmarshalNSON(m nson.Map) error {
+	var missing []string
+	if v166, has := m["name"]; has {
+		switch x167 := v166.(type) {
+		case nson.Null:
+		case nson.String:
+			self.Name = string(x167)
+		default:
+			return fmt.Errorf("field Name: expected String, got %T", v166)
+		}
+	} else {
+		missing = append(missing, "name")
+	}
+	if v168, has := m["owner"]; has {
+		switch x169 := v168.(type) {
+		case nson.Null:
+		case nson.String:
+			self.Owner = string(x169)
+		default:
+			return fmt.Errorf("field Owner: expected String, got %T", v168)
+		}
+	} else {
+		missing = append(missing, "owner")
+	}
+	if v170, has := m["mode"]; has {
+		switch x171 := v170.(type) {
+		case nson.Null:
+		case nson.String:
+			self.Mode = Mode(x171)
+		default:
+			return fmt.Errorf("field Mode: expected String, got %T", v170)
+		}
+	} else {
+		self.Mode = Mode("auto")
+	}
+	if v172, has := m["retries"]; has {
+		if _, ok := v172.(nson.Null); ok {
+			self.Retries = nil
+		} else {
+			if self.Retries == nil {
+				self.Retries = new(int8)
+			}
+			switch x173 := v172.(type) {
+			case nson.Null:
+			case nson.I8:
+				(*self.Retries) = int8(x173)
+			default:
+				return fmt.Errorf("field Retries: expected I8, got %T", v172)
+			}
+		}
+	} else {
+		self.Retries = new(int8)
+		*self.Retries = int8(3)
+	}
+	if v174, has := m["limit"]; has {
+		switch x175 := v174.(type) {
+		case nson.Null:
+		case nson.U32:
+			self.Limit = uint32(x175)
+		default:
+			return fmt.Errorf("field Limit: expected U32, got %T", v174)
+		}
+	} else {
+		self.Limit = uint32(16)
+	}
+	if v176, has := m["weight"]; has {
+		switch x177 := v176.(type) {
+		case nson.Null:
+		case nson.F32:
+			self.Weight = float32(x177)
+		default:
+			return fmt.Errorf("field Weight: expected F32, got %T", v176)
+		}
+	} else {
+		self.Weight = float32(0.5)
+	}
+	if v178, has := m["strict"]; has {
+		switch x179 := v178.(type) {
+		case nson.Null:
+		case nson.Bool:
+			self.Strict = bool(x179)
+		default:
+			return fmt.Errorf("field Strict: expected Bool, got %T", v178)
+		}
+	} else {
+		self.Strict = bool(true)
+	}
+	if v180, has := m["start"]; has {
+		switch x181 := v180.(type) {
+		case nson.Null:
+		case nson.Timestamp:
+			self.Start = time.UnixMilli(int64(x181))
+		default:
+			return fmt.Errorf("field Start: expected Timestamp for time.Time, got %T", v180)
+		}
+	} else {
+		self.Start = time.UnixMilli(1704164645006)
+	}
+	if v182, has := m["deadline"]; has {
+		switch x183 := v182.(type) {
+		case nson.Null:
+		case nson.U64:
+			self.Deadline = nson.Timestamp(x183)
+		case nson.Timestamp:
+			self.Deadline = nson.Timestamp(x183)
+		default:
+			return fmt.Errorf("field Deadline: expected U64 or Timestamp, got %T", v182)
+		}
+	} else {
+		self.Deadline = nson.Timestamp(1700000000000)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required fields: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// MarshalNSON 实现 nson.MapMarshaler
+func (self Reading) MarshalNSON() (nson.Map, error) {
+	m := make(nson.Map, 2)
+	m["value"] = nson.F32(self.Value)
+	m["At"] = nson.Timestamp(self.At.UnixMilli())
+	return m, nil
+}
+
+// AppendNSON 将编码后的 Map 追加到 buf，结果与 nson.EncodeMap 相同
+func (self Reading) AppendNSON(buf []byte) ([]byte, error) {
+	var start int
+	buf, start = nson.AppendMapStart(buf)
+	buf = append(buf, "\x06value"...)
+	buf = nson.AppendF32(buf, nson.F32(self.Value))
+	buf = append(buf, "\x03At"...)
+	buf = nson.AppendTimestamp(buf, nson.Timestamp(self.At.UnixMilli()))
+	return nson.AppendMapEnd(buf, start), nil
+}
+
+// UnmarshalNSON 实现 nson.MapUnmarshaler
+func (self *Reading) UnmarshalNSON(m nson.Map) error {
+	if v184, has := m["value"]; has {
+		switch x185 := v184.(type) {
+		case nson.Null:
+		case nson.F32:
+			self.Value = float32(x185)
+		default:
+			return fmt.Errorf("field Value: expected F32, got %T", v184)
+		}
+	}
+	if v186, has := m["At"]; has {
+		switch x187 := v186.(type) {
+		case nson.Null:
+		case nson.Timestamp:
+			self.At = time.UnixMilli(int64(x187))
+		default:
+			return fmt.Errorf("field At: expected Timestamp for time.Time, got %T", v186)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,347 @@
+package nson_test
+
+//go:generate go run ../cmd/nsongen -marshal generated_test.go
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	nson "github.com/danclive/nson-go"
+)
+
+type Level uint8
+
+type Labels map[string]string
+
+type GenBase struct {
+	Id      nson.Id   `nson:"_id"`
+	Created time.Time `nson:"created"`
+}
+
+//nsongen:marshal
+type Sensor struct {
+	GenBase
+	Point
+	Name     string             `nson:"name"`
+	Port     uint16             `nson:"port"`
+	Count    int                `nson:"count,omitempty"`
+	Total    int64              `nson:"total"`
+	Ratio    float64            `nson:"ratio"`
+	Enabled  bool               `nson:"enabled,omitempty"`
+	Level    Level              `nson:"level"`
+	Payload  []byte             `nson:"payload,omitempty"`
+	Tags     []string           `nson:"tags"`
+	Matrix   [2][]int8          `nson:"matrix"`
+	Labels   Labels             `nson:"labels,omitempty"`
+	Scores   map[string]float32 `nson:"scores"`
+	Note     *string            `nson:"note"`
+	Extra    nson.Value         `nson:"extra"`
+	Attrs    nson.Map           `nson:"attrs,omitempty"`
+	Raw      nson.Array         `nson:"raw,omitempty"`
+	Last     *Reading           `nson:"last"`
+	Readings []Reading          `nson:"readings"`
+	Seen     *time.Time         `nson:"seen,omitempty"`
+	Shade    Color              `nson:"shade,omitempty"`
+	Palette  []Color            `nson:"palette"`
+	Version  Version            `nson:"version"`
+	Sum      Checksum           `nson:"sum,omitempty"`
+	Ignored  string             `nson:"-"`
+	Rest     nson.Map           `nson:",remain"`
+	Meta     Meta               `nson:",inline"`
+	Loc      *Location          `nson:",inline"`
+	Untagged uint32
+	internal int
+}
+
+type Reading struct {
+	Value F32Value `nson:"value"`
+	At    time.Time
+}
+
+type F32Value = float32
+
+// Meta 内联到 Sensor，Name 被 Sensor.Name 遮蔽
+type Meta struct {
+	Owner string `nson:"owner"`
+	Name  string `nson:"name"`
+}
+
+type Location struct {
+	Lat float64 `nson:"lat"`
+	Lng float64 `nson:"lng"`
+}
+
+//nsongen:marshal
+type Settings struct {
+	Version int32            `nson:"version"`
+	Values  map[string]int64 `nson:",inline"`
+}
+
+type reflectSettings Settings
+
+//nsongen:marshal
+type Job struct {
+	Name     string         `nson:"name,required"`
+	Owner    string         `nson:"owner,required"`
+	Mode     Mode           `nson:"mode,default=auto"`
+	Retries  *int8          `nson:"retries,default=3"`
+	Limit    uint32         `nson:"limit,default=0x10"`
+	Weight   float32        `nson:"weight,default=0.5"`
+	Strict   bool           `nson:"strict,default=true"`
+	Start    time.Time      `nson:"start,default=2024-01-02T03:04:05.006Z"`
+	Deadline nson.Timestamp `nson:"deadline,default=1700000000000"`
+}
+
+type Mode string
+
+type reflectJob Job
+
+// reflectSensor 与 Sensor 字段相同但没有生成的方法，Marshal 会使用反射
+type reflectSensor Sensor
+
+func sampleSensor() Sensor {
+	note := "hi"
+	id, _ := nson.IdFromHex("0123456789abcdef01234567")
+
+	return Sensor{
+		GenBase:  GenBase{Id: id, Created: time.UnixMilli(1700000000000)},
+		Name:     "probe",
+		Port:     8080,
+		Total:    -5,
+		Ratio:    0.5,
+		Level:    3,
+		Payload:  []byte{1, 2},
+		Tags:     []string{"a", "b"},
+		Matrix:   [2][]int8{{1}, {2, 3}},
+		Labels:   Labels{"room": "lab"},
+		Scores:   map[string]float32{"x": 1.5},
+		Note:     &note,
+		Extra:    nson.I32(7),
+		Raw:      nson.Array{nson.String("r"), nson.Null{}},
+		Last:     &Reading{Value: 2.5, At: time.UnixMilli(1700000001000)},
+		Readings: []Reading{{Value: 1}, {Value: 2}},
+		Point:    Point{3, 4},
+		Palette:  []Color{Red, 0},
+		Version:  Version{2, 1},
+		Sum:      7,
+		Ignored:  "skip",
+		Rest:     nson.Map{"vendor": nson.String("acme"), "name": nson.String("shadowed")},
+		Meta:     Meta{Owner: "ops", Name: "hidden"},
+		Loc:      &Location{Lat: 1.5, Lng: 2.5},
+		Untagged: 9,
+		internal: 1,
+	}
+}
+
+func TestGeneratedMarshalMatchesReflection(t *testing.T) {
+	s := sampleSensor()
+
+	m, err := nson.Marshal(s)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	want, err := nson.Marshal(reflectSensor(s))
+	if err != nil {
+		t.Fatalf("Marshal via reflection failed: %v", err)
+	}
+
+	if !nson.Equal(m, want) {
+		t.Errorf("Generated Marshal mismatch:\n got: %v\nwant: %v", m, want)
+	}
+
+	if _, has := m["Ignored"]; has {
+		t.Error("Field tagged with - should be skipped")
+	}
+	if _, has := m["count"]; has {
+		t.Error("Empty omitempty field should be skipped")
+	}
+	if m["extra"] != nson.I32(7) || m["Untagged"] != nson.U32(9) || m["name"] != nson.String("probe") || m["vendor"] != nson.String("acme") ||
+		m["owner"] != nson.String("ops") || m["lat"] != nson.F64(1.5) {
+		t.Errorf("Unexpected values: %v", m)
+	}
+
+	buf, err := s.AppendNSON([]byte{0xff})
+	if err != nil {
+		t.Fatalf("AppendNSON failed: %v", err)
+	}
+	if buf[0] != 0xff {
+		t.Fatal("AppendNSON should append to buf")
+	}
+
+	decoded, err := nson.DecodeMap(bytes.NewBuffer(buf[1:]))
+	if err != nil {
+		t.Fatalf("DecodeMap failed: %v", err)
+	}
+	if !nson.Equal(decoded, m) {
+		t.Errorf("AppendNSON mismatch:\n got: %v\nwant: %v", decoded, m)
+	}
+}
+
+func TestGeneratedUnmarshal(t *testing.T) {
+	s := sampleSensor()
+	m, err := nson.Marshal(s)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var got Sensor
+	if err := nson.Unmarshal(m, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	var want reflectSensor
+	if err := nson.Unmarshal(m, &want); err != nil {
+		t.Fatalf("Unmarshal via reflection failed: %v", err)
+	}
+
+	remarshaled, _ := nson.Marshal(got)
+	expected, _ := nson.Marshal(want)
+	if !nson.Equal(remarshaled, expected) || !nson.Equal(remarshaled, m) {
+		t.Errorf("Round trip mismatch:\n got: %v\nwant: %v", remarshaled, m)
+	}
+
+	if got.Ignored != "" || *got.Note != "hi" || got.Last.At.UnixMilli() != 1700000001000 || len(got.Rest) != 1 || got.Rest["vendor"] != nson.String("acme") {
+		t.Errorf("Unexpected result: %+v", got)
+	}
+
+	m["note"] = nson.Null{}
+	if err := nson.Unmarshal(m, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if got.Note != nil {
+		t.Error("Null should set pointer to nil")
+	}
+}
+
+func TestGeneratedUnmarshalErrors(t *testing.T) {
+	tests := map[string]struct {
+		m    nson.Map
+		want string
+	}{
+		"type":   {nson.Map{"port": nson.I32(1)}, "field Port: expected U16, got nson.I32"},
+		"index":  {nson.Map{"tags": nson.Array{nson.String("a"), nson.I8(1)}}, "field Tags: index 1: expected String, got nson.I8"},
+		"key":    {nson.Map{"scores": nson.Map{"x": nson.F64(1)}}, "field Scores: key x: expected F32, got nson.F64"},
+		"nested": {nson.Map{"readings": nson.Array{nson.Map{"value": nson.String("x")}}}, "field Readings: index 0: field Value: expected F32, got nson.String"},
+		"length": {nson.Map{"matrix": nson.Array{}}, "field Matrix: array length mismatch: expected 2, got 0"},
+		"time":   {nson.Map{"created": nson.I64(1)}, "field Created: expected Timestamp for time.Time, got nson.I64"},
+		"text":   {nson.Map{"version": nson.I32(1)}, "field Version: expected String, got nson.I32"},
+		"binary": {nson.Map{"sum": nson.Binary{1}}, "field Sum: invalid checksum length 1"},
+		"custom": {nson.Map{"palette": nson.Array{nson.String("blue")}}, `field Palette: index 0: unknown color "blue"`},
+	}
+
+	for name, tt := range tests {
+		var s Sensor
+		err := nson.Unmarshal(tt.m, &s)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%v: expected error %q, got %v", name, tt.want, err)
+		}
+	}
+}
+
+func TestGeneratedUnmarshalWithOptions(t *testing.T) {
+	// 选项不为零值时不使用生成的 UnmarshalNSON
+	var s Sensor
+	opts := nson.UnmarshalOptions{NumericConversion: nson.NumericWiden}
+	if err := opts.Unmarshal(nson.Map{"port": nson.U8(5), "readings": nson.Array{nson.Map{"value": nson.I8(1)}}}, &s); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if s.Port != 5 || s.Readings[0].Value != 1 {
+		t.Errorf("Unexpected result: %+v", s)
+	}
+}
+
+func TestGeneratedInline(t *testing.T) {
+	s := sampleSensor()
+	s.Loc = nil
+
+	m, err := nson.Marshal(s)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want, _ := nson.Marshal(reflectSensor(s))
+	if !nson.Equal(m, want) {
+		t.Errorf("Generated Marshal mismatch:\n got: %v\nwant: %v", m, want)
+	}
+	if _, has := m["lat"]; has {
+		t.Error("Fields of nil inline pointer should be skipped")
+	}
+
+	m["lng"] = nson.F64(3)
+	var got Sensor
+	if err := nson.Unmarshal(m, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if got.Loc == nil || got.Loc.Lng != 3 || got.Meta.Owner != "ops" || got.Meta.Name != "" {
+		t.Errorf("Unexpected result: %+v %+v", got.Loc, got.Meta)
+	}
+
+	settings := Settings{Version: 2, Values: map[string]int64{"a": 1, "version": 9}}
+	m, err = nson.Marshal(settings)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want, _ = nson.Marshal(reflectSettings(settings))
+	if !nson.Equal(m, want) || !nson.Equal(m, nson.Map{"version": nson.I32(2), "a": nson.I64(1)}) {
+		t.Errorf("Generated Marshal mismatch:\n got: %v\nwant: %v", m, want)
+	}
+
+	var gs Settings
+	if err := nson.Unmarshal(m, &gs); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if gs.Version != 2 || len(gs.Values) != 1 || gs.Values["a"] != 1 {
+		t.Errorf("Unexpected result: %+v", gs)
+	}
+
+	err = nson.Unmarshal(nson.Map{"b": nson.String("x")}, &gs)
+	if err == nil || !strings.Contains(err.Error(), "field Values: key b: expected I64 or Timestamp, got nson.String") {
+		t.Errorf("Expected key error, got %v", err)
+	}
+	var rs reflectSettings
+	err = nson.Unmarshal(nson.Map{"b": nson.String("x")}, &rs)
+	if err == nil || !strings.Contains(err.Error(), "field Values: key b: expected I64 or Timestamp, got nson.String") {
+		t.Errorf("Expected key error via reflection, got %v", err)
+	}
+}
+
+func TestGeneratedRequiredAndDefault(t *testing.T) {
+	m := nson.Map{"name": nson.String("a"), "owner": nson.String("b")}
+
+	var got Job
+	if err := nson.Unmarshal(m, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	var want reflectJob
+	if err := nson.Unmarshal(m, &want); err != nil {
+		t.Fatalf("Unmarshal via reflection failed: %v", err)
+	}
+
+	if got.Mode != "auto" || *got.Retries != 3 || got.Limit != 16 || got.Weight != 0.5 || !got.Strict ||
+		got.Start.UnixMilli() != 1704164645006 || got.Deadline != 1700000000000 {
+		t.Errorf("Unexpected result: %+v", got)
+	}
+
+	gm, _ := nson.Marshal(got)
+	wm, _ := nson.Marshal(want)
+	if !nson.Equal(gm, wm) {
+		t.Errorf("Generated mismatch:\n got: %v\nwant: %v", gm, wm)
+	}
+
+	// 每次写入新的指针
+	var other Job
+	nson.Unmarshal(m, &other)
+	if other.Retries == got.Retries {
+		t.Error("Default pointer should not be shared")
+	}
+
+	for _, target := range []any{&Job{}, &reflectJob{}} {
+		err := nson.Unmarshal(nson.Map{"mode": nson.String("x")}, target)
+		if err == nil || err.Error() != "missing required fields: name, owner" {
+			t.Errorf("%T: expected missing fields error, got %v", target, err)
+		}
+	}
+}
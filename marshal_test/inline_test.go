@@ -0,0 +1,165 @@
+package nson_test
+
+import (
+	"strings"
+	"testing"
+
+	nson "github.com/danclive/nson-go"
+)
+
+type Audit struct {
+	By   string `nson:"by"`
+	Note string `nson:"note"`
+}
+
+type Document struct {
+	*Audit `nson:",inline"`
+	Title  string            `nson:"title"`
+	Note   string            `nson:"note"`
+	Owner  Address           `nson:",inline"`
+	Extra  map[string]string `nson:",inline"`
+}
+
+func TestInlineFields(t *testing.T) {
+	d := Document{
+		Audit: &Audit{By: "bob", Note: "hidden"},
+		Title: "t",
+		Note:  "n",
+		Owner: Address{City: "X"},
+		Extra: map[string]string{"lang": "en", "title": "ignored"},
+	}
+
+	m, err := nson.Marshal(d)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	want := nson.Map{
+		"by":       nson.String("bob"),
+		"title":    nson.String("t"),
+		"note":     nson.String("n"),
+		"street":   nson.String(""),
+		"city":     nson.String("X"),
+		"zip_code": nson.String(""),
+		"lang":     nson.String("en"),
+	}
+	if !nson.Equal(m, want) {
+		t.Errorf("Marshal mismatch:\n got: %v\nwant: %v", m, want)
+	}
+
+	var got Document
+	if err := nson.Unmarshal(m, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if got.Audit == nil || got.By != "bob" || got.Audit.Note != "" || got.Note != "n" || got.Owner.City != "X" ||
+		len(got.Extra) != 1 || got.Extra["lang"] != "en" {
+		t.Errorf("Unexpected result: %+v %+v", got, got.Audit)
+	}
+
+	// nil 指针中的字段不编码，解码时没有对应的键则不分配
+	d.Audit = nil
+	m, err = nson.Marshal(d)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if _, has := m["by"]; has {
+		t.Error("Fields of nil inline pointer should be skipped")
+	}
+
+	got = Document{}
+	if err := nson.Unmarshal(m, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if got.Audit != nil {
+		t.Error("Inline pointer should stay nil without its keys")
+	}
+
+	err = nson.Unmarshal(nson.Map{"lang": nson.I32(1)}, &got)
+	if err == nil || !strings.Contains(err.Error(), "field Extra: key lang: expected String, got nson.I32") {
+		t.Errorf("Expected inline map error, got %v", err)
+	}
+}
+
+func TestInlineShadowing(t *testing.T) {
+	type inner struct {
+		B string `nson:"b"`
+	}
+
+	// 同一层级中 tag 指定了名称的字段优先
+	var tagged struct {
+		X struct {
+			B string `nson:"B"`
+		} `nson:",inline"`
+		Y struct {
+			B string
+		} `nson:",inline"`
+	}
+	tagged.X.B = "x"
+	tagged.Y.B = "y"
+	m, err := nson.Marshal(tagged)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !nson.Equal(m, nson.Map{"B": nson.String("x")}) {
+		t.Errorf("Unexpected result: %v", m)
+	}
+
+	var ambiguous struct {
+		X inner `nson:",inline"`
+		Y inner `nson:",inline"`
+	}
+	if _, err := nson.Marshal(ambiguous); err == nil || !strings.Contains(err.Error(), `duplicate key "b" in fields B and B`) {
+		t.Errorf("Expected duplicate key error, got %v", err)
+	}
+
+	var sameLevel struct {
+		A string `nson:"x"`
+		B string `nson:"x"`
+	}
+	if err := nson.Unmarshal(nson.Map{}, &sameLevel); err == nil || !strings.Contains(err.Error(), `duplicate key "x" in fields A and B`) {
+		t.Errorf("Expected duplicate key error, got %v", err)
+	}
+}
+
+type cyclic struct {
+	Name string  `nson:"name"`
+	Next *cyclic `nson:",inline"`
+}
+
+func TestInlineInvalid(t *testing.T) {
+	if _, err := nson.Marshal(cyclic{}); err == nil || !strings.Contains(err.Error(), "inline cycle") {
+		t.Errorf("Expected cycle error, got %v", err)
+	}
+
+	var bad struct {
+		N int `nson:",inline"`
+	}
+	if _, err := nson.Marshal(bad); err == nil || !strings.Contains(err.Error(), "field N: inline requires") {
+		t.Errorf("Expected inline type error, got %v", err)
+	}
+
+	var twice struct {
+		A map[string]int `nson:",inline"`
+		B nson.Map       `nson:",remain"`
+	}
+	if _, err := nson.Marshal(twice); err == nil || !strings.Contains(err.Error(), "duplicate remain field") {
+		t.Errorf("Expected duplicate remain error, got %v", err)
+	}
+}
+
+func TestInlineSchema(t *testing.T) {
+	schema, err := nson.SchemaFor[Document]()
+	if err != nil {
+		t.Fatalf("SchemaFor failed: %v", err)
+	}
+
+	if schema.Fields["by"] == nil || schema.Fields["by"].Required {
+		t.Error("Fields of inline pointer should not be required")
+	}
+	if !schema.Fields["city"].Required || !schema.Fields["title"].Required {
+		t.Error("Inline struct fields should be required")
+	}
+	if schema.Values == nil || len(schema.Values.Types) != 1 || schema.Values.Types[0] != nson.DataTypeSTRING {
+		t.Errorf("Expected String values, got %+v", schema.Values)
+	}
+}
@@ -0,0 +1,139 @@
+package nson_test
+
+import (
+	"strings"
+	"testing"
+
+	nson "github.com/danclive/nson-go"
+)
+
+type NamedServer struct {
+	HTTPServerID string
+	MaxConn2     int32
+	Host_Name    string
+	Port         uint16 `nson:"PORT"`
+}
+
+func TestNamingStrategies(t *testing.T) {
+	tests := []struct {
+		naming *nson.Naming
+		names  []string
+	}{
+		{nil, []string{"HTTPServerID", "MaxConn2", "Host_Name"}},
+		{nson.SnakeCase, []string{"http_server_id", "max_conn2", "host_name"}},
+		{nson.KebabCase, []string{"http-server-id", "max-conn2", "host-name"}},
+		{nson.CamelCase, []string{"httpServerId", "maxConn2", "hostName"}},
+		{nson.LowerCase, []string{"httpserverid", "maxconn2", "host_name"}},
+	}
+
+	for _, tt := range tests {
+		m, err := nson.MarshalOptions{Naming: tt.naming}.Marshal(NamedServer{})
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+
+		for _, name := range tt.names {
+			if _, ok := m[name]; !ok {
+				t.Errorf("Expected key %q in %v", name, m)
+			}
+		}
+
+		// tag 中的名称不受影响
+		if _, ok := m["PORT"]; !ok || len(m) != 4 {
+			t.Errorf("Unexpected keys: %v", m)
+		}
+	}
+}
+
+func TestNamingRoundTrip(t *testing.T) {
+	in := NamedServer{HTTPServerID: "s1", MaxConn2: 8, Host_Name: "h", Port: 80}
+
+	m, err := nson.MarshalOptions{Naming: nson.SnakeCase}.Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var out NamedServer
+	if err := (nson.UnmarshalOptions{Naming: nson.SnakeCase}).Unmarshal(m, &out); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if out != in {
+		t.Errorf("Expected %+v, got %+v", in, out)
+	}
+
+	// 默认选项使用字段名，键不匹配
+	out = NamedServer{}
+	if err := nson.Unmarshal(m, &out); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if out.HTTPServerID != "" || out.Port != 80 {
+		t.Errorf("Unexpected result: %+v", out)
+	}
+}
+
+func TestNamingFunc(t *testing.T) {
+	upper := nson.NamingFunc(strings.ToUpper)
+
+	m, err := nson.MarshalOptions{Naming: upper}.Marshal(NamedServer{MaxConn2: 3})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if m["MAXCONN2"] != nson.I32(3) {
+		t.Errorf("Unexpected result: %v", m)
+	}
+
+	// 与 tag 中的名称相同时 tag 优先
+	type Tagged struct {
+		Port int32
+		P    int32 `nson:"PORT"`
+	}
+	m, err = nson.MarshalOptions{Naming: upper}.Marshal(Tagged{Port: 1, P: 2})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if len(m) != 1 || m["PORT"] != nson.I32(2) {
+		t.Errorf("Unexpected result: %v", m)
+	}
+
+	// 生成的名称相同
+	type Conflict struct {
+		HostName  string
+		Host_Name string
+	}
+	_, err = nson.MarshalOptions{Naming: nson.SnakeCase}.Marshal(Conflict{})
+	if err == nil || !strings.Contains(err.Error(), `duplicate key "host_name"`) {
+		t.Errorf("Expected duplicate key error, got %v", err)
+	}
+	if _, err := nson.Marshal(Conflict{}); err != nil {
+		t.Errorf("Marshal failed: %v", err)
+	}
+}
+
+func TestCaseInsensitive(t *testing.T) {
+	opts := nson.UnmarshalOptions{CaseInsensitive: true, DisallowUnknownFields: true}
+
+	var out NamedServer
+	m := nson.Map{"httpserverid": nson.String("a"), "port": nson.U16(1), "Host_Name": nson.String("h")}
+	if err := opts.Unmarshal(m, &out); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if out.HTTPServerID != "a" || out.Port != 1 || out.Host_Name != "h" {
+		t.Errorf("Unexpected result: %+v", out)
+	}
+
+	// 完全相同的键优先，否则使用排序最小的键
+	out = NamedServer{}
+	m = nson.Map{"PORT": nson.U16(1), "port": nson.U16(2), "MAXCONN2": nson.I32(3), "maxconn2": nson.I32(4)}
+	if err := opts.Unmarshal(m, &out); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if out.Port != 1 || out.MaxConn2 != 3 {
+		t.Errorf("Unexpected result: %+v", out)
+	}
+
+	// 区分大小写时为未知键
+	err := nson.UnmarshalOptions{DisallowUnknownFields: true}.Unmarshal(nson.Map{"port": nson.U16(1)}, &out)
+	if err == nil || !strings.Contains(err.Error(), "unknown fields: port") {
+		t.Errorf("Expected unknown field error, got %v", err)
+	}
+}
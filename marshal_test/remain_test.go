@@ -0,0 +1,120 @@
+package nson_test
+
+import (
+	"strings"
+	"testing"
+
+	nson "github.com/danclive/nson-go"
+)
+
+type Profile struct {
+	Name string   `nson:"name"`
+	Tags []string `nson:"tags,omitempty"`
+	Rest nson.Map `nson:",remain"`
+}
+
+func TestDisallowUnknownFields(t *testing.T) {
+	m := nson.Map{
+		"name":     nson.String("a"),
+		"age":      nson.I32(1),
+		"nickname": nson.String("x"),
+	}
+
+	var p Person
+	if err := nson.Unmarshal(m, &p); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	opts := nson.UnmarshalOptions{DisallowUnknownFields: true}
+	err := opts.Unmarshal(m, &p)
+	if err == nil || err.Error() != "unknown fields: nickname" {
+		t.Errorf("Expected unknown field error, got %v", err)
+	}
+
+	m["phone"] = nson.String("y")
+	err = opts.Unmarshal(m, &p)
+	if err == nil || !strings.Contains(err.Error(), "unknown fields: nickname, phone") {
+		t.Errorf("Expected sorted unknown fields, got %v", err)
+	}
+
+	// 嵌套的结构体同样检查
+	var w struct {
+		Home Address `nson:"home"`
+	}
+	err = opts.Unmarshal(nson.Map{"home": nson.Map{"city": nson.String("X"), "zip": nson.String("1")}}, &w)
+	if err == nil || !strings.Contains(err.Error(), "field Home: unknown fields: zip") {
+		t.Errorf("Expected nested unknown field error, got %v", err)
+	}
+
+	// remain 字段收集未知的键，不返回错误
+	var pr Profile
+	if err := opts.Unmarshal(nson.Map{"name": nson.String("a"), "x": nson.I8(1)}, &pr); err != nil {
+		t.Errorf("Unmarshal with remain failed: %v", err)
+	}
+}
+
+func TestRemainField(t *testing.T) {
+	m := nson.Map{
+		"name": nson.String("a"),
+		"tags": nson.Array{nson.String("t")},
+		"x":    nson.I8(1),
+		"y":    nson.Array{nson.Null{}},
+	}
+
+	var p Profile
+	if err := nson.Unmarshal(m, &p); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if !nson.Equal(p.Rest, nson.Map{"x": nson.I8(1), "y": nson.Array{nson.Null{}}}) {
+		t.Errorf("Unexpected remain: %v", p.Rest)
+	}
+
+	out, err := nson.Marshal(p)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	if !nson.Equal(out, m) {
+		t.Errorf("Round trip mismatch:\n got: %v\nwant: %v", out, m)
+	}
+
+	// 与字段同名的键被忽略，即使字段因 omitempty 没有编码
+	p.Tags = nil
+	p.Rest = nson.Map{"name": nson.String("b"), "tags": nson.I32(1), "z": nil}
+	out, err = nson.Marshal(p)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !nson.Equal(out, nson.Map{"name": nson.String("a"), "z": nson.Null{}}) {
+		t.Errorf("Unexpected result: %v", out)
+	}
+
+	// 没有未知的键时保持原值
+	if err := nson.Unmarshal(nson.Map{"name": nson.String("c")}, &p); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if p.Name != "c" || len(p.Rest) != 3 {
+		t.Errorf("Unexpected result: %+v", p)
+	}
+}
+
+func TestRemainFieldInvalid(t *testing.T) {
+	var bad struct {
+		Rest map[string]any `nson:",remain"`
+	}
+	if _, err := nson.Marshal(bad); err == nil || !strings.Contains(err.Error(), "remain requires nson.Map") {
+		t.Errorf("Expected remain type error, got %v", err)
+	}
+	if err := nson.Unmarshal(nson.Map{}, &bad); err == nil {
+		t.Error("Expected remain type error")
+	}
+
+	var dup struct {
+		A nson.Map `nson:",remain"`
+		B nson.Map `nson:",remain"`
+	}
+	if _, err := nson.Marshal(dup); err == nil || !strings.Contains(err.Error(), "duplicate remain field") {
+		t.Errorf("Expected duplicate remain error, got %v", err)
+	}
+}
@@ -0,0 +1,194 @@
+package nson_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	nson "github.com/danclive/nson-go"
+)
+
+type Node struct {
+	Name     string          `nson:"name"`
+	Children []*Node         `nson:"children,omitempty"`
+	Created  time.Time       `nson:"created"`
+	Owner    nson.Id         `nson:"owner"`
+	Raw      []byte          `nson:"raw,omitempty"`
+	Scores   map[string]int8 `nson:"scores"`
+	Point    [2]float64      `nson:"point"`
+}
+
+func TestSchemaFor(t *testing.T) {
+	schema, err := nson.SchemaFor[Employee]()
+	if err != nil {
+		t.Fatalf("SchemaFor failed: %v", err)
+	}
+
+	if !slices.Equal(schema.Types, []nson.DataType{nson.DataTypeMAP}) {
+		t.Errorf("Unexpected root types: %v", schema.Types)
+	}
+
+	tests := []struct {
+		field    string
+		types    []nson.DataType
+		required bool
+	}{
+		{"name", []nson.DataType{nson.DataTypeSTRING}, true},
+		{"age", []nson.DataType{nson.DataTypeI32}, true},
+		{"email", []nson.DataType{nson.DataTypeSTRING}, false},
+		{"employee_id", []nson.DataType{nson.DataTypeU64}, true},
+		{"address", []nson.DataType{nson.DataTypeMAP, nson.DataTypeNULL}, false},
+		{"tags", []nson.DataType{nson.DataTypeARRAY}, true},
+		{"metadata", []nson.DataType{nson.DataTypeMAP}, false},
+	}
+
+	for _, tt := range tests {
+		fs, has := schema.Fields[tt.field]
+		if !has {
+			t.Errorf("Missing field %v", tt.field)
+			continue
+		}
+		if !slices.Equal(fs.Types, tt.types) || fs.Required != tt.required {
+			t.Errorf("Field %v: got types %v required %v", tt.field, fs.Types, fs.Required)
+		}
+	}
+
+	if _, has := schema.Fields["Person"]; has {
+		t.Error("Embedded struct should be flattened")
+	}
+	if zip := schema.Fields["address"].Fields["zip_code"]; zip == nil || !zip.Required {
+		t.Errorf("Unexpected nested field: %v", zip)
+	}
+
+	m, err := nson.Marshal(Employee{Person: Person{Name: "Bob"}, Address: &Address{City: "X"}})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if errs := schema.Validate(m); errs != nil {
+		t.Errorf("Marshal output does not match schema: %v", errs)
+	}
+
+	delete(m, "salary")
+	m["age"] = nson.I64(30)
+	if errs := schema.Validate(m); len(errs) != 2 {
+		t.Errorf("Expected 2 errors, got %v", errs)
+	}
+}
+
+func TestSchemaOfRecursiveAndSpecialTypes(t *testing.T) {
+	schema, err := nson.SchemaOf(reflect.TypeOf(Node{}))
+	if err != nil {
+		t.Fatalf("SchemaOf failed: %v", err)
+	}
+
+	children := schema.Fields["children"]
+	if children.Items == nil || children.Items.Fields != nil {
+		t.Errorf("Expected recursive reference to stop at Map, got %v", children.Map())
+	}
+
+	want := map[string]nson.DataType{
+		"created": nson.DataTypeTIMESTAMP,
+		"owner":   nson.DataTypeID,
+		"raw":     nson.DataTypeBINARY,
+	}
+	for name, dt := range want {
+		if types := schema.Fields[name].Types; len(types) != 1 || types[0] != dt {
+			t.Errorf("Field %v: expected %v, got %v", name, dt, types)
+		}
+	}
+
+	if v := schema.Fields["scores"].Values; v == nil || v.Types[0] != nson.DataTypeI8 {
+		t.Errorf("Unexpected map value schema: %v", schema.Fields["scores"].Map())
+	}
+	if p := schema.Fields["point"]; *p.MinItems != 2 || *p.MaxItems != 2 {
+		t.Errorf("Unexpected fixed array schema: %v", p.Map())
+	}
+
+	m, err := nson.Marshal(Node{Name: "root", Children: []*Node{{Name: "leaf"}}, Scores: map[string]int8{"a": 1}})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if errs := schema.Validate(m); errs != nil {
+		t.Errorf("Marshal output does not match schema: %v", errs)
+	}
+
+	if _, err := nson.SchemaFor[map[int]string](); err == nil {
+		t.Error("Expected error for non-string map keys")
+	}
+	if _, err := nson.SchemaFor[chan int](); err == nil {
+		t.Error("Expected error for unsupported type")
+	}
+}
+
+func TestSchemaJSONExport(t *testing.T) {
+	schema, err := nson.SchemaFor[Employee]()
+	if err != nil {
+		t.Fatalf("SchemaFor failed: %v", err)
+	}
+
+	data, err := schema.JSONSchema()
+	if err != nil {
+		t.Fatalf("JSONSchema failed: %v", err)
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+
+	if doc["$schema"] != nson.JSONSchemaDraft || doc["type"] != "object" {
+		t.Errorf("Unexpected root: %v", doc)
+	}
+
+	props := doc["properties"].(map[string]any)
+
+	age := props["age"].(map[string]any)
+	if age["type"] != "integer" || age["minimum"] != float64(-2147483648) || age["x-nson-type"] != "I32" {
+		t.Errorf("Unexpected age: %v", age)
+	}
+
+	address := props["address"].(map[string]any)
+	anyOf := address["anyOf"].([]any)
+	if len(anyOf) != 2 || anyOf[1].(map[string]any)["type"] != "null" {
+		t.Errorf("Unexpected address: %v", address)
+	}
+
+	required := doc["required"].([]any)
+	if slices.Contains(required, any("email")) || !slices.Contains(required, any("name")) {
+		t.Errorf("Unexpected required: %v", required)
+	}
+
+	// NSON 形式可以编码传输后还原
+	parsed, err := nson.SchemaFromMap(schema.Map())
+	if err != nil {
+		t.Fatalf("SchemaFromMap failed: %v", err)
+	}
+	if !nson.Equal(parsed.Map(), schema.Map()) {
+		t.Error("NSON round trip mismatch")
+	}
+}
+
+func TestSchemaJSONRoundTrip(t *testing.T) {
+	for _, typ := range []reflect.Type{reflect.TypeFor[Employee](), reflect.TypeFor[Node]()} {
+		schema, err := nson.SchemaOf(typ)
+		if err != nil {
+			t.Fatalf("SchemaOf failed: %v", err)
+		}
+
+		data, err := schema.JSONSchema()
+		if err != nil {
+			t.Fatalf("JSONSchema failed: %v", err)
+		}
+
+		parsed, err := nson.SchemaFromJSON(data)
+		if err != nil {
+			t.Fatalf("SchemaFromJSON failed: %v", err)
+		}
+
+		if !nson.Equal(parsed.Map(), schema.Map()) {
+			t.Errorf("%v: JSON round trip mismatch:\n got: %v\nwant: %v", typ, parsed.Map(), schema.Map())
+		}
+	}
+}
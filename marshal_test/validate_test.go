@@ -0,0 +1,176 @@
+package nson_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	nson "github.com/danclive/nson-go"
+)
+
+type DeviceConfig struct {
+	Name    string            `nson:"name" validate:"min=1,max=8"`
+	Port    uint16            `nson:"port" validate:"min=1,max=65535"`
+	Mode    string            `nson:"mode" validate:"oneof=auto manual"`
+	Serial  string            `nson:"serial" validate:"regex=^[A-Z]{2,3}-[0-9]+$"`
+	Ratio   *float64          `nson:"ratio" validate:"min=0,max=1"`
+	Level   int8              `nson:"level" validate:"oneof=1 2 3"`
+	Key     []byte            `nson:"key" validate:"len=4"`
+	Hosts   []string          `nson:"hosts" validate:"max=2"`
+	Version Version           `nson:"version" validate:"oneof=1.0 2.0"`
+	Nodes   []DeviceNode      `nson:"nodes"`
+	Groups  map[string]Device `nson:"groups,omitempty"`
+}
+
+type DeviceNode struct {
+	Id int32 `nson:"id" validate:"min=1"`
+}
+
+type Device struct {
+	Node *DeviceNode `nson:"node"`
+}
+
+func validConfig() DeviceConfig {
+	return DeviceConfig{
+		Name:    "dev",
+		Port:    80,
+		Mode:    "auto",
+		Serial:  "AB-12",
+		Level:   2,
+		Key:     []byte{1, 2, 3, 4},
+		Version: Version{1, 0},
+		Nodes:   []DeviceNode{{Id: 1}},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	c := validConfig()
+	if err := nson.Validate(&c); err != nil {
+		t.Fatalf("Expected valid config, got %v", err)
+	}
+
+	ratio := 1.5
+	c.Name = ""
+	c.Port = 0
+	c.Mode = "off"
+	c.Serial = "x-1"
+	c.Ratio = &ratio
+	c.Level = 4
+	c.Key = []byte{1}
+	c.Hosts = []string{"a", "b", "c"}
+	c.Version = Version{3, 0}
+	c.Nodes = append(c.Nodes, DeviceNode{Id: 0})
+	c.Groups = map[string]Device{"g": {Node: &DeviceNode{}}, "h": {}}
+
+	err := nson.Validate(c)
+
+	var errs nson.ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected ValidationErrors, got %v", err)
+	}
+
+	want := []string{
+		"name: expected length at least 1, got 0",
+		"port: value U16(0) is less than minimum U16(1)",
+		"mode: value String(off) is not one of Array[String(auto), String(manual)]",
+		`serial: value "x-1" does not match pattern`,
+		"(1.5) is greater than maximum",
+		"level: value I8(4) is not one of",
+		"key: expected length at least 4, got 1",
+		"hosts: expected at most 2 items, got 3",
+		"version: value String(3.0) is not one of",
+		"nodes.1.id: value I32(0) is less than minimum I32(1)",
+		"groups.g.node.id: value I32(0) is less than minimum I32(1)",
+	}
+
+	if len(errs) != len(want) {
+		t.Fatalf("Expected %d errors, got %d: %v", len(want), len(errs), err)
+	}
+	for i, w := range want {
+		if !strings.Contains(errs[i].Error(), w) {
+			t.Errorf("Error %d: expected %q, got %q", i, w, errs[i].Error())
+		}
+	}
+}
+
+func TestUnmarshalValidate(t *testing.T) {
+	c := validConfig()
+	m, err := nson.Marshal(c)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	m["port"] = nson.U16(0)
+
+	var got DeviceConfig
+	if err := nson.Unmarshal(m, &got); err != nil {
+		t.Fatalf("Unmarshal without validation failed: %v", err)
+	}
+
+	opts := nson.UnmarshalOptions{Validate: true}
+	err = opts.Unmarshal(m, &got)
+	if err == nil || err.Error() != "port: value U16(0) is less than minimum U16(1)" {
+		t.Errorf("Expected validation error, got %v", err)
+	}
+
+	var nodes []DeviceNode
+	err = opts.UnmarshalValue(nson.Array{nson.Map{"id": nson.I32(-1)}}, &nodes)
+	if err == nil || !strings.HasPrefix(err.Error(), "0.id: ") {
+		t.Errorf("Expected validation error with index path, got %v", err)
+	}
+}
+
+func TestValidateInvalidTag(t *testing.T) {
+	tests := map[string]struct {
+		v    any
+		want string
+	}{
+		"rule": {struct {
+			N int `validate:"positive"`
+		}{}, `field N: invalid validate tag: rule "positive": unknown rule`},
+		"value": {struct {
+			N uint8 `validate:"max=300"`
+		}{}, `rule "max=300": String(300) overflows U8`},
+		"kind": {struct {
+			B []string `validate:"oneof=a"`
+		}{}, "not supported for Array"},
+		"bool": {struct {
+			B bool `validate:"min=1"`
+		}{}, "unsupported type bool"},
+		"regex": {struct {
+			S string `validate:"regex=("`
+		}{}, "error parsing regexp"},
+		"type": {struct {
+			C Color `validate:"oneof=red"`
+		}{}, "unsupported type nson_test.Color"},
+	}
+
+	for name, tt := range tests {
+		err := nson.Validate(tt.v)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%v: expected error %q, got %v", name, tt.want, err)
+		}
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	schema, err := nson.SchemaFor[DeviceConfig]()
+	if err != nil {
+		t.Fatalf("SchemaFor failed: %v", err)
+	}
+
+	c := validConfig()
+	c.Mode = "off"
+	c.Nodes[0].Id = 0
+	m, _ := nson.Marshal(c)
+
+	errs := schema.Validate(m)
+	if len(errs) != 2 || errs[0].Path != "mode" || errs[1].Path != "nodes.0.id" {
+		t.Errorf("Unexpected schema errors: %v", errs)
+	}
+
+	c = validConfig()
+	m, _ = nson.Marshal(c)
+	if errs := schema.Validate(m); len(errs) != 0 {
+		t.Errorf("Expected valid, got %v", errs)
+	}
+}
@@ -0,0 +1,117 @@
+package nson_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	nson "github.com/danclive/nson-go"
+)
+
+func TestMarshalValue(t *testing.T) {
+	tests := []struct {
+		in   any
+		want nson.Value
+	}{
+		{nil, nson.Null{}},
+		{int16(-3), nson.I16(-3)},
+		{"hi", nson.String("hi")},
+		{time.UnixMilli(5), nson.Timestamp(5)},
+		{[]uint16{1, 2}, nson.Array{nson.U16(1), nson.U16(2)}},
+		{map[string]Address{"home": {City: "X"}}, nson.Map{"home": nson.Map{"street": nson.String(""), "city": nson.String("X"), "zip_code": nson.String("")}}},
+		{[]Color{Red}, nson.Array{nson.String("red")}},
+		{(*int32)(nil), nson.Null{}},
+	}
+
+	for _, tt := range tests {
+		got, err := nson.MarshalValue(tt.in)
+		if err != nil {
+			t.Errorf("MarshalValue(%#v) failed: %v", tt.in, err)
+			continue
+		}
+		if !nson.Equal(got, tt.want) {
+			t.Errorf("MarshalValue(%#v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	if _, err := nson.MarshalValue(make(chan int)); err == nil {
+		t.Error("Expected error for unsupported type")
+	}
+}
+
+func TestUnmarshalValue(t *testing.T) {
+	var people []Person
+	err := nson.UnmarshalValue(nson.Array{nson.Map{"name": nson.String("a"), "age": nson.I32(3)}}, &people)
+	if err != nil {
+		t.Fatalf("UnmarshalValue failed: %v", err)
+	}
+	if len(people) != 1 || people[0].Name != "a" || people[0].Age != 3 {
+		t.Errorf("Unexpected result: %+v", people)
+	}
+
+	var n uint32
+	if err := nson.UnmarshalValue(nson.U32(7), &n); err != nil || n != 7 {
+		t.Errorf("Expected 7, got %v (%v)", n, err)
+	}
+
+	var p *string
+	if err := nson.UnmarshalValue(nson.Null{}, &p); err != nil || p != nil {
+		t.Errorf("Expected nil pointer, got %v (%v)", p, err)
+	}
+
+	if err := nson.UnmarshalValue(nson.U32(7), n); err == nil {
+		t.Error("Expected error for non-pointer")
+	}
+
+	err = nson.UnmarshalValue(nson.Array{nson.I8(1)}, &[]string{})
+	if err == nil || !strings.Contains(err.Error(), "index 0: expected String, got nson.I8") {
+		t.Errorf("Expected type error, got %v", err)
+	}
+}
+
+func TestMarshalBytes(t *testing.T) {
+	in := map[string][]int64{"a": {1, 2}}
+
+	data, err := nson.MarshalBytes(in)
+	if err != nil {
+		t.Fatalf("MarshalBytes failed: %v", err)
+	}
+
+	val, _ := nson.MarshalValue(in)
+	buf := new(bytes.Buffer)
+	nson.EncodeValue(buf, val)
+	if !bytes.Equal(data, buf.Bytes()) {
+		t.Errorf("MarshalBytes should match EncodeValue")
+	}
+
+	var out map[string][]int64
+	if err := nson.UnmarshalBytes(data, &out); err != nil {
+		t.Fatalf("UnmarshalBytes failed: %v", err)
+	}
+	if len(out["a"]) != 2 || out["a"][1] != 2 {
+		t.Errorf("Unexpected result: %v", out)
+	}
+
+	if err := nson.UnmarshalBytes(append(data, 0), &out); err == nil {
+		t.Error("Expected error for trailing bytes")
+	}
+
+	// 生成了 AppendNSON 的结构体直接追加编码
+	s := sampleSensor()
+	data, err = nson.MarshalBytes(&s)
+	if err != nil {
+		t.Fatalf("MarshalBytes failed: %v", err)
+	}
+	if nson.DataType(data[0]) != nson.DataTypeMAP {
+		t.Fatalf("Expected Map tag, got %x", data[0])
+	}
+
+	var got Sensor
+	if err := nson.UnmarshalBytes(data, &got); err != nil {
+		t.Fatalf("UnmarshalBytes failed: %v", err)
+	}
+	if got.Name != s.Name || got.Point != s.Point {
+		t.Errorf("Unexpected result: %+v", got)
+	}
+}
@@ -0,0 +1,162 @@
+package nson_test
+
+import (
+	"strings"
+	"testing"
+
+	nson "github.com/danclive/nson-go"
+)
+
+type Counter struct {
+	Count   int      `nson:"count,type=i64"`
+	Port    int      `nson:"port,type=u16"`
+	Created int64    `nson:"created,type=timestamp"`
+	Total   uint64   `nson:"total,type=string"`
+	Ratio   float32  `nson:"ratio,type=string"`
+	Limit   *int32   `nson:"limit,type=i8"`
+	Weight  float64  `nson:"weight,type=i32" validate:"max=100"`
+	Hits    *uint    `nson:"hits,omitempty,type=u64"`
+	Score   int16    `nson:"score,type=f32,default=3"`
+	Extra   []string `nson:"extra,omitempty"`
+}
+
+func TestWireType(t *testing.T) {
+	limit := int32(-5)
+	in := Counter{
+		Count:   1 << 40,
+		Port:    8080,
+		Created: 1700000000000,
+		Total:   18446744073709551615,
+		Ratio:   0.1,
+		Limit:   &limit,
+		Weight:  42,
+		Score:   7,
+	}
+
+	m, err := nson.Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	want := nson.Map{
+		"count":   nson.I64(1 << 40),
+		"port":    nson.U16(8080),
+		"created": nson.Timestamp(1700000000000),
+		"total":   nson.String("18446744073709551615"),
+		"ratio":   nson.String("0.1"),
+		"limit":   nson.I8(-5),
+		"weight":  nson.I32(42),
+		"score":   nson.F32(7),
+	}
+	if !nson.Equal(m, want) {
+		t.Errorf("Expected %v, got %v", want, m)
+	}
+
+	var out Counter
+	if err := nson.Unmarshal(m, &out); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if out.Count != in.Count || out.Port != in.Port || out.Created != in.Created || out.Total != in.Total ||
+		out.Ratio != in.Ratio || *out.Limit != limit || out.Weight != in.Weight || out.Hits != nil || out.Score != in.Score {
+		t.Errorf("Expected %+v, got %+v", in, out)
+	}
+
+	// Null 将指针置为 nil，默认值按 Go 类型解析
+	delete(m, "score")
+	m["limit"] = nson.Null{}
+	out = Counter{}
+	if err := nson.Unmarshal(m, &out); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if out.Limit != nil || out.Score != 3 {
+		t.Errorf("Unexpected result: %+v", out)
+	}
+}
+
+func TestWireTypeRange(t *testing.T) {
+	limit := int32(200)
+	marshalErrors := map[string]Counter{
+		"field Port: I64(70000) overflows U16":       {Port: 70000},
+		"field Port: I64(-1) overflows U16":          {Port: -1},
+		"field Created: I64(-1) overflows Timestamp": {Created: -1},
+		"cannot be represented exactly as I32":       {Weight: 1.5},
+		"field Limit: I64(200) overflows I8":         {Limit: &limit},
+	}
+
+	for want, in := range marshalErrors {
+		_, err := nson.Marshal(in)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error %q, got %v", want, err)
+		}
+	}
+
+	unmarshalErrors := map[string]nson.Map{
+		"field Count: expected I64, got nson.I32":       {"count": nson.I32(1)},
+		"field Total: String(-1) overflows U64":         {"total": nson.String("-1")},
+		"field Total: cannot parse \"x\" as U64":        {"total": nson.String("x")},
+		"field Score: F32(1e+10) overflows I16":         {"score": nson.F32(1e10)},
+		"field Score: F32(1.5) cannot be represented":   {"score": nson.F32(1.5)},
+		"field Created: Timestamp(9223372036854775808)": {"created": nson.Timestamp(1 << 63)},
+	}
+
+	for want, m := range unmarshalErrors {
+		var out Counter
+		err := nson.Unmarshal(m, &out)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error %q, got %v", want, err)
+		}
+	}
+}
+
+func TestWireTypeSchemaAndValidate(t *testing.T) {
+	schema, err := nson.SchemaFor[Counter]()
+	if err != nil {
+		t.Fatalf("SchemaFor failed: %v", err)
+	}
+
+	m, err := nson.Marshal(Counter{Weight: 42})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if errs := schema.Validate(m); len(errs) > 0 {
+		t.Errorf("Validate failed: %v", errs)
+	}
+
+	m["count"] = nson.I32(1)
+	if errs := schema.Validate(m); len(errs) == 0 {
+		t.Error("Expected schema error for I32 count")
+	}
+
+	if err := nson.Validate(Counter{Weight: 101}); err == nil || !strings.Contains(err.Error(), "weight") {
+		t.Errorf("Expected validation error, got %v", err)
+	}
+}
+
+func TestWireTypeErrors(t *testing.T) {
+	type BadName struct {
+		N int `nson:"n,type=int"`
+	}
+	type BadKind struct {
+		S string `nson:"s,type=i64"`
+	}
+	type BadFloat struct {
+		F float64 `nson:"f,type=timestamp"`
+	}
+	type BadBinary struct {
+		N int `nson:"n,type=binary"`
+	}
+
+	tests := map[string]any{
+		"field N: unsupported type=int":                                  BadName{},
+		"field S: type=i64 requires a numeric field, got string":         BadKind{},
+		"field F: type=timestamp requires an integer field, got float64": BadFloat{},
+		"field N: unsupported type=binary":                               BadBinary{},
+	}
+
+	for want, v := range tests {
+		_, err := nson.Marshal(v)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error %q, got %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package nson
+
+// MergePatch 按 RFC 7386 (JSON Merge Patch) 语义将 patch 合并到 target，
+// 返回新的 Map，target 和 patch 都不会被修改：
+//
+//   - patch 中的 Map 与 target 中的 Map 递归合并
+//   - patch 中的 Null 删除对应的键
+//   - 其他值（包括 Array）直接替换
+func MergePatch(target, patch Map) Map {
+	result := target.Clone()
+	if result == nil {
+		result = Map{}
+	}
+
+	mergePatchInto(result, patch)
+
+	return result
+}
+
+func mergePatchInto(target, patch Map) {
+	for k, v := range patch {
+		switch p := v.(type) {
+		case Null:
+			delete(target, k)
+		case Map:
+			t, ok := target[k].(Map)
+			if !ok {
+				t = Map{}
+			}
+			mergePatchInto(t, p)
+			target[k] = t
+		default:
+			target[k] = Clone(v)
+		}
+	}
+}
+
+// ArrayMergeStrategy DeepMerge 处理 Array 的方式
+type ArrayMergeStrategy uint8
+
+const (
+	// ArrayReplace 用新的 Array 替换旧的 Array
+	ArrayReplace ArrayMergeStrategy = iota
+	// ArrayAppend 将新的 Array 追加到旧的 Array 后面
+	ArrayAppend
+	// ArrayMergeByKey 将两个 Array 中 MergeKey 相同的 Map 元素递归合并，
+	// 其余元素追加到末尾
+	ArrayMergeByKey
+)
+
+// MergeOptions DeepMerge 的选项
+type MergeOptions struct {
+	Arrays   ArrayMergeStrategy
+	MergeKey string // ArrayMergeByKey 使用的键
+	// DeleteNull 为 true 时 Null 删除对应的键，新加入的 Map 中的 Null 也会递归删除，
+	// 否则 Null 作为普通值覆盖
+	DeleteNull bool
+}
+
+// DeepMerge 将 overlay 递归合并到 base 上并返回新的 Map，适合用于多层配置：
+//
+//	config := DeepMerge(DeepMerge(defaults, file, opts), env, opts)
+func DeepMerge(base, overlay Map, opts MergeOptions) Map {
+	result := base.Clone()
+	if result == nil {
+		result = Map{}
+	}
+
+	deepMergeInto(result, overlay, opts)
+
+	return result
+}
+
+func deepMergeInto(base, overlay Map, opts MergeOptions) {
+	for k, v := range overlay {
+		switch o := v.(type) {
+		case Null:
+			if opts.DeleteNull {
+				delete(base, k)
+			} else {
+				base[k] = Null{}
+			}
+		case Map:
+			if b, ok := base[k].(Map); ok {
+				deepMergeInto(b, o, opts)
+			} else {
+				base[k] = mergeNew(o, opts)
+			}
+		case Array:
+			if b, ok := base[k].(Array); ok {
+				base[k] = mergeArray(b, o, opts)
+			} else {
+				base[k] = mergeNewArray(o, opts)
+			}
+		default:
+			base[k] = Clone(v)
+		}
+	}
+}
+
+// mergeNew 复制 base 中没有对应值的 Map，DeleteNull 时递归去掉其中的 Null
+func mergeNew(overlay Map, opts MergeOptions) Map {
+	m := make(Map, len(overlay))
+	deepMergeInto(m, overlay, opts)
+	return m
+}
+
+// mergeNewArray 复制 overlay 中新加入的 Array，其中的 Map 按 mergeNew 处理
+func mergeNewArray(overlay Array, opts MergeOptions) Array {
+	arr := make(Array, len(overlay))
+	for i, item := range overlay {
+		if m, ok := item.(Map); ok {
+			arr[i] = mergeNew(m, opts)
+		} else {
+			arr[i] = Clone(item)
+		}
+	}
+	return arr
+}
+
+func mergeArray(base, overlay Array, opts MergeOptions) Array {
+	switch opts.Arrays {
+	case ArrayAppend:
+		return append(base, mergeNewArray(overlay, opts)...)
+
+	case ArrayMergeByKey:
+		for _, item := range overlay {
+			if i := indexByKey(base, item, opts.MergeKey); i >= 0 {
+				deepMergeInto(base[i].(Map), item.(Map), opts)
+			} else {
+				base = append(base, mergeNewArray(Array{item}, opts)...)
+			}
+		}
+		return base
+
+	default:
+		return mergeNewArray(overlay, opts)
+	}
+}
+
+// indexByKey 在 arr 中查找与 item 的 key 值相等的 Map 元素，未找到返回 -1
+func indexByKey(arr Array, item Value, key string) int {
+	m, ok := item.(Map)
+	if !ok {
+		return -1
+	}
+
+	id, has := m[key]
+	if !has {
+		return -1
+	}
+
+	for i, v := range arr {
+		if e, ok := v.(Map); ok {
+			if eid, has := e[key]; has && EqualNumeric(eid, id) {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
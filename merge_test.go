@@ -0,0 +1,131 @@
+package nson
+
+import (
+	"testing"
+)
+
+func TestMergePatch(t *testing.T) {
+	target := Map{
+		"title": String("Goodbye!"),
+		"author": Map{
+			"givenName":  String("John"),
+			"familyName": String("Doe"),
+		},
+		"tags":    Array{String("example"), String("sample")},
+		"content": String("This will be unchanged"),
+	}
+	patch := Map{
+		"title":       String("Hello!"),
+		"phoneNumber": String("+01-123-456-7890"),
+		"author":      Map{"familyName": Null{}},
+		"tags":        Array{String("example")},
+	}
+
+	result := MergePatch(target, patch)
+
+	want := Map{
+		"title":       String("Hello!"),
+		"author":      Map{"givenName": String("John")},
+		"tags":        Array{String("example")},
+		"content":     String("This will be unchanged"),
+		"phoneNumber": String("+01-123-456-7890"),
+	}
+	if !Equal(result, want) {
+		t.Errorf("Expected %v, got %v", want, result)
+	}
+
+	if _, has := target["author"].(Map)["familyName"]; !has {
+		t.Error("MergePatch must not modify the target")
+	}
+}
+
+func TestMergePatchReplacesNonMap(t *testing.T) {
+	result := MergePatch(Map{"a": String("x")}, Map{"a": Map{"b": Null{}, "c": I32(1)}})
+
+	want := Map{"a": Map{"c": I32(1)}}
+	if !Equal(result, want) {
+		t.Errorf("Expected %v, got %v", want, result)
+	}
+}
+
+func TestDeepMerge(t *testing.T) {
+	defaults := Map{
+		"server": Map{"host": String("0.0.0.0"), "port": U16(80)},
+		"plugins": Array{
+			Map{"name": String("auth"), "enabled": Bool(true)},
+			Map{"name": String("log"), "level": String("info")},
+		},
+		"debug": Bool(false),
+	}
+	overlay := Map{
+		"server": Map{"port": U16(8080)},
+		"plugins": Array{
+			Map{"name": String("log"), "level": String("debug")},
+			Map{"name": String("metrics")},
+		},
+		"debug": Null{},
+	}
+
+	replaced := DeepMerge(defaults, overlay, MergeOptions{})
+	if !Equal(replaced["plugins"], overlay["plugins"]) {
+		t.Errorf("Expected plugins to be replaced, got %v", replaced["plugins"])
+	}
+	if replaced["debug"] != (Null{}) {
+		t.Errorf("Expected debug to be Null, got %v", replaced["debug"])
+	}
+	if port, _ := replaced.GetPath("server.port"); port != U16(8080) {
+		t.Errorf("Expected port 8080, got %v", port)
+	}
+	if host, _ := replaced.GetPath("server.host"); host != String("0.0.0.0") {
+		t.Errorf("Expected host to survive, got %v", host)
+	}
+
+	appended := DeepMerge(defaults, overlay, MergeOptions{Arrays: ArrayAppend, DeleteNull: true})
+	if len(appended["plugins"].(Array)) != 4 {
+		t.Errorf("Expected 4 plugins, got %v", appended["plugins"])
+	}
+	if _, has := appended["debug"]; has {
+		t.Error("Expected debug to be deleted")
+	}
+
+	merged := DeepMerge(defaults, overlay, MergeOptions{Arrays: ArrayMergeByKey, MergeKey: "name"})
+	want := Array{
+		Map{"name": String("auth"), "enabled": Bool(true)},
+		Map{"name": String("log"), "level": String("debug")},
+		Map{"name": String("metrics")},
+	}
+	if !Equal(merged["plugins"], want) {
+		t.Errorf("Expected %v, got %v", want, merged["plugins"])
+	}
+
+	if level, _ := defaults.GetPath("plugins.1.level"); level != String("info") {
+		t.Error("DeepMerge must not modify the base")
+	}
+}
+
+func TestDeepMergeDeleteNullNew(t *testing.T) {
+	base := Map{"server": String("off")}
+	overlay := Map{
+		"cache":   Map{"size": I32(10), "dir": Null{}, "ttl": Map{"max": Null{}, "min": I32(1)}},
+		"server":  Map{"host": Null{}, "port": U16(80)},
+		"plugins": Array{Map{"name": String("log"), "level": Null{}}},
+	}
+
+	got := DeepMerge(base, overlay, MergeOptions{Arrays: ArrayMergeByKey, MergeKey: "name", DeleteNull: true})
+	want := Map{
+		"cache":   Map{"size": I32(10), "ttl": Map{"min": I32(1)}},
+		"server":  Map{"port": U16(80)},
+		"plugins": Array{Map{"name": String("log")}},
+	}
+	if !Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	kept := DeepMerge(base, overlay, MergeOptions{})
+	if v, _ := kept.GetPath("cache.ttl.max"); v != (Null{}) {
+		t.Errorf("Expected nested Null to be kept without DeleteNull, got %v", v)
+	}
+	if _, has := overlay["cache"].(Map)["dir"]; !has {
+		t.Error("DeepMerge must not modify the overlay")
+	}
+}
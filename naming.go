@@ -0,0 +1,79 @@
+package nson
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Naming 为没有在 tag 中指定名称的字段生成键名，通过 MarshalOptions 和 UnmarshalOptions 使用。
+// 字段信息按 Naming 的指针缓存，NamingFunc 创建的规则应当保存下来重复使用
+type Naming struct {
+	fn func(field string) string
+}
+
+// NamingFunc 使用自定义函数创建 Naming，fn 的参数为 Go 字段名
+func NamingFunc(fn func(field string) string) *Naming {
+	return &Naming{fn: fn}
+}
+
+var (
+	// SnakeCase 如 HTTPServerID 为 "http_server_id"
+	SnakeCase = NamingFunc(func(field string) string {
+		return strings.Join(lowerWords(field), "_")
+	})
+	// KebabCase 如 HTTPServerID 为 "http-server-id"
+	KebabCase = NamingFunc(func(field string) string {
+		return strings.Join(lowerWords(field), "-")
+	})
+	// CamelCase 如 HTTPServerID 为 "httpServerId"
+	CamelCase = NamingFunc(func(field string) string {
+		words := lowerWords(field)
+		for i := 1; i < len(words); i++ {
+			r := []rune(words[i])
+			r[0] = unicode.ToUpper(r[0])
+			words[i] = string(r)
+		}
+		return strings.Join(words, "")
+	})
+	// LowerCase 如 HTTPServerID 为 "httpserverid"
+	LowerCase = NamingFunc(strings.ToLower)
+)
+
+// Name 返回字段的键名，nil 使用字段名本身
+func (self *Naming) Name(field string) string {
+	if self == nil {
+		return field
+	}
+
+	return self.fn(field)
+}
+
+// lowerWords 将字段名拆分为小写的单词，下划线、小写到大写的变化以及连续大写的结尾处为分界，
+// 数字属于前一个单词，如 "HTTPServer2ID" 为 http、server2、id
+func lowerWords(field string) []string {
+	var words []string
+
+	for _, part := range strings.Split(field, "_") {
+		runes := []rune(part)
+		start := 0
+
+		for i := 1; i < len(runes); i++ {
+			prev, cur := runes[i-1], runes[i]
+
+			if !unicode.IsUpper(cur) {
+				continue
+			}
+
+			if !unicode.IsUpper(prev) || i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+				words = append(words, strings.ToLower(string(runes[start:i])))
+				start = i
+			}
+		}
+
+		if start < len(runes) {
+			words = append(words, strings.ToLower(string(runes[start:])))
+		}
+	}
+
+	return words
+}
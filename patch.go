@@ -0,0 +1,424 @@
+package nson
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 参考 RFC 6902 (JSON Patch) 的文档补丁。
+// 路径使用 JSON Pointer 格式（RFC 6901），如 "/a/b/0"，"" 表示整个文档，
+// 键中的 "~" 和 "/" 分别转义为 "~0" 和 "~1"，数组下标 "-" 表示末尾。
+
+const (
+	PatchAdd     = "add"
+	PatchRemove  = "remove"
+	PatchReplace = "replace"
+	PatchMove    = "move"
+	PatchCopy    = "copy"
+	PatchTest    = "test"
+)
+
+// Operation 补丁中的一个操作
+type Operation struct {
+	Op    string
+	Path  string
+	From  string // 仅用于 move 和 copy
+	Value Value  // 仅用于 add、replace 和 test
+}
+
+// Patch 有序的操作列表
+type Patch []Operation
+
+// Diff 计算从 old 到 new 的补丁，满足 Apply(old, Diff(old, new)) 等于 new。
+// 同一层 Map 中被删除的值若原样出现在新的键下，会生成 move 操作。
+func Diff(old, new Map) Patch {
+	patch := Patch{}
+	diffMap(&patch, "", old, new)
+	return patch
+}
+
+func diffMap(patch *Patch, base string, old, new Map) {
+	var removed, added []string
+
+	for _, k := range sortedKeys(old) {
+		if _, has := new[k]; !has {
+			removed = append(removed, k)
+		}
+	}
+
+	for _, k := range sortedKeys(new) {
+		if _, has := old[k]; !has {
+			added = append(added, k)
+		}
+	}
+
+	// 检测键的重命名
+	moved := make(map[string]bool)
+	for _, from := range removed {
+		for _, to := range added {
+			if !moved[to] && Equal(old[from], new[to]) {
+				*patch = append(*patch, Operation{Op: PatchMove, From: appendPointer(base, from), Path: appendPointer(base, to)})
+				moved[from] = true
+				moved[to] = true
+				break
+			}
+		}
+	}
+
+	for _, k := range removed {
+		if !moved[k] {
+			*patch = append(*patch, Operation{Op: PatchRemove, Path: appendPointer(base, k)})
+		}
+	}
+
+	for _, k := range sortedKeys(old) {
+		if nv, has := new[k]; has {
+			diffValue(patch, appendPointer(base, k), old[k], nv)
+		}
+	}
+
+	for _, k := range added {
+		if !moved[k] {
+			*patch = append(*patch, Operation{Op: PatchAdd, Path: appendPointer(base, k), Value: Clone(new[k])})
+		}
+	}
+}
+
+func diffValue(patch *Patch, path string, old, new Value) {
+	switch o := old.(type) {
+	case Map:
+		if n, ok := new.(Map); ok {
+			diffMap(patch, path, o, n)
+			return
+		}
+	case Array:
+		if n, ok := new.(Array); ok {
+			diffArray(patch, path, o, n)
+			return
+		}
+	}
+
+	if !Equal(old, new) {
+		*patch = append(*patch, Operation{Op: PatchReplace, Path: path, Value: Clone(new)})
+	}
+}
+
+// diffArray 去掉相同的前缀和后缀后逐个比较，多余的元素从尾部删除或追加
+func diffArray(patch *Patch, base string, old, new Array) {
+	prefix := 0
+	for prefix < len(old) && prefix < len(new) && Equal(old[prefix], new[prefix]) {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(old)-prefix && suffix < len(new)-prefix &&
+		Equal(old[len(old)-1-suffix], new[len(new)-1-suffix]) {
+		suffix++
+	}
+
+	o := old[prefix : len(old)-suffix]
+	n := new[prefix : len(new)-suffix]
+
+	common := min(len(o), len(n))
+	for i := 0; i < common; i++ {
+		diffValue(patch, appendPointer(base, strconv.Itoa(prefix+i)), o[i], n[i])
+	}
+
+	for i := len(o) - 1; i >= common; i-- {
+		*patch = append(*patch, Operation{Op: PatchRemove, Path: appendPointer(base, strconv.Itoa(prefix+i))})
+	}
+
+	for i := common; i < len(n); i++ {
+		*patch = append(*patch, Operation{Op: PatchAdd, Path: appendPointer(base, strconv.Itoa(prefix+i)), Value: Clone(n[i])})
+	}
+}
+
+// Apply 将补丁应用到 m 的副本上并返回结果，m 本身不会被修改。
+// 任意操作失败时返回错误，不会返回部分应用的结果。
+func Apply(m Map, p Patch) (Map, error) {
+	doc := m.Clone()
+	if doc == nil {
+		doc = Map{}
+	}
+
+	for i, op := range p {
+		var err error
+		doc, err = applyOperation(doc, op)
+		if err != nil {
+			return nil, fmt.Errorf("Patch operation %d (%v %v): %w", i, op.Op, op.Path, err)
+		}
+	}
+
+	return doc, nil
+}
+
+func applyOperation(doc Map, op Operation) (Map, error) {
+	segs, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case PatchAdd:
+		if op.Value == nil {
+			return nil, errors.New("Missing value")
+		}
+		return pointerAdd(doc, segs, Clone(op.Value))
+
+	case PatchRemove:
+		_, err := pointerRemove(doc, segs)
+		return doc, err
+
+	case PatchReplace:
+		if op.Value == nil {
+			return nil, errors.New("Missing value")
+		}
+		if _, has := lookupPath(doc, segs); !has {
+			return nil, errors.New("Path not found")
+		}
+		if len(segs) == 0 {
+			return pointerAdd(doc, segs, Clone(op.Value))
+		}
+		return doc, writeBack(doc, segs, Clone(op.Value))
+
+	case PatchMove:
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		if isPointerPrefix(from, segs) {
+			if len(from) == len(segs) {
+				return doc, nil
+			}
+			return nil, errors.New("Cannot move a value into itself")
+		}
+		value, err := pointerRemove(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, segs, value)
+
+	case PatchCopy:
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, has := lookupPath(doc, from)
+		if !has {
+			return nil, errors.New("From path not found")
+		}
+		return pointerAdd(doc, segs, Clone(value))
+
+	case PatchTest:
+		value, has := lookupPath(doc, segs)
+		if !has {
+			return nil, errors.New("Path not found")
+		}
+		if !Equal(value, op.Value) {
+			return nil, fmt.Errorf("Test failed, value: %v", value)
+		}
+		return doc, nil
+
+	default:
+		return nil, fmt.Errorf("Unsupported operation: %v", op.Op)
+	}
+}
+
+// pointerAdd 在 segs 处添加值，数组中为插入而不是覆盖
+func pointerAdd(doc Map, segs []string, value Value) (Map, error) {
+	if len(segs) == 0 {
+		m, ok := value.(Map)
+		if !ok {
+			return nil, fmt.Errorf("Unexpected Type, path: , value: %v", value)
+		}
+		return m, nil
+	}
+
+	parent, last, err := pointerParent(doc, segs)
+	if err != nil {
+		return nil, err
+	}
+
+	switch c := parent.(type) {
+	case Map:
+		c[last] = value
+		return doc, nil
+	case Array:
+		i := len(c)
+		if last != "-" {
+			var ok bool
+			i, ok = parseIndex(last)
+			if !ok || i > len(c) {
+				return nil, fmt.Errorf("Invalid array index: %v", last)
+			}
+		}
+		c = append(c, nil)
+		copy(c[i+1:], c[i:])
+		c[i] = value
+		return doc, writeBack(doc, segs[:len(segs)-1], c)
+	default:
+		return nil, fmt.Errorf("Unexpected Type, value: %v", parent)
+	}
+}
+
+// pointerRemove 删除 segs 处的值并返回被删除的值
+func pointerRemove(doc Map, segs []string) (Value, error) {
+	if len(segs) == 0 {
+		return nil, errors.New("Cannot remove the root")
+	}
+
+	value, has := lookupPath(doc, segs)
+	if !has {
+		return nil, errors.New("Path not found")
+	}
+
+	if _, _, err := deleteIn(doc, segs, 0); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// pointerParent 返回 segs 的父容器和最后一段
+func pointerParent(doc Map, segs []string) (Value, string, error) {
+	parent, has := lookupPath(doc, segs[:len(segs)-1])
+	if !has {
+		return nil, "", errors.New("Parent path not found")
+	}
+
+	return parent, segs[len(segs)-1], nil
+}
+
+// writeBack 将修改后的容器写回 segs 处，Array 追加后底层切片可能变化
+func writeBack(doc Map, segs []string, value Value) error {
+	if len(segs) == 0 {
+		return nil
+	}
+
+	_, err := setIn(doc, segs, 0, value, false)
+	return err
+}
+
+func isPointerPrefix(prefix, segs []string) bool {
+	if len(prefix) > len(segs) {
+		return false
+	}
+
+	for i := range prefix {
+		if prefix[i] != segs[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parsePointer 解析 JSON Pointer
+func parsePointer(p string) ([]string, error) {
+	if p == "" {
+		return []string{}, nil
+	}
+
+	if p[0] != '/' {
+		return nil, fmt.Errorf("Invalid pointer: %v", p)
+	}
+
+	segs := strings.Split(p[1:], "/")
+	for i, seg := range segs {
+		if strings.Contains(seg, "~") {
+			seg = strings.ReplaceAll(seg, "~1", "/")
+			seg = strings.ReplaceAll(seg, "~0", "~")
+			segs[i] = seg
+		}
+	}
+
+	return segs, nil
+}
+
+func appendPointer(base string, key string) string {
+	key = strings.ReplaceAll(key, "~", "~0")
+	key = strings.ReplaceAll(key, "/", "~1")
+	return base + "/" + key
+}
+
+// Array 将补丁表示为 NSON 值，每个操作是一个 Map：
+//
+//	{"op": String, "path": String, "from": String, "value": Value}
+//
+// from 和 value 仅在需要时出现。
+func (self Patch) Array() Array {
+	arr := make(Array, 0, len(self))
+
+	for _, op := range self {
+		m := Map{
+			"op":   String(op.Op),
+			"path": String(op.Path),
+		}
+
+		if op.Op == PatchMove || op.Op == PatchCopy {
+			m["from"] = String(op.From)
+		}
+
+		if op.Value != nil {
+			m["value"] = op.Value
+		}
+
+		arr = append(arr, m)
+	}
+
+	return arr
+}
+
+// PatchFromArray 从 NSON 表示解析补丁
+func PatchFromArray(arr Array) (Patch, error) {
+	patch := make(Patch, 0, len(arr))
+
+	for i, item := range arr {
+		m, ok := item.(Map)
+		if !ok {
+			return nil, fmt.Errorf("Unexpected Type, index: %v, value: %v", i, item)
+		}
+
+		op, err := m.GetString("op")
+		if err != nil {
+			return nil, fmt.Errorf("index %d: %w", i, err)
+		}
+
+		path, err := m.GetString("path")
+		if err != nil {
+			return nil, fmt.Errorf("index %d: %w", i, err)
+		}
+
+		operation := Operation{Op: op, Path: path}
+
+		if op == PatchMove || op == PatchCopy {
+			if operation.From, err = m.GetString("from"); err != nil {
+				return nil, fmt.Errorf("index %d: %w", i, err)
+			}
+		}
+
+		if value, has := m["value"]; has {
+			operation.Value = value
+		}
+
+		patch = append(patch, operation)
+	}
+
+	return patch, nil
+}
+
+func EncodePatch(patch Patch, buf *bytes.Buffer) error {
+	return EncodeArray(patch.Array(), buf)
+}
+
+func DecodePatch(buf *bytes.Buffer) (Patch, error) {
+	arr, err := DecodeArray(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return PatchFromArray(arr)
+}
@@ -0,0 +1,147 @@
+package nson
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDiffApply(t *testing.T) {
+	old := Map{
+		"name":    String("sensor"),
+		"version": I32(1),
+		"config":  Map{"rate": U16(10), "mode": String("auto")},
+		"tags":    Array{String("a"), String("b"), String("c"), String("d")},
+		"blob":    Binary{1, 2, 3},
+		"x/y":     Bool(true),
+	}
+	new := Map{
+		"name":     String("sensor"),
+		"version":  I64(2),
+		"config":   Map{"rate": U16(20), "level": I8(3)},
+		"tags":     Array{String("a"), String("x"), String("d"), String("e")},
+		"data":     Binary{1, 2, 3},
+		"x/y":      Bool(true),
+		"location": Map{"lat": F64(1.5)},
+	}
+
+	patch := Diff(old, new)
+
+	result, err := Apply(old, patch)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	if !Equal(result, new) {
+		t.Errorf("Expected %v, got %v", new, result)
+	}
+
+	if _, has := old["data"]; has {
+		t.Error("Apply must not modify the original map")
+	}
+
+	foundMove := false
+	for _, op := range patch {
+		if op.Op == PatchMove && op.From == "/blob" && op.Path == "/data" {
+			foundMove = true
+		}
+	}
+	if !foundMove {
+		t.Errorf("Expected move /blob -> /data, got %v", patch)
+	}
+}
+
+func TestDiffEqual(t *testing.T) {
+	m := Map{"a": Array{I32(1)}, "b": Map{"c": Null{}}}
+
+	if patch := Diff(m, m.Clone()); len(patch) != 0 {
+		t.Errorf("Expected empty patch, got %v", patch)
+	}
+}
+
+func TestApplyOperations(t *testing.T) {
+	doc := Map{
+		"a":    Array{I32(1), I32(3)},
+		"b":    Map{"c": String("x")},
+		"a~/b": I32(7),
+	}
+
+	patch := Patch{
+		{Op: PatchAdd, Path: "/a/1", Value: I32(2)},
+		{Op: PatchAdd, Path: "/a/-", Value: I32(4)},
+		{Op: PatchTest, Path: "/a/3", Value: I32(4)},
+		{Op: PatchCopy, From: "/b", Path: "/d"},
+		{Op: PatchMove, From: "/b/c", Path: "/b/e"},
+		{Op: PatchReplace, Path: "/a~0~1b", Value: I32(8)},
+		{Op: PatchRemove, Path: "/a/0"},
+	}
+
+	result, err := Apply(doc, patch)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	want := Map{
+		"a":    Array{I32(2), I32(3), I32(4)},
+		"b":    Map{"e": String("x")},
+		"d":    Map{"c": String("x")},
+		"a~/b": I32(8),
+	}
+	if !Equal(result, want) {
+		t.Errorf("Expected %v, got %v", want, result)
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	doc := Map{"a": Array{I32(1)}, "b": Map{}}
+
+	tests := []Patch{
+		{{Op: PatchRemove, Path: "/missing"}},
+		{{Op: PatchReplace, Path: "/missing", Value: I32(1)}},
+		{{Op: PatchAdd, Path: "/a/5", Value: I32(1)}},
+		{{Op: PatchAdd, Path: "/x/y", Value: I32(1)}},
+		{{Op: PatchTest, Path: "/a/0", Value: I64(1)}},
+		{{Op: PatchMove, From: "/b", Path: "/b/c"}},
+		{{Op: PatchAdd, Path: "a", Value: I32(1)}},
+		{{Op: "unknown", Path: "/a"}},
+	}
+
+	for _, patch := range tests {
+		if _, err := Apply(doc, patch); err == nil {
+			t.Errorf("Expected error for %v", patch)
+		}
+	}
+}
+
+func TestPatchEncodeDecode(t *testing.T) {
+	patch := Patch{
+		{Op: PatchAdd, Path: "/a", Value: Map{"b": I32(1)}},
+		{Op: PatchMove, From: "/c", Path: "/d"},
+		{Op: PatchRemove, Path: "/e/0"},
+		{Op: PatchReplace, Path: "/f", Value: Null{}},
+	}
+
+	buf := new(bytes.Buffer)
+	if err := EncodePatch(patch, buf); err != nil {
+		t.Fatalf("EncodePatch failed: %v", err)
+	}
+
+	decoded, err := DecodePatch(buf)
+	if err != nil {
+		t.Fatalf("DecodePatch failed: %v", err)
+	}
+
+	if len(decoded) != len(patch) {
+		t.Fatalf("Expected %d operations, got %d", len(patch), len(decoded))
+	}
+
+	for i := range patch {
+		if decoded[i].Op != patch[i].Op || decoded[i].Path != patch[i].Path ||
+			decoded[i].From != patch[i].From || !Equal(decoded[i].Value, patch[i].Value) {
+			t.Errorf("Operation %d mismatch: got %v, want %v", i, decoded[i], patch[i])
+		}
+	}
+
+	if _, err := PatchFromArray(Array{Map{"op": String("move"), "path": String("/a")}}); err == nil {
+		t.Error("Expected error for move without from")
+	}
+}
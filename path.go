@@ -0,0 +1,372 @@
+package nson
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 路径使用 "." 分隔，如 "a.b.0.c"。
+// 在 Map 中每一段都是键，在 Array 中每一段必须是十进制下标。
+
+// splitPath 将路径拆分为各段
+func splitPath(path string) ([]string, error) {
+	if path == "" {
+		return nil, errors.New("Empty path")
+	}
+
+	segs := strings.Split(path, ".")
+	for _, seg := range segs {
+		if seg == "" {
+			return nil, fmt.Errorf("Invalid path: %v", path)
+		}
+	}
+
+	return segs, nil
+}
+
+// parseIndex 解析数组下标
+func parseIndex(seg string) (int, bool) {
+	if seg == "" || (len(seg) > 1 && seg[0] == '0') {
+		return 0, false
+	}
+
+	for i := 0; i < len(seg); i++ {
+		if seg[i] < '0' || seg[i] > '9' {
+			return 0, false
+		}
+	}
+
+	i, err := strconv.Atoi(seg)
+	if err != nil {
+		return 0, false
+	}
+
+	return i, true
+}
+
+// lookupPath 沿路径查找值
+func lookupPath(value Value, segs []string) (Value, bool) {
+	for _, seg := range segs {
+		switch v := value.(type) {
+		case Map:
+			next, has := v[seg]
+			if !has {
+				return nil, false
+			}
+			value = next
+		case Array:
+			i, ok := parseIndex(seg)
+			if !ok || i >= len(v) {
+				return nil, false
+			}
+			value = v[i]
+		default:
+			return nil, false
+		}
+	}
+
+	return value, true
+}
+
+func (self Map) GetPath(path string) (Value, bool) {
+	segs, err := splitPath(path)
+	if err != nil {
+		return nil, false
+	}
+
+	return lookupPath(self, segs)
+}
+
+func (self Array) GetPath(path string) (Value, bool) {
+	segs, err := splitPath(path)
+	if err != nil {
+		return nil, false
+	}
+
+	return lookupPath(self, segs)
+}
+
+// SetPath 按路径设置值，缺失的中间节点会自动创建为 Map。
+// 数组下标等于数组长度时追加元素，超出长度时返回错误。
+func (self *Map) SetPath(path string, value Value) error {
+	return self.setPath(path, value, false)
+}
+
+// SetPathPad 与 SetPath 相同，但数组下标超出长度时使用 Null 填充
+func (self *Map) SetPathPad(path string, value Value) error {
+	return self.setPath(path, value, true)
+}
+
+func (self *Map) setPath(path string, value Value, pad bool) error {
+	segs, err := splitPath(path)
+	if err != nil {
+		return err
+	}
+
+	if *self == nil {
+		*self = Map{}
+	}
+
+	_, err = setIn(*self, segs, 0, value, pad)
+	return err
+}
+
+func (self *Array) SetPath(path string, value Value) error {
+	return self.setPath(path, value, false)
+}
+
+func (self *Array) SetPathPad(path string, value Value) error {
+	return self.setPath(path, value, true)
+}
+
+func (self *Array) setPath(path string, value Value, pad bool) error {
+	segs, err := splitPath(path)
+	if err != nil {
+		return err
+	}
+
+	v, err := setIn(*self, segs, 0, value, pad)
+	if err != nil {
+		return err
+	}
+
+	*self = v.(Array)
+	return nil
+}
+
+// setIn 在 container 中设置 segs[n:] 对应的值，返回更新后的容器。
+// Array 追加元素后底层切片可能变化，所以调用方需要写回返回值。
+func setIn(container Value, segs []string, n int, value Value, pad bool) (Value, error) {
+	seg := segs[n]
+	last := n == len(segs)-1
+
+	switch c := container.(type) {
+	case Map:
+		if last {
+			c[seg] = value
+			return c, nil
+		}
+
+		child, has := c[seg]
+		if !has || isNullValue(child) {
+			child = Map{}
+		}
+
+		child, err := setIn(child, segs, n+1, value, pad)
+		if err != nil {
+			return nil, err
+		}
+
+		c[seg] = child
+		return c, nil
+
+	case Array:
+		i, ok := parseIndex(seg)
+		if !ok {
+			return nil, fmt.Errorf("Invalid array index, path: %v", joinPath(segs, n))
+		}
+
+		if i > len(c) {
+			if !pad {
+				return nil, fmt.Errorf("Array index out of range, path: %v", joinPath(segs, n))
+			}
+			for len(c) < i {
+				c = append(c, Null{})
+			}
+		}
+
+		if last {
+			if i == len(c) {
+				c = append(c, value)
+			} else {
+				c[i] = value
+			}
+			return c, nil
+		}
+
+		var child Value = Map{}
+		if i < len(c) && !isNullValue(c[i]) {
+			child = c[i]
+		}
+
+		child, err := setIn(child, segs, n+1, value, pad)
+		if err != nil {
+			return nil, err
+		}
+
+		if i == len(c) {
+			c = append(c, child)
+		} else {
+			c[i] = child
+		}
+		return c, nil
+
+	default:
+		return nil, fmt.Errorf("Unexpected Type, path: %v, value: %v", joinPath(segs, n-1), container)
+	}
+}
+
+// DeletePath 按路径删除值，返回值是否存在。
+// 删除数组元素时后续元素前移。
+func (self *Map) DeletePath(path string) (bool, error) {
+	segs, err := splitPath(path)
+	if err != nil {
+		return false, err
+	}
+
+	_, has, err := deleteIn(*self, segs, 0)
+	return has, err
+}
+
+func (self *Array) DeletePath(path string) (bool, error) {
+	segs, err := splitPath(path)
+	if err != nil {
+		return false, err
+	}
+
+	v, has, err := deleteIn(*self, segs, 0)
+	if err != nil {
+		return false, err
+	}
+
+	*self = v.(Array)
+	return has, nil
+}
+
+func deleteIn(container Value, segs []string, n int) (Value, bool, error) {
+	seg := segs[n]
+	last := n == len(segs)-1
+
+	switch c := container.(type) {
+	case Map:
+		child, has := c[seg]
+		if !has {
+			return c, false, nil
+		}
+
+		if last {
+			delete(c, seg)
+			return c, true, nil
+		}
+
+		child, has, err := deleteIn(child, segs, n+1)
+		if err != nil {
+			return nil, false, err
+		}
+
+		c[seg] = child
+		return c, has, nil
+
+	case Array:
+		i, ok := parseIndex(seg)
+		if !ok {
+			return nil, false, fmt.Errorf("Invalid array index, path: %v", joinPath(segs, n))
+		}
+
+		if i >= len(c) {
+			return c, false, nil
+		}
+
+		if last {
+			return append(c[:i], c[i+1:]...), true, nil
+		}
+
+		child, has, err := deleteIn(c[i], segs, n+1)
+		if err != nil {
+			return nil, false, err
+		}
+
+		c[i] = child
+		return c, has, nil
+
+	default:
+		return nil, false, fmt.Errorf("Unexpected Type, path: %v, value: %v", joinPath(segs, n-1), container)
+	}
+}
+
+// AppendPath 向路径指向的 Array 追加元素，Array 不存在时自动创建
+func (self *Map) AppendPath(path string, values ...Value) error {
+	return self.appendPath(path, values, false)
+}
+
+// AppendPathPad 与 AppendPath 相同，但数组下标超出长度时使用 Null 填充
+func (self *Map) AppendPathPad(path string, values ...Value) error {
+	return self.appendPath(path, values, true)
+}
+
+func (self *Map) appendPath(path string, values []Value, pad bool) error {
+	segs, err := splitPath(path)
+	if err != nil {
+		return err
+	}
+
+	if *self == nil {
+		*self = Map{}
+	}
+
+	arr, err := appendTarget(*self, segs, path)
+	if err != nil {
+		return err
+	}
+
+	_, err = setIn(*self, segs, 0, append(arr, values...), pad)
+	return err
+}
+
+func (self *Array) AppendPath(path string, values ...Value) error {
+	return self.appendPath(path, values, false)
+}
+
+func (self *Array) AppendPathPad(path string, values ...Value) error {
+	return self.appendPath(path, values, true)
+}
+
+func (self *Array) appendPath(path string, values []Value, pad bool) error {
+	segs, err := splitPath(path)
+	if err != nil {
+		return err
+	}
+
+	arr, err := appendTarget(*self, segs, path)
+	if err != nil {
+		return err
+	}
+
+	v, err := setIn(*self, segs, 0, append(arr, values...), pad)
+	if err != nil {
+		return err
+	}
+
+	*self = v.(Array)
+	return nil
+}
+
+// appendTarget 返回路径上已有的 Array，不存在时返回 nil
+func appendTarget(root Value, segs []string, path string) (Array, error) {
+	value, has := lookupPath(root, segs)
+	if !has || isNullValue(value) {
+		return nil, nil
+	}
+
+	arr, ok := value.(Array)
+	if !ok {
+		return nil, fmt.Errorf("Unexpected Type, path: %v, value: %v", path, value)
+	}
+
+	return arr, nil
+}
+
+// isNullValue 判断中间节点是否可以被替换为新的 Map
+func isNullValue(value Value) bool {
+	return value == nil || value.DataType() == DataTypeNULL
+}
+
+func joinPath(segs []string, n int) string {
+	if n < 0 {
+		return ""
+	}
+
+	return strings.Join(segs[:n+1], ".")
+}
@@ -0,0 +1,153 @@
+package nson
+
+import (
+	"testing"
+)
+
+func TestGetPath(t *testing.T) {
+	m := Map{
+		"a": Map{
+			"b": Array{I32(1), Map{"c": String("x")}},
+		},
+	}
+
+	if v, ok := m.GetPath("a.b.1.c"); !ok || v != String("x") {
+		t.Errorf("Expected String(x), got %v, %v", v, ok)
+	}
+
+	if _, ok := m.GetPath("a.b.2"); ok {
+		t.Error("Expected a.b.2 to be absent")
+	}
+
+	if _, ok := m.GetPath("a.b.01"); ok {
+		t.Error("Expected leading zero index to be rejected")
+	}
+
+	if _, ok := m.GetPath("a..b"); ok {
+		t.Error("Expected empty segment to be rejected")
+	}
+}
+
+func TestSetPathCreatesMaps(t *testing.T) {
+	m := Map{}
+
+	if err := m.SetPath("server.http.port", U16(8080)); err != nil {
+		t.Fatalf("SetPath failed: %v", err)
+	}
+
+	if v, ok := m.GetPath("server.http.port"); !ok || v != U16(8080) {
+		t.Errorf("Expected U16(8080), got %v", v)
+	}
+
+	if err := m.SetPath("server.http.port.value", I32(1)); err == nil {
+		t.Error("Expected error when intermediate value is not a container")
+	}
+
+	var nilMap Map
+	if err := nilMap.SetPath("a.b", Bool(true)); err != nil {
+		t.Fatalf("SetPath on nil map failed: %v", err)
+	}
+	if !nilMap.Contains("a") {
+		t.Error("Expected nil map to be initialised")
+	}
+}
+
+func TestSetPathArray(t *testing.T) {
+	m := Map{"list": Array{I32(1)}}
+
+	if err := m.SetPath("list.1", I32(2)); err != nil {
+		t.Fatalf("SetPath append failed: %v", err)
+	}
+
+	if err := m.SetPath("list.5", I32(6)); err == nil {
+		t.Error("Expected out of range error without padding")
+	}
+
+	if err := m.SetPathPad("list.4.name", String("pad")); err != nil {
+		t.Fatalf("SetPathPad failed: %v", err)
+	}
+
+	list, _ := m.GetArray("list")
+	if len(list) != 5 {
+		t.Fatalf("Expected 5 elements, got %d", len(list))
+	}
+	if list[2] != (Null{}) || list[3] != (Null{}) {
+		t.Errorf("Expected Null padding, got %v", list)
+	}
+	if v, ok := m.GetPath("list.4.name"); !ok || v != String("pad") {
+		t.Errorf("Expected String(pad), got %v", v)
+	}
+
+	if err := m.SetPath("list.x", I32(0)); err == nil {
+		t.Error("Expected invalid index error")
+	}
+}
+
+func TestArrayRootPath(t *testing.T) {
+	a := Array{}
+
+	if err := a.SetPath("0.name", String("first")); err != nil {
+		t.Fatalf("SetPath failed: %v", err)
+	}
+	if err := a.AppendPath("0.tags", String("x"), String("y")); err != nil {
+		t.Fatalf("AppendPath failed: %v", err)
+	}
+
+	if len(a) != 1 {
+		t.Fatalf("Expected 1 element, got %d", len(a))
+	}
+	if v, ok := a.GetPath("0.tags.1"); !ok || v != String("y") {
+		t.Errorf("Expected String(y), got %v", v)
+	}
+
+	if has, err := a.DeletePath("0"); err != nil || !has {
+		t.Fatalf("DeletePath failed: %v, %v", has, err)
+	}
+	if len(a) != 0 {
+		t.Errorf("Expected empty array, got %v", a)
+	}
+}
+
+func TestDeletePath(t *testing.T) {
+	m := Map{
+		"a": Map{"b": I32(1), "c": Array{I32(1), I32(2), I32(3)}},
+		"s": String("x"),
+	}
+
+	if has, err := m.DeletePath("a.b"); err != nil || !has {
+		t.Errorf("Expected a.b to be deleted, got %v, %v", has, err)
+	}
+	if has, err := m.DeletePath("a.b"); err != nil || has {
+		t.Errorf("Expected a.b to be absent, got %v, %v", has, err)
+	}
+
+	if has, err := m.DeletePath("a.c.1"); err != nil || !has {
+		t.Errorf("Expected a.c.1 to be deleted, got %v, %v", has, err)
+	}
+	if v, _ := m.GetPath("a.c"); len(v.(Array)) != 2 || v.(Array)[1] != I32(3) {
+		t.Errorf("Expected elements to shift, got %v", v)
+	}
+
+	if _, err := m.DeletePath("s.x"); err == nil {
+		t.Error("Expected error when deleting through a String")
+	}
+}
+
+func TestAppendPath(t *testing.T) {
+	m := Map{"name": String("x")}
+
+	if err := m.AppendPath("a.list", I32(1)); err != nil {
+		t.Fatalf("AppendPath failed: %v", err)
+	}
+	if err := m.AppendPath("a.list", I32(2)); err != nil {
+		t.Fatalf("AppendPath failed: %v", err)
+	}
+
+	if v, _ := m.GetPath("a.list"); len(v.(Array)) != 2 {
+		t.Errorf("Expected 2 elements, got %v", v)
+	}
+
+	if err := m.AppendPath("name", I32(1)); err == nil {
+		t.Error("Expected error when appending to a String")
+	}
+}
@@ -0,0 +1,328 @@
+package nson
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// 投影规则使用 Map 表示，语法参考 MongoDB 的 find 投影：
+//
+//	Map{"name": I32(1), "meta.owner": Bool(true)}     // 只保留指定字段
+//	Map{"password": I32(0), "meta.secret": I32(0)}    // 去掉指定字段
+//	Map{"comments": Map{"$slice": I32(-5)}}           // 数组取最后 5 个元素
+//	Map{"comments": Map{"$slice": Array{I32(10), I32(5)}}} // 跳过 10 个取 5 个
+//	Map{"owner": String("$meta.owner")}               // 重命名，值取自另一路径
+//
+// 包含和排除不能混用；$slice 可以与任意一种模式一起使用，重命名属于包含模式。
+// 路径经过 Array 时作用于每个 Map 元素。
+
+// Projection 编译后的投影规则
+type Projection struct {
+	root      *projectionNode
+	exclusion bool
+	renames   []projectionRename
+}
+
+type projectionNode struct {
+	leaf     bool
+	slice    *projectionSlice
+	children map[string]*projectionNode
+}
+
+type projectionSlice struct {
+	skip  int
+	limit int
+	last  bool // 仅指定负数时取最后 limit 个
+}
+
+type projectionRename struct {
+	to   []string
+	from []string
+}
+
+// Project 按 spec 投影文档，返回新的 Map
+func Project(m Map, spec Map) (Map, error) {
+	p, err := CompileProjection(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.Apply(m)
+}
+
+// CompileProjection 编译投影规则，便于对多个文档重复使用
+func CompileProjection(spec Map) (*Projection, error) {
+	p := &Projection{root: &projectionNode{}}
+
+	hasInclude := false
+
+	for _, key := range sortedKeys(spec) {
+		segs, err := splitPath(key)
+		if err != nil {
+			return nil, err
+		}
+
+		switch v := spec[key].(type) {
+		case Bool:
+			if v {
+				hasInclude = true
+			} else {
+				p.exclusion = true
+			}
+			if err := p.add(segs, nil); err != nil {
+				return nil, err
+			}
+
+		case String:
+			if !strings.HasPrefix(string(v), "$") {
+				return nil, fmt.Errorf("field %v: rename source must start with $, got %v", key, v)
+			}
+			from, err := splitPath(string(v)[1:])
+			if err != nil {
+				return nil, fmt.Errorf("field %v: %w", key, err)
+			}
+			hasInclude = true
+			p.renames = append(p.renames, projectionRename{to: segs, from: from})
+
+		case Map:
+			operand, has := v["$slice"]
+			if !has || len(v) != 1 {
+				return nil, fmt.Errorf("field %v: unsupported projection %v", key, v)
+			}
+			slice, err := parseSlice(operand)
+			if err != nil {
+				return nil, fmt.Errorf("field %v: %w", key, err)
+			}
+			if err := p.add(segs, slice); err != nil {
+				return nil, err
+			}
+
+		default:
+			n, ok := toNumber(v)
+			if !ok {
+				return nil, fmt.Errorf("field %v: unsupported projection %v", key, v)
+			}
+			if compareNumber(n, number{kind: numberInt}) != 0 {
+				hasInclude = true
+			} else {
+				p.exclusion = true
+			}
+			if err := p.add(segs, nil); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if hasInclude && p.exclusion {
+		return nil, errors.New("Cannot mix inclusion and exclusion in projection")
+	}
+
+	return p, nil
+}
+
+// add 向规则树添加一条路径
+func (self *Projection) add(segs []string, slice *projectionSlice) error {
+	node := self.root
+
+	for i, seg := range segs {
+		if node.leaf {
+			return fmt.Errorf("Path collision: %v", strings.Join(segs, "."))
+		}
+
+		if node.children == nil {
+			node.children = make(map[string]*projectionNode)
+		}
+
+		child, has := node.children[seg]
+		if !has {
+			child = &projectionNode{}
+			node.children[seg] = child
+		} else if i == len(segs)-1 {
+			return fmt.Errorf("Path collision: %v", strings.Join(segs, "."))
+		}
+
+		node = child
+	}
+
+	node.leaf = true
+	node.slice = slice
+
+	return nil
+}
+
+// Apply 按规则投影文档，返回新的 Map，m 不会被修改。
+// 重命名的目标路径无法写入时（如经过非容器的值）返回错误
+func (self *Projection) Apply(m Map) (Map, error) {
+	var result Map
+
+	if self.exclusion || self.onlySlices(self.root) {
+		result = excludeMap(m, self.root)
+	} else {
+		result = includeMap(m, self.root)
+	}
+
+	for _, r := range self.renames {
+		if v, has := lookupPath(m, r.from); has {
+			if _, err := setIn(result, r.to, 0, Clone(v), false); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// onlySlices 判断规则树是否只包含 $slice（或为空），此时保留其他字段
+func (self *Projection) onlySlices(node *projectionNode) bool {
+	if len(self.renames) > 0 {
+		return false
+	}
+
+	if node.leaf {
+		return node.slice != nil
+	}
+
+	for _, child := range node.children {
+		if !self.onlySlices(child) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func includeMap(src Map, node *projectionNode) Map {
+	result := Map{}
+
+	for key, child := range node.children {
+		v, has := src[key]
+		if !has {
+			continue
+		}
+
+		if child.leaf {
+			result[key] = child.slice.apply(Clone(v))
+			continue
+		}
+
+		switch x := v.(type) {
+		case Map:
+			result[key] = includeMap(x, child)
+		case Array:
+			arr := Array{}
+			for _, item := range x {
+				if m, ok := item.(Map); ok {
+					arr = append(arr, includeMap(m, child))
+				}
+			}
+			result[key] = arr
+		}
+	}
+
+	return result
+}
+
+func excludeMap(src Map, node *projectionNode) Map {
+	result := make(Map, len(src))
+
+	for key, v := range src {
+		child, has := node.children[key]
+		if !has {
+			result[key] = Clone(v)
+			continue
+		}
+
+		if child.leaf {
+			if child.slice != nil {
+				result[key] = child.slice.apply(Clone(v))
+			}
+			continue
+		}
+
+		switch x := v.(type) {
+		case Map:
+			result[key] = excludeMap(x, child)
+		case Array:
+			arr := make(Array, 0, len(x))
+			for _, item := range x {
+				if m, ok := item.(Map); ok {
+					arr = append(arr, excludeMap(m, child))
+				} else {
+					arr = append(arr, Clone(item))
+				}
+			}
+			result[key] = arr
+		default:
+			result[key] = Clone(v)
+		}
+	}
+
+	return result
+}
+
+// parseSlice 解析 $slice：N 或 Array{skip, limit}
+func parseSlice(operand Value) (*projectionSlice, error) {
+	if arr, ok := operand.(Array); ok {
+		if len(arr) != 2 {
+			return nil, errors.New("$slice expects [skip, limit]")
+		}
+		skip, ok1 := sliceInt(arr[0])
+		limit, ok2 := sliceInt(arr[1])
+		if !ok1 || !ok2 || limit <= 0 {
+			return nil, errors.New("$slice expects integer skip and positive limit")
+		}
+		return &projectionSlice{skip: skip, limit: limit}, nil
+	}
+
+	n, ok := sliceInt(operand)
+	if !ok {
+		return nil, fmt.Errorf("$slice expects integer, got %v", dataTypeOf(operand))
+	}
+
+	if n < 0 {
+		// -MinInt 溢出，取最后 MaxInt 个与取全部相同
+		return &projectionSlice{limit: -max(n, -math.MaxInt), last: true}, nil
+	}
+
+	return &projectionSlice{limit: n}, nil
+}
+
+// sliceInt 将整数转换为 int，超出 int 范围的值取最接近的边界
+func sliceInt(v Value) (int, bool) {
+	n, ok := toNumber(v)
+	if !ok {
+		return 0, false
+	}
+
+	switch n.kind {
+	case numberInt:
+		return int(max(min(n.i, math.MaxInt), math.MinInt)), true
+	case numberUint:
+		return int(min(n.u, math.MaxInt)), true
+	default:
+		return 0, false
+	}
+}
+
+// apply 对 Array 取切片，其他类型原样返回
+func (self *projectionSlice) apply(v Value) Value {
+	arr, ok := v.(Array)
+	if self == nil || !ok {
+		return v
+	}
+
+	if self.last {
+		return arr[max(len(arr)-self.limit, 0):]
+	}
+
+	start := self.skip
+	if start < 0 {
+		start = max(len(arr)+start, 0)
+	}
+	start = min(start, len(arr))
+
+	// 先与剩余长度比较，避免 start+limit 溢出
+	return arr[start : start+min(self.limit, len(arr)-start)]
+}
@@ -0,0 +1,152 @@
+package nson
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func projectDoc() Map {
+	return Map{
+		"name":     String("order-1"),
+		"password": String("secret"),
+		"customer": Map{"name": String("ann"), "email": String("ann@example.com")},
+		"lines": Array{
+			Map{"sku": String("a"), "qty": I32(1), "price": F64(1.5)},
+			Map{"sku": String("b"), "qty": I32(2), "price": F64(2.5)},
+			Map{"sku": String("c"), "qty": I32(3), "price": F64(3.5)},
+		},
+		"history": Array{I32(1), I32(2), I32(3), I32(4), I32(5)},
+	}
+}
+
+func TestProjectInclude(t *testing.T) {
+	doc := projectDoc()
+
+	result, err := Project(doc, Map{"name": I32(1), "customer.name": Bool(true), "lines.sku": U8(1)})
+	if err != nil {
+		t.Fatalf("Project failed: %v", err)
+	}
+
+	want := Map{
+		"name":     String("order-1"),
+		"customer": Map{"name": String("ann")},
+		"lines":    Array{Map{"sku": String("a")}, Map{"sku": String("b")}, Map{"sku": String("c")}},
+	}
+	if !Equal(result, want) {
+		t.Errorf("Expected %v, got %v", want, result)
+	}
+
+	// 投影结果可以直接编码
+	if err := EncodeMap(result, new(bytes.Buffer)); err != nil {
+		t.Errorf("EncodeMap failed: %v", err)
+	}
+}
+
+func TestProjectExclude(t *testing.T) {
+	doc := projectDoc()
+
+	result, err := Project(doc, Map{"password": I32(0), "customer.email": Bool(false), "lines.price": I64(0)})
+	if err != nil {
+		t.Fatalf("Project failed: %v", err)
+	}
+
+	if _, has := result["password"]; has {
+		t.Error("Expected password to be excluded")
+	}
+	if _, has := result.GetPath("customer.email"); has {
+		t.Error("Expected customer.email to be excluded")
+	}
+	if _, has := result.GetPath("lines.0.price"); has {
+		t.Error("Expected lines.price to be excluded")
+	}
+	if v, _ := result.GetPath("lines.2.qty"); v != I32(3) {
+		t.Errorf("Expected lines.2.qty to survive, got %v", v)
+	}
+	if _, has := doc["password"]; !has {
+		t.Error("Project must not modify the original")
+	}
+}
+
+func TestProjectSlice(t *testing.T) {
+	doc := projectDoc()
+
+	tests := []struct {
+		spec Map
+		want Array
+	}{
+		{Map{"history": Map{"$slice": I32(2)}}, Array{I32(1), I32(2)}},
+		{Map{"history": Map{"$slice": I32(-2)}}, Array{I32(4), I32(5)}},
+		{Map{"history": Map{"$slice": Array{I32(1), I32(2)}}}, Array{I32(2), I32(3)}},
+		{Map{"history": Map{"$slice": Array{I32(-2), I32(5)}}}, Array{I32(4), I32(5)}},
+		{Map{"history": Map{"$slice": I32(10)}}, Array{I32(1), I32(2), I32(3), I32(4), I32(5)}},
+		// 很大的值不溢出
+		{Map{"history": Map{"$slice": Array{I32(1), I64(math.MaxInt64)}}}, Array{I32(2), I32(3), I32(4), I32(5)}},
+		{Map{"history": Map{"$slice": Array{I64(math.MinInt64), U64(math.MaxUint64)}}}, Array{I32(1), I32(2), I32(3), I32(4), I32(5)}},
+		{Map{"history": Map{"$slice": Array{U64(math.MaxUint64), I32(1)}}}, Array{}},
+		{Map{"history": Map{"$slice": U64(math.MaxUint64)}}, Array{I32(1), I32(2), I32(3), I32(4), I32(5)}},
+		{Map{"history": Map{"$slice": I64(math.MinInt64)}}, Array{I32(1), I32(2), I32(3), I32(4), I32(5)}},
+	}
+
+	for _, tt := range tests {
+		result, err := Project(doc, tt.spec)
+		if err != nil {
+			t.Fatalf("Project(%v) failed: %v", tt.spec, err)
+		}
+		if !Equal(result["history"], tt.want) {
+			t.Errorf("Project(%v): expected %v, got %v", tt.spec, tt.want, result["history"])
+		}
+		// 只有 $slice 时保留其他字段
+		if _, has := result["name"]; !has {
+			t.Errorf("Project(%v): expected other fields to be kept", tt.spec)
+		}
+	}
+
+	result, err := Project(doc, Map{"name": I32(1), "history": Map{"$slice": I32(1)}})
+	if err != nil {
+		t.Fatalf("Project failed: %v", err)
+	}
+	if !Equal(result, Map{"name": String("order-1"), "history": Array{I32(1)}}) {
+		t.Errorf("Unexpected result: %v", result)
+	}
+}
+
+func TestProjectRename(t *testing.T) {
+	doc := projectDoc()
+
+	result, err := Project(doc, Map{"name": I32(1), "buyer.email": String("$customer.email")})
+	if err != nil {
+		t.Fatalf("Project failed: %v", err)
+	}
+
+	want := Map{"name": String("order-1"), "buyer": Map{"email": String("ann@example.com")}}
+	if !Equal(result, want) {
+		t.Errorf("Expected %v, got %v", want, result)
+	}
+
+	// 目标路径经过非容器的值
+	if _, err := Project(doc, Map{"name": I32(1), "name.first": String("$customer.name")}); err == nil {
+		t.Error("Expected error for rename through String")
+	}
+	if _, err := Project(doc, Map{"lines": I32(1), "lines.sku": String("$name")}); err == nil {
+		t.Error("Expected error for rename through Array")
+	}
+}
+
+func TestProjectErrors(t *testing.T) {
+	specs := []Map{
+		{"a": I32(1), "b": I32(0)},
+		{"a": I32(0), "c": String("$b")},
+		{"a": I32(1), "a.b": I32(1)},
+		{"a": String("b")},
+		{"a": Map{"$slice": String("x")}},
+		{"a": Map{"$elemMatch": Map{}}},
+		{"a": Binary{}},
+	}
+
+	for _, spec := range specs {
+		if _, err := Project(Map{}, spec); err == nil {
+			t.Errorf("Expected error for %v", spec)
+		}
+	}
+}
@@ -0,0 +1,559 @@
+package nson
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// 查询过滤器使用 Map 表示，语法参考 MongoDB：
+//
+//	Map{
+//		"status": String("active"),                    // 相等
+//		"size":   Map{"$gt": I32(10), "$lte": U8(100)}, // 比较，跨数值宽度
+//		"tags":   Map{"$in": Array{String("a")}},      // 数组字段匹配任意元素
+//		"meta.owner": Map{"$exists": Bool(true)},      // 点分路径
+//		"$or": Array{Map{...}, Map{...}},
+//	}
+//
+// 支持的字段操作符：$eq $ne $gt $gte $lt $lte $in $nin $exists $type
+// $regex（可配合 $options）$elemMatch $size $all $not；
+// 顶层逻辑操作符：$and $or $nor $not；
+// 顶层 $expr 使用表达式（见 Eval）比较同一文档的多个字段，如
+// Map{"$expr": Map{"$gt": Array{String("$spent"), String("$budget")}}}。
+//
+// 路径经过 Array 时，数字段表示下标，其他段作用于每个 Map 元素；
+// 字段值是 Array 时，除 $size、$elemMatch 外的操作符对数组本身或任一元素成立即匹配。
+
+// Matcher 判断文档是否满足条件
+type Matcher interface {
+	Match(doc Map) bool
+}
+
+type docPredicate func(doc Map) bool
+
+func (self docPredicate) Match(doc Map) bool {
+	return self(doc)
+}
+
+// Compile 编译查询过滤器
+func Compile(filter Map) (Matcher, error) {
+	match, err := compileFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return match, nil
+}
+
+// MustCompile 与 Compile 相同，但出错时 panic
+func MustCompile(filter Map) Matcher {
+	m, err := Compile(filter)
+	if err != nil {
+		panic(err)
+	}
+
+	return m
+}
+
+// valuesPredicate 作用于路径上找到的所有值，路径不存在时 values 为空
+type valuesPredicate func(values []Value) bool
+
+func compileFilter(filter Map) (docPredicate, error) {
+	preds := make([]docPredicate, 0, len(filter))
+
+	for _, key := range sortedKeys(filter) {
+		operand := filter[key]
+
+		if strings.HasPrefix(key, "$") {
+			pred, err := compileLogical(key, operand)
+			if err != nil {
+				return nil, err
+			}
+			preds = append(preds, pred)
+			continue
+		}
+
+		segs, err := splitPath(key)
+		if err != nil {
+			return nil, err
+		}
+
+		vpred, err := compileField(operand)
+		if err != nil {
+			return nil, fmt.Errorf("field %v: %w", key, err)
+		}
+
+		preds = append(preds, func(doc Map) bool {
+			return vpred(resolveValues(doc, segs))
+		})
+	}
+
+	return func(doc Map) bool {
+		for _, pred := range preds {
+			if !pred(doc) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func compileLogical(op string, operand Value) (docPredicate, error) {
+	if op == "$expr" {
+		expr, err := compileExpr(operand)
+		if err != nil {
+			return nil, fmt.Errorf("$expr: %w", err)
+		}
+		// 计算出错的文档视为不匹配
+		return func(doc Map) bool {
+			v, err := expr(doc)
+			return err == nil && Truthy(v)
+		}, nil
+	}
+
+	if op == "$not" {
+		filter, ok := operand.(Map)
+		if !ok {
+			return nil, fmt.Errorf("$not expects Map, got %v", dataTypeOf(operand))
+		}
+		pred, err := compileFilter(filter)
+		if err != nil {
+			return nil, err
+		}
+		return func(doc Map) bool { return !pred(doc) }, nil
+	}
+
+	arr, ok := operand.(Array)
+	if !ok {
+		return nil, fmt.Errorf("%v expects Array, got %v", op, dataTypeOf(operand))
+	}
+	if len(arr) == 0 {
+		return nil, fmt.Errorf("%v expects a non-empty Array", op)
+	}
+
+	preds := make([]docPredicate, 0, len(arr))
+	for i, item := range arr {
+		filter, ok := item.(Map)
+		if !ok {
+			return nil, fmt.Errorf("%v index %d: expected Map, got %v", op, i, dataTypeOf(item))
+		}
+		pred, err := compileFilter(filter)
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, pred)
+	}
+
+	switch op {
+	case "$and":
+		return func(doc Map) bool {
+			for _, pred := range preds {
+				if !pred(doc) {
+					return false
+				}
+			}
+			return true
+		}, nil
+	case "$or", "$nor":
+		want := op == "$or"
+		return func(doc Map) bool {
+			for _, pred := range preds {
+				if pred(doc) {
+					return want
+				}
+			}
+			return !want
+		}, nil
+	default:
+		return nil, fmt.Errorf("Unknown operator: %v", op)
+	}
+}
+
+// compileField 编译字段条件：操作符 Map 或者相等比较的值，Map 不能同时有操作符和普通的键
+func compileField(operand Value) (valuesPredicate, error) {
+	if ops, ok := operand.(Map); ok {
+		if isOperatorMap(ops) {
+			return compileOperators(ops)
+		}
+		for k := range ops {
+			if strings.HasPrefix(k, "$") {
+				return nil, fmt.Errorf("Cannot mix operator %v with fields", k)
+			}
+		}
+	}
+
+	return compileEq(operand), nil
+}
+
+// isOperatorMap 判断 Map 是否所有键都以 "$" 开头
+func isOperatorMap(m Map) bool {
+	if len(m) == 0 {
+		return false
+	}
+
+	for k := range m {
+		if !strings.HasPrefix(k, "$") {
+			return false
+		}
+	}
+
+	return true
+}
+
+func compileOperators(ops Map) (valuesPredicate, error) {
+	preds := make([]valuesPredicate, 0, len(ops))
+
+	for _, op := range sortedKeys(ops) {
+		if op == "$options" {
+			if _, has := ops["$regex"]; !has {
+				return nil, errors.New("$options without $regex")
+			}
+			continue
+		}
+
+		pred, err := compileOperator(op, ops[op], ops)
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, pred)
+	}
+
+	return func(values []Value) bool {
+		for _, pred := range preds {
+			if !pred(values) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func compileOperator(op string, operand Value, ops Map) (valuesPredicate, error) {
+	switch op {
+	case "$eq":
+		return compileEq(operand), nil
+
+	case "$ne":
+		eq := compileEq(operand)
+		return func(values []Value) bool { return !eq(values) }, nil
+
+	case "$gt", "$gte", "$lt", "$lte":
+		return compileCompare(op, operand), nil
+
+	case "$in", "$nin":
+		arr, ok := operand.(Array)
+		if !ok {
+			return nil, fmt.Errorf("%v expects Array, got %v", op, dataTypeOf(operand))
+		}
+		eqs := make([]valuesPredicate, 0, len(arr))
+		for _, item := range arr {
+			eqs = append(eqs, compileEq(item))
+		}
+		want := op == "$in"
+		return func(values []Value) bool {
+			for _, eq := range eqs {
+				if eq(values) {
+					return want
+				}
+			}
+			return !want
+		}, nil
+
+	case "$all":
+		arr, ok := operand.(Array)
+		if !ok {
+			return nil, fmt.Errorf("$all expects Array, got %v", dataTypeOf(operand))
+		}
+		eqs := make([]valuesPredicate, 0, len(arr))
+		for _, item := range arr {
+			eqs = append(eqs, compileEq(item))
+		}
+		return func(values []Value) bool {
+			if len(eqs) == 0 {
+				return false
+			}
+			for _, eq := range eqs {
+				if !eq(values) {
+					return false
+				}
+			}
+			return true
+		}, nil
+
+	case "$exists":
+		want, ok := operand.(Bool)
+		if !ok {
+			return nil, fmt.Errorf("$exists expects Bool, got %v", dataTypeOf(operand))
+		}
+		return func(values []Value) bool {
+			return (len(values) > 0) == bool(want)
+		}, nil
+
+	case "$type":
+		match, err := compileType(operand)
+		if err != nil {
+			return nil, err
+		}
+		return anyValue(match), nil
+
+	case "$size":
+		n, ok := toNumber(operand)
+		if !ok {
+			return nil, fmt.Errorf("$size expects number, got %v", dataTypeOf(operand))
+		}
+		return func(values []Value) bool {
+			for _, v := range values {
+				if arr, ok := v.(Array); ok && compareNumber(number{kind: numberInt, i: int64(len(arr))}, n) == 0 {
+					return true
+				}
+			}
+			return false
+		}, nil
+
+	case "$regex":
+		re, err := compileRegex(operand, ops["$options"])
+		if err != nil {
+			return nil, err
+		}
+		return anyValue(func(v Value) bool {
+			s, ok := v.(String)
+			return ok && re.MatchString(string(s))
+		}), nil
+
+	case "$elemMatch":
+		return compileElemMatch(operand)
+
+	case "$not":
+		cond, ok := operand.(Map)
+		if !ok {
+			return nil, fmt.Errorf("$not expects Map, got %v", dataTypeOf(operand))
+		}
+		pred, err := compileOperators(cond)
+		if err != nil {
+			return nil, err
+		}
+		return func(values []Value) bool { return !pred(values) }, nil
+
+	default:
+		return nil, fmt.Errorf("Unknown operator: %v", op)
+	}
+}
+
+// compileEq 相等比较，数值跨宽度比较；Null 同时匹配不存在的字段
+func compileEq(operand Value) valuesPredicate {
+	if _, ok := operand.(Null); ok {
+		return func(values []Value) bool {
+			if len(values) == 0 {
+				return true
+			}
+			return anyValue(func(v Value) bool { return EqualNumeric(v, operand) })(values)
+		}
+	}
+
+	return anyValue(func(v Value) bool { return EqualNumeric(v, operand) })
+}
+
+// compileCompare 大小比较，只比较 Compare 中同一类的值，如数值只与数值比较
+func compileCompare(op string, operand Value) valuesPredicate {
+	rank := typeRank(operand)
+
+	return anyValue(func(v Value) bool {
+		if typeRank(v) != rank {
+			return false
+		}
+
+		c := Compare(v, operand)
+		switch op {
+		case "$gt":
+			return c > 0
+		case "$gte":
+			return c >= 0
+		case "$lt":
+			return c < 0
+		default:
+			return c <= 0
+		}
+	})
+}
+
+// compileType 解析 $type：DataType 的数值、类型名称、"number" 或它们组成的 Array
+func compileType(operand Value) (func(Value) bool, error) {
+	if arr, ok := operand.(Array); ok {
+		matches := make([]func(Value) bool, 0, len(arr))
+		for _, item := range arr {
+			match, err := compileType(item)
+			if err != nil {
+				return nil, err
+			}
+			matches = append(matches, match)
+		}
+		return func(v Value) bool {
+			for _, match := range matches {
+				if match(v) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	}
+
+	if s, ok := operand.(String); ok {
+		if strings.EqualFold(string(s), "number") {
+			return func(v Value) bool { return v.DataType().IsNumeric() }, nil
+		}
+		dt, ok := ParseDataType(string(s))
+		if !ok {
+			return nil, fmt.Errorf("Unknown type: %v", s)
+		}
+		return func(v Value) bool { return v.DataType() == dt }, nil
+	}
+
+	if n, ok := toNumber(operand); ok && n.kind != numberFloat {
+		// 先检查范围，避免转换为 DataType 时截断，如 0x113 变成 I32
+		code := n.u
+		if n.kind == numberInt {
+			if n.i < 0 {
+				return nil, fmt.Errorf("Unknown type: %v", operand)
+			}
+			code = uint64(n.i)
+		}
+		dt := DataType(code)
+		if _, known := dataTypeNames[dt]; !known || code > math.MaxUint8 {
+			return nil, fmt.Errorf("Unknown type: %v", operand)
+		}
+		return func(v Value) bool { return v.DataType() == dt }, nil
+	}
+
+	return nil, fmt.Errorf("$type expects String, integer or Array, got %v", dataTypeOf(operand))
+}
+
+func compileRegex(pattern Value, options Value) (*regexp.Regexp, error) {
+	s, ok := pattern.(String)
+	if !ok {
+		return nil, fmt.Errorf("$regex expects String, got %v", dataTypeOf(pattern))
+	}
+
+	expr := string(s)
+
+	if options != nil {
+		opts, ok := options.(String)
+		if !ok {
+			return nil, fmt.Errorf("$options expects String, got %v", dataTypeOf(options))
+		}
+		for _, c := range opts {
+			if !strings.ContainsRune("imsU", c) {
+				return nil, fmt.Errorf("Unsupported regex option: %c", c)
+			}
+		}
+		if opts != "" {
+			expr = "(?" + string(opts) + ")" + expr
+		}
+	}
+
+	return regexp.Compile(expr)
+}
+
+// compileElemMatch 匹配数组中至少一个元素满足条件的字段。
+// 条件为操作符 Map 时作用于元素本身，否则作为过滤器作用于 Map 元素。
+func compileElemMatch(operand Value) (valuesPredicate, error) {
+	cond, ok := operand.(Map)
+	if !ok {
+		return nil, fmt.Errorf("$elemMatch expects Map, got %v", dataTypeOf(operand))
+	}
+
+	var match func(Value) bool
+
+	if isOperatorMap(cond) {
+		pred, err := compileOperators(cond)
+		if err != nil {
+			return nil, err
+		}
+		match = func(v Value) bool { return pred([]Value{v}) }
+	} else {
+		pred, err := compileFilter(cond)
+		if err != nil {
+			return nil, err
+		}
+		match = func(v Value) bool {
+			m, ok := v.(Map)
+			return ok && pred(m)
+		}
+	}
+
+	return func(values []Value) bool {
+		for _, v := range values {
+			if arr, ok := v.(Array); ok {
+				for _, item := range arr {
+					if match(item) {
+						return true
+					}
+				}
+			}
+		}
+		return false
+	}, nil
+}
+
+// anyValue 对任一值或数组值的任一元素成立即匹配
+func anyValue(match func(Value) bool) valuesPredicate {
+	return func(values []Value) bool {
+		for _, v := range values {
+			if match(v) {
+				return true
+			}
+			if arr, ok := v.(Array); ok {
+				for _, item := range arr {
+					if match(item) {
+						return true
+					}
+				}
+			}
+		}
+		return false
+	}
+}
+
+// resolveValues 查找路径上的所有值，经过 Array 时展开 Map 元素
+func resolveValues(doc Map, segs []string) []Value {
+	var values []Value
+	collectValues(doc, segs, &values)
+	return values
+}
+
+func collectValues(value Value, segs []string, out *[]Value) {
+	if len(segs) == 0 {
+		*out = append(*out, value)
+		return
+	}
+
+	switch v := value.(type) {
+	case Map:
+		if next, has := v[segs[0]]; has {
+			collectValues(next, segs[1:], out)
+		}
+	case Array:
+		if i, ok := parseIndex(segs[0]); ok {
+			if i < len(v) {
+				collectValues(v[i], segs[1:], out)
+			}
+			return
+		}
+		for _, item := range v {
+			if m, ok := item.(Map); ok {
+				collectValues(m, segs, out)
+			}
+		}
+	}
+}
+
+// dataTypeOf 返回值的类型名称，用于错误信息
+func dataTypeOf(v Value) string {
+	if v == nil {
+		return "nil"
+	}
+
+	return v.DataType().String()
+}
@@ -0,0 +1,138 @@
+package nson
+
+import (
+	"testing"
+)
+
+func queryDocs() []Map {
+	return []Map{
+		{
+			"name":   String("alpha"),
+			"size":   I32(5),
+			"status": String("active"),
+			"tags":   Array{String("red"), String("blue")},
+			"meta":   Map{"owner": String("ann")},
+			"items":  Array{Map{"sku": String("a1"), "qty": U8(2)}, Map{"sku": String("b2"), "qty": U8(9)}},
+		},
+		{
+			"name":   String("beta"),
+			"size":   I64(50),
+			"status": String("inactive"),
+			"tags":   Array{String("green")},
+			"items":  Array{Map{"sku": String("c3"), "qty": U8(1)}},
+		},
+		{
+			"name":   String("Gamma"),
+			"size":   F64(500.5),
+			"status": Null{},
+			"meta":   Map{"owner": String("bob")},
+		},
+	}
+}
+
+func matchNames(t *testing.T, filter Map) []string {
+	t.Helper()
+
+	matcher, err := Compile(filter)
+	if err != nil {
+		t.Fatalf("Compile(%v) failed: %v", filter, err)
+	}
+
+	var names []string
+	for _, doc := range queryDocs() {
+		if matcher.Match(doc) {
+			name, _ := doc.GetString("name")
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+func TestQueryMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Map
+		want   []string
+	}{
+		{"equality", Map{"status": String("active")}, []string{"alpha"}},
+		{"numeric across widths", Map{"size": U8(5)}, []string{"alpha"}},
+		{"gt", Map{"size": Map{"$gt": I8(5)}}, []string{"beta", "Gamma"}},
+		{"range", Map{"size": Map{"$gte": U16(5), "$lt": F32(100)}}, []string{"alpha", "beta"}},
+		{"ne", Map{"status": Map{"$ne": String("active")}}, []string{"beta", "Gamma"}},
+		{"in", Map{"tags": Map{"$in": Array{String("green"), String("blue")}}}, []string{"alpha", "beta"}},
+		{"nin", Map{"tags": Map{"$nin": Array{String("red")}}}, []string{"beta", "Gamma"}},
+		{"array contains", Map{"tags": String("red")}, []string{"alpha"}},
+		{"exists", Map{"meta": Map{"$exists": Bool(true)}}, []string{"alpha", "Gamma"}},
+		{"not exists", Map{"meta": Map{"$exists": Bool(false)}}, []string{"beta"}},
+		{"null matches missing", Map{"meta.owner": Null{}}, []string{"beta"}},
+		{"type name", Map{"size": Map{"$type": String("f64")}}, []string{"Gamma"}},
+		{"type code", Map{"size": Map{"$type": U8(DataTypeI64)}}, []string{"beta"}},
+		{"type number", Map{"size": Map{"$type": String("number")}}, []string{"alpha", "beta", "Gamma"}},
+		{"type array", Map{"status": Map{"$type": Array{String("null"), String("bool")}}}, []string{"Gamma"}},
+		{"dotted", Map{"meta.owner": String("bob")}, []string{"Gamma"}},
+		{"dotted through array", Map{"items.sku": String("c3")}, []string{"beta"}},
+		{"array index", Map{"items.1.qty": Map{"$gt": I32(5)}}, []string{"alpha"}},
+		{"regex", Map{"name": Map{"$regex": String("^g"), "$options": String("i")}}, []string{"Gamma"}},
+		{"elemMatch", Map{"items": Map{"$elemMatch": Map{"sku": String("a1"), "qty": Map{"$gte": I32(2)}}}}, []string{"alpha"}},
+		{"elemMatch operators", Map{"items.qty": Map{"$elemMatch": Map{"$gt": I32(5)}}}, nil},
+		{"size", Map{"tags": Map{"$size": I32(2)}}, []string{"alpha"}},
+		{"all", Map{"tags": Map{"$all": Array{String("red"), String("blue")}}}, []string{"alpha"}},
+		{"not", Map{"size": Map{"$not": Map{"$gt": I32(10)}}}, []string{"alpha"}},
+		{"and", Map{"$and": Array{Map{"size": Map{"$gt": I32(1)}}, Map{"meta": Map{"$exists": Bool(true)}}}}, []string{"alpha", "Gamma"}},
+		{"or", Map{"$or": Array{Map{"name": String("beta")}, Map{"size": Map{"$gt": I32(100)}}}}, []string{"beta", "Gamma"}},
+		{"nor", Map{"$nor": Array{Map{"name": String("beta")}, Map{"size": Map{"$gt": I32(100)}}}}, []string{"alpha"}},
+		{"top level not", Map{"$not": Map{"name": String("alpha")}}, []string{"beta", "Gamma"}},
+		{"map equality", Map{"meta": Map{"owner": String("ann")}}, []string{"alpha"}},
+		{"type bracketing", Map{"name": Map{"$gt": I32(0)}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := matchNames(t, tt.filter)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Expected %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestQueryCompileErrors(t *testing.T) {
+	filters := []Map{
+		{"a": Map{"$unknown": I32(1)}},
+		{"a": Map{"$in": I32(1)}},
+		{"a": Map{"$exists": I32(1)}},
+		{"a": Map{"$type": String("nope")}},
+		{"a": Map{"$regex": String("(")}},
+		{"a": Map{"$options": String("i")}},
+		{"$or": Map{}},
+		{"$and": Array{I32(1)}},
+		{"$and": Array{}},
+		{"$or": Array{}},
+		{"$nor": Array{}},
+		{"a..b": I32(1)},
+		{"a": Map{"$type": I32(0x100 + int32(DataTypeI32))}},
+		{"a": Map{"$type": U64(1<<40 + uint64(DataTypeI32))}},
+		{"a": Map{"$type": I32(-1)}},
+		{"a": Map{"$gt": I32(1), "b": I32(2)}},
+	}
+
+	for _, filter := range filters {
+		if _, err := Compile(filter); err == nil {
+			t.Errorf("Expected error for %v", filter)
+		}
+	}
+
+	m, err := Compile(Map{"a": Map{"$type": U8(DataTypeI32)}, "b": Map{"c": I32(1)}})
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	if !m.Match(Map{"a": I32(1), "b": Map{"c": I32(1)}}) || m.Match(Map{"a": I64(1), "b": Map{"c": I32(1)}}) {
+		t.Error("Unexpected match result for numeric $type")
+	}
+}
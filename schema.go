@@ -0,0 +1,447 @@
+package nson
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Schema 描述一个值的约束，通常根节点描述 Map：
+//
+//	schema := &Schema{
+//		Types: []DataType{DataTypeMAP},
+//		Fields: map[string]*Schema{
+//			"id":   {Types: []DataType{DataTypeID}, Required: true},
+//			"port": {Types: []DataType{DataTypeU16}, Min: U16(1)},
+//			"name": {Types: []DataType{DataTypeSTRING}, MaxLength: Limit(64), Pattern: "^[a-z]+$"},
+//			"tags": {Types: []DataType{DataTypeARRAY}, Items: &Schema{Types: []DataType{DataTypeSTRING}}},
+//		},
+//	}
+//
+// 各项约束只作用于对应类型的值，如 MinLength 只检查 String 和 Binary。
+// Schema 可以通过 Map 和 SchemaFromMap 与 NSON 表示相互转换。
+type Schema struct {
+	Description string
+	Types       []DataType // 允许的类型，为空表示任意类型；可为 Null 的字段需要包含 DataTypeNULL
+	Required    bool       // 作为 Map 字段时必须存在
+
+	Fields map[string]*Schema // Map 的字段
+	Values *Schema            // Map 中 Fields 以外字段的值
+	Closed bool               // Map 不允许出现 Fields 以外的字段
+
+	Items    *Schema // Array 的元素
+	MinItems *int
+	MaxItems *int
+
+	Enum []Value // 允许的值，按 EqualNumeric 比较
+	Min  Value   // 最小值（包含），与值的类型同类时才比较，数值跨宽度比较
+	Max  Value   // 最大值（包含）
+
+	MinLength *int // String 的字符数或 Binary 的字节数
+	MaxLength *int
+	Pattern   string // String 需要匹配的正则表达式
+}
+
+// Limit 返回 n 的指针，便于设置 Schema 的长度约束
+func Limit(n int) *int {
+	return &n
+}
+
+// ValidationError 描述一处校验失败，Path 为点分路径，根节点为空字符串
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (self ValidationError) Error() string {
+	if self.Path == "" {
+		return self.Message
+	}
+
+	return self.Path + ": " + self.Message
+}
+
+// Validate 校验 m，返回全部校验失败，通过时返回 nil
+func (self *Schema) Validate(m Map) []ValidationError {
+	return self.ValidateValue(m)
+}
+
+// ValidateValue 校验任意值
+func (self *Schema) ValidateValue(v Value) []ValidationError {
+	var errs []ValidationError
+	self.validate(&errs, "", v)
+	return errs
+}
+
+func (self *Schema) validate(errs *[]ValidationError, path string, v Value) {
+	report := func(format string, args ...any) {
+		*errs = append(*errs, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// Go 的 nil 不是合法的 Value，与 Null 不同
+	if v == nil {
+		report("invalid nil value")
+		return
+	}
+
+	dt := v.DataType()
+
+	if len(self.Types) > 0 && !slices.Contains(self.Types, dt) {
+		report("expected %v, got %v", typeList(self.Types), dt)
+		return
+	}
+
+	if len(self.Enum) > 0 && !slices.ContainsFunc(self.Enum, func(e Value) bool { return EqualNumeric(e, v) }) {
+		report("value %v is not one of %v", v, Array(self.Enum))
+	}
+
+	if self.Min != nil && typeRank(self.Min) == typeRank(v) && Compare(v, self.Min) < 0 {
+		report("value %v is less than minimum %v", v, self.Min)
+	}
+
+	if self.Max != nil && typeRank(self.Max) == typeRank(v) && Compare(v, self.Max) > 0 {
+		report("value %v is greater than maximum %v", v, self.Max)
+	}
+
+	switch x := v.(type) {
+	case String:
+		self.validateLength(report, utf8.RuneCountInString(string(x)))
+
+		if self.Pattern != "" {
+			re, err := compilePattern(self.Pattern)
+			if err != nil {
+				report("invalid pattern %q: %v", self.Pattern, err)
+			} else if !re.MatchString(string(x)) {
+				report("value %q does not match pattern %q", string(x), self.Pattern)
+			}
+		}
+
+	case Binary:
+		self.validateLength(report, len(x))
+
+	case Array:
+		if self.MinItems != nil && len(x) < *self.MinItems {
+			report("expected at least %d items, got %d", *self.MinItems, len(x))
+		}
+		if self.MaxItems != nil && len(x) > *self.MaxItems {
+			report("expected at most %d items, got %d", *self.MaxItems, len(x))
+		}
+		if self.Items != nil {
+			for i, item := range x {
+				self.Items.validate(errs, childPath(path, fmt.Sprint(i)), item)
+			}
+		}
+
+	case Map:
+		for _, name := range sortedSchemaFields(self.Fields) {
+			field := self.Fields[name]
+			value, has := x[name]
+			if !has {
+				if field.Required {
+					*errs = append(*errs, ValidationError{Path: childPath(path, name), Message: "required field is missing"})
+				}
+				continue
+			}
+			field.validate(errs, childPath(path, name), value)
+		}
+
+		if self.Closed || self.Values != nil {
+			for _, name := range sortedKeys(x) {
+				if _, has := self.Fields[name]; has {
+					continue
+				}
+				if self.Closed {
+					*errs = append(*errs, ValidationError{Path: childPath(path, name), Message: "unknown field"})
+				} else {
+					self.Values.validate(errs, childPath(path, name), x[name])
+				}
+			}
+		}
+	}
+}
+
+func (self *Schema) validateLength(report func(format string, args ...any), n int) {
+	if self.MinLength != nil && n < *self.MinLength {
+		report("expected length at least %d, got %d", *self.MinLength, n)
+	}
+
+	if self.MaxLength != nil && n > *self.MaxLength {
+		report("expected length at most %d, got %d", *self.MaxLength, n)
+	}
+}
+
+func childPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
+
+func typeList(types []DataType) string {
+	names := make([]string, len(types))
+	for i, dt := range types {
+		names[i] = dt.String()
+	}
+
+	return strings.Join(names, " or ")
+}
+
+func sortedSchemaFields(fields map[string]*Schema) []string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	return keys
+}
+
+var patternCache sync.Map
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patternCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	patternCache.Store(pattern, re)
+	return re, nil
+}
+
+// Map 将 Schema 表示为 NSON 值：
+//
+//	{
+//		"description": String,
+//		"type":        String 或 Array{String...}，类型名称如 "I32"、"Map"
+//		"required":    Bool,
+//		"fields":      Map{name: schema},
+//		"values":      schema,
+//		"closed":      Bool,
+//		"items":       schema,
+//		"minItems":    I64, "maxItems": I64,
+//		"enum":        Array,
+//		"min":         Value, "max": Value,
+//		"minLength":   I64, "maxLength": I64,
+//		"pattern":     String,
+//	}
+//
+// 未设置的约束不出现。
+func (self *Schema) Map() Map {
+	m := Map{}
+
+	if self.Description != "" {
+		m["description"] = String(self.Description)
+	}
+
+	switch len(self.Types) {
+	case 0:
+	case 1:
+		m["type"] = String(self.Types[0].String())
+	default:
+		types := make(Array, len(self.Types))
+		for i, dt := range self.Types {
+			types[i] = String(dt.String())
+		}
+		m["type"] = types
+	}
+
+	if self.Required {
+		m["required"] = Bool(true)
+	}
+
+	if self.Fields != nil {
+		fields := make(Map, len(self.Fields))
+		for name, field := range self.Fields {
+			fields[name] = field.Map()
+		}
+		m["fields"] = fields
+	}
+
+	if self.Values != nil {
+		m["values"] = self.Values.Map()
+	}
+
+	if self.Closed {
+		m["closed"] = Bool(true)
+	}
+
+	if self.Items != nil {
+		m["items"] = self.Items.Map()
+	}
+
+	setSchemaInt(m, "minItems", self.MinItems)
+	setSchemaInt(m, "maxItems", self.MaxItems)
+
+	if self.Enum != nil {
+		m["enum"] = Clone(Array(self.Enum))
+	}
+
+	if self.Min != nil {
+		m["min"] = Clone(self.Min)
+	}
+
+	if self.Max != nil {
+		m["max"] = Clone(self.Max)
+	}
+
+	setSchemaInt(m, "minLength", self.MinLength)
+	setSchemaInt(m, "maxLength", self.MaxLength)
+
+	if self.Pattern != "" {
+		m["pattern"] = String(self.Pattern)
+	}
+
+	return m
+}
+
+func setSchemaInt(m Map, key string, n *int) {
+	if n != nil {
+		m[key] = I64(*n)
+	}
+}
+
+// SchemaFromMap 从 NSON 表示解析 Schema，格式见 Schema.Map
+func SchemaFromMap(m Map) (*Schema, error) {
+	return schemaFromMap(m, "")
+}
+
+func schemaFromMap(m Map, path string) (*Schema, error) {
+	fail := func(format string, args ...any) (*Schema, error) {
+		msg := fmt.Sprintf(format, args...)
+		if path != "" {
+			msg = path + ": " + msg
+		}
+		return nil, fmt.Errorf("Invalid schema, %v", msg)
+	}
+
+	schema := &Schema{}
+
+	for _, key := range sortedKeys(m) {
+		value := m[key]
+		if value == nil {
+			return fail("%v: invalid nil value", key)
+		}
+
+		switch key {
+		case "description", "pattern":
+			s, ok := value.(String)
+			if !ok {
+				return fail("%v expects String, got %v", key, value.DataType())
+			}
+			if key == "description" {
+				schema.Description = string(s)
+			} else {
+				if _, err := compilePattern(string(s)); err != nil {
+					return fail("pattern: %v", err)
+				}
+				schema.Pattern = string(s)
+			}
+
+		case "type":
+			names, ok := value.(Array)
+			if !ok {
+				names = Array{value}
+			}
+			for _, name := range names {
+				s, ok := name.(String)
+				if !ok {
+					return fail("type expects String or Array of String, got %v", dataTypeOf(name))
+				}
+				dt, ok := ParseDataType(string(s))
+				if !ok {
+					return fail("unknown type %q", string(s))
+				}
+				schema.Types = append(schema.Types, dt)
+			}
+
+		case "required", "closed":
+			b, ok := value.(Bool)
+			if !ok {
+				return fail("%v expects Bool, got %v", key, value.DataType())
+			}
+			if key == "required" {
+				schema.Required = bool(b)
+			} else {
+				schema.Closed = bool(b)
+			}
+
+		case "fields":
+			fields, ok := value.(Map)
+			if !ok {
+				return fail("fields expects Map, got %v", value.DataType())
+			}
+			schema.Fields = make(map[string]*Schema, len(fields))
+			for _, name := range sortedKeys(fields) {
+				fm, ok := fields[name].(Map)
+				if !ok {
+					return fail("field %v expects Map, got %v", name, dataTypeOf(fields[name]))
+				}
+				field, err := schemaFromMap(fm, childPath(path, name))
+				if err != nil {
+					return nil, err
+				}
+				schema.Fields[name] = field
+			}
+
+		case "items", "values":
+			sm, ok := value.(Map)
+			if !ok {
+				return fail("%v expects Map, got %v", key, value.DataType())
+			}
+			sub, err := schemaFromMap(sm, childPath(path, key))
+			if err != nil {
+				return nil, err
+			}
+			if key == "items" {
+				schema.Items = sub
+			} else {
+				schema.Values = sub
+			}
+
+		case "minItems", "maxItems", "minLength", "maxLength":
+			n, ok := sliceInt(value)
+			if !ok || n < 0 {
+				return fail("%v expects non-negative integer, got %v", key, value)
+			}
+			switch key {
+			case "minItems":
+				schema.MinItems = &n
+			case "maxItems":
+				schema.MaxItems = &n
+			case "minLength":
+				schema.MinLength = &n
+			case "maxLength":
+				schema.MaxLength = &n
+			}
+
+		case "enum":
+			arr, ok := value.(Array)
+			if !ok {
+				return fail("enum expects Array, got %v", value.DataType())
+			}
+			if i := slices.IndexFunc(arr, func(v Value) bool { return v == nil }); i >= 0 {
+				return fail("enum.%d: invalid nil value", i)
+			}
+			schema.Enum = Clone(arr).(Array)
+
+		case "min":
+			schema.Min = Clone(value)
+
+		case "max":
+			schema.Max = Clone(value)
+
+		default:
+			return fail("unknown keyword %v", key)
+		}
+	}
+
+	return schema, nil
+}
@@ -0,0 +1,733 @@
+package nson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+)
+
+// JSONSchemaDraft JSONSchema 输出的 "$schema"
+const JSONSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// idPattern Id 在 JSON Schema 中的格式
+const idPattern = "^[0-9a-f]{24}$"
+
+// JSONSchema 将 Schema 导出为 JSON Schema，便于非 Go 的对端校验同样的约束。
+//
+// 整数类型输出为 "integer"，并以类型的取值范围作为默认的 minimum 和 maximum；
+// Timestamp 为毫秒数；Id 为 24 位十六进制字符串；Binary 为十六进制字符串。
+// 每个节点带有 "x-nson-type" 记录原始的类型名称。
+// JSON 不能表示 NaN 和 ±Inf，Min、Max 或 Enum 中有这些值时返回错误。
+func (self *Schema) JSONSchema() ([]byte, error) {
+	if err := self.checkJSON(""); err != nil {
+		return nil, err
+	}
+
+	doc := self.jsonSchema()
+	doc["$schema"] = JSONSchemaDraft
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func (self *Schema) jsonSchema() map[string]any {
+	doc := map[string]any{}
+
+	if self.Description != "" {
+		doc["description"] = self.Description
+	}
+
+	switch len(self.Types) {
+	case 0:
+	case 1:
+		for k, v := range self.jsonSchemaFor(self.Types[0]) {
+			doc[k] = v
+		}
+		doc["x-nson-type"] = self.Types[0].String()
+	default:
+		anyOf := make([]any, 0, len(self.Types))
+		names := make([]string, 0, len(self.Types))
+		for _, dt := range self.Types {
+			anyOf = append(anyOf, self.jsonSchemaFor(dt))
+			names = append(names, dt.String())
+		}
+		doc["anyOf"] = anyOf
+		doc["x-nson-type"] = names
+	}
+
+	if len(self.Types) == 0 {
+		// 没有限定类型时，约束作用于对应类型的值，与 Validate 一致
+		for k, v := range self.jsonSchemaFor(0) {
+			doc[k] = v
+		}
+	}
+
+	if self.Enum != nil {
+		enum := make([]any, len(self.Enum))
+		for i, v := range self.Enum {
+			enum[i] = jsonValue(v)
+		}
+		doc["enum"] = enum
+	}
+
+	return doc
+}
+
+// checkJSON 检查 Schema 中的值能否输出为 JSON，path 为出错时报告的位置
+func (self *Schema) checkJSON(path string) error {
+	check := func(keyword string, values ...Value) error {
+		for _, v := range values {
+			if v != nil && !jsonFinite(v) {
+				return fmt.Errorf("%v: NaN and Inf cannot be represented in JSON", childPath(path, keyword))
+			}
+		}
+		return nil
+	}
+
+	if err := check("minimum", self.Min); err != nil {
+		return err
+	}
+	if err := check("maximum", self.Max); err != nil {
+		return err
+	}
+	if err := check("enum", self.Enum...); err != nil {
+		return err
+	}
+
+	for _, name := range sortedSchemaFields(self.Fields) {
+		if err := self.Fields[name].checkJSON(childPath(path, name)); err != nil {
+			return err
+		}
+	}
+	if self.Items != nil {
+		if err := self.Items.checkJSON(childPath(path, "items")); err != nil {
+			return err
+		}
+	}
+	if self.Values != nil {
+		if err := self.Values.checkJSON(childPath(path, "values")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// jsonFinite 判断值中的浮点数是否都是有限的，包括 Map 和 Array 中的元素
+func jsonFinite(v Value) bool {
+	switch x := v.(type) {
+	case F32:
+		return !math.IsNaN(float64(x)) && !math.IsInf(float64(x), 0)
+	case F64:
+		return !math.IsNaN(float64(x)) && !math.IsInf(float64(x), 0)
+	case Map:
+		for _, item := range x {
+			if !jsonFinite(item) {
+				return false
+			}
+		}
+	case Array:
+		for _, item := range x {
+			if !jsonFinite(item) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// jsonSchemaFor 生成 dt 类型的 JSON Schema，dt 为 0 时输出全部适用的约束
+func (self *Schema) jsonSchemaFor(dt DataType) map[string]any {
+	doc := map[string]any{}
+	all := dt == 0
+
+	if name := jsonTypeName(dt); name != "" {
+		doc["type"] = name
+	}
+
+	if all || dt.IsNumeric() || dt == DataTypeTIMESTAMP {
+		lo, hi := jsonTypeRange(dt)
+		if boundApplies(self.Min, dt) {
+			lo = jsonValue(self.Min)
+		}
+		if boundApplies(self.Max, dt) {
+			hi = jsonValue(self.Max)
+		}
+		if lo != nil {
+			doc["minimum"] = lo
+		}
+		if hi != nil {
+			doc["maximum"] = hi
+		}
+	}
+
+	switch {
+	case dt == DataTypeSTRING || all:
+		if self.MinLength != nil {
+			doc["minLength"] = *self.MinLength
+		}
+		if self.MaxLength != nil {
+			doc["maxLength"] = *self.MaxLength
+		}
+		if self.Pattern != "" {
+			doc["pattern"] = self.Pattern
+		}
+
+	case dt == DataTypeBINARY:
+		doc["contentEncoding"] = "base16"
+		if self.MinLength != nil {
+			doc["minLength"] = *self.MinLength * 2
+		}
+		if self.MaxLength != nil {
+			doc["maxLength"] = *self.MaxLength * 2
+		}
+
+	case dt == DataTypeID:
+		doc["pattern"] = idPattern
+	}
+
+	if dt == DataTypeARRAY || (all && (self.Items != nil || self.MinItems != nil || self.MaxItems != nil)) {
+		if self.Items != nil {
+			doc["items"] = self.Items.jsonSchema()
+		}
+		if self.MinItems != nil {
+			doc["minItems"] = *self.MinItems
+		}
+		if self.MaxItems != nil {
+			doc["maxItems"] = *self.MaxItems
+		}
+	}
+
+	if dt == DataTypeMAP || (all && (self.Fields != nil || self.Values != nil || self.Closed)) {
+		if self.Fields != nil {
+			props := make(map[string]any, len(self.Fields))
+			required := []string{}
+			for _, name := range sortedSchemaFields(self.Fields) {
+				props[name] = self.Fields[name].jsonSchema()
+				if self.Fields[name].Required {
+					required = append(required, name)
+				}
+			}
+			doc["properties"] = props
+			if len(required) > 0 {
+				doc["required"] = required
+			}
+		}
+		if self.Closed {
+			doc["additionalProperties"] = false
+		} else if self.Values != nil {
+			doc["additionalProperties"] = self.Values.jsonSchema()
+		}
+	}
+
+	return doc
+}
+
+// boundApplies 判断 Min 或 Max 是否约束 dt 类型的值，dt 为 0 时只看 bound 自身的类型
+func boundApplies(bound Value, dt DataType) bool {
+	if bound == nil {
+		return false
+	}
+
+	if dt == 0 {
+		return bound.DataType().IsNumeric() || bound.DataType() == DataTypeTIMESTAMP
+	}
+
+	return typeRank(bound) == typeRank(dt.ZeroValue())
+}
+
+func jsonTypeName(dt DataType) string {
+	switch {
+	case dt == DataTypeF32 || dt == DataTypeF64:
+		return "number"
+	case dt.IsNumeric() || dt == DataTypeTIMESTAMP:
+		return "integer"
+	}
+
+	switch dt {
+	case DataTypeSTRING, DataTypeBINARY, DataTypeID:
+		return "string"
+	case DataTypeBOOL:
+		return "boolean"
+	case DataTypeNULL:
+		return "null"
+	case DataTypeMAP:
+		return "object"
+	case DataTypeARRAY:
+		return "array"
+	}
+
+	return ""
+}
+
+// jsonTypeRange 返回整数类型的取值范围
+func jsonTypeRange(dt DataType) (any, any) {
+	switch dt {
+	case DataTypeI8:
+		return math.MinInt8, math.MaxInt8
+	case DataTypeI16:
+		return math.MinInt16, math.MaxInt16
+	case DataTypeI32:
+		return math.MinInt32, math.MaxInt32
+	case DataTypeI64:
+		return int64(math.MinInt64), int64(math.MaxInt64)
+	case DataTypeU8:
+		return 0, math.MaxUint8
+	case DataTypeU16:
+		return 0, math.MaxUint16
+	case DataTypeU32:
+		return 0, uint32(math.MaxUint32)
+	case DataTypeU64, DataTypeTIMESTAMP:
+		return 0, uint64(math.MaxUint64)
+	}
+
+	return nil, nil
+}
+
+// jsonValue 将 NSON 值转换为 encoding/json 可以输出的值
+func jsonValue(v Value) any {
+	switch x := v.(type) {
+	case Map:
+		out := make(map[string]any, len(x))
+		for k, item := range x {
+			out[k] = jsonValue(item)
+		}
+		return out
+	case Array:
+		out := make([]any, len(x))
+		for i, item := range x {
+			out[i] = jsonValue(item)
+		}
+		return out
+	case String:
+		return string(x)
+	case Bool:
+		return bool(x)
+	case Null:
+		return nil
+	case Binary:
+		return x.Hex()
+	case Id:
+		return x.Hex()
+	case Timestamp:
+		return uint64(x)
+	}
+
+	n, ok := toNumber(v)
+	if !ok {
+		return v.String()
+	}
+
+	switch n.kind {
+	case numberInt:
+		return n.i
+	case numberUint:
+		return n.u
+	default:
+		return n.f
+	}
+}
+
+// SchemaFromJSON 解析 JSON Schema，支持 JSONSchema 输出的关键字。
+// 有 "x-nson-type" 时使用其中的类型；否则 "integer" 按 minimum 和 maximum
+// 选择能容纳的最窄整数类型（没有范围时为 I64），"number" 为 F64，
+// contentEncoding 为 base16 的 "string" 为 Binary。不认识的关键字被忽略。
+func SchemaFromJSON(data []byte) (*Schema, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	return schemaFromJSON(doc, "")
+}
+
+func schemaFromJSON(doc map[string]any, path string) (*Schema, error) {
+	fail := func(format string, args ...any) (*Schema, error) {
+		msg := fmt.Sprintf(format, args...)
+		if path != "" {
+			msg = path + ": " + msg
+		}
+		return nil, fmt.Errorf("Invalid schema, %v", msg)
+	}
+
+	schema := &Schema{}
+
+	if s, ok := doc["description"].(string); ok {
+		schema.Description = s
+	}
+
+	// anyOf 的各个分支合并为联合类型
+	if branches, ok := doc["anyOf"].([]any); ok {
+		for i, item := range branches {
+			b, ok := item.(map[string]any)
+			if !ok {
+				return fail("anyOf index %d expects object", i)
+			}
+			branch, err := schemaFromJSON(b, path)
+			if err != nil {
+				return nil, err
+			}
+			schema.merge(branch)
+		}
+	}
+
+	if v, ok := doc["minimum"]; ok {
+		bound, err := jsonNumber(v)
+		if err != nil {
+			return fail("minimum: %v", err)
+		}
+		schema.Min = bound
+	}
+
+	if v, ok := doc["maximum"]; ok {
+		bound, err := jsonNumber(v)
+		if err != nil {
+			return fail("maximum: %v", err)
+		}
+		schema.Max = bound
+	}
+
+	for _, key := range []string{"minLength", "maxLength", "minItems", "maxItems"} {
+		v, ok := doc[key]
+		if !ok {
+			continue
+		}
+		n, err := jsonNumber(v)
+		if err != nil {
+			return fail("%v: %v", key, err)
+		}
+		i, ok := sliceInt(n)
+		if !ok || i < 0 {
+			return fail("%v expects non-negative integer, got %v", key, v)
+		}
+		switch key {
+		case "minLength":
+			schema.MinLength = &i
+		case "maxLength":
+			schema.MaxLength = &i
+		case "minItems":
+			schema.MinItems = &i
+		case "maxItems":
+			schema.MaxItems = &i
+		}
+	}
+
+	if s, ok := doc["pattern"].(string); ok {
+		if _, err := compilePattern(s); err != nil {
+			return fail("pattern: %v", err)
+		}
+		schema.Pattern = s
+	}
+
+	if enum, ok := doc["enum"].([]any); ok {
+		schema.Enum = make([]Value, 0, len(enum))
+		for _, item := range enum {
+			v, err := valueFromJSON(item)
+			if err != nil {
+				return fail("enum: %v", err)
+			}
+			schema.Enum = append(schema.Enum, v)
+		}
+	}
+
+	if props, ok := doc["properties"].(map[string]any); ok {
+		schema.Fields = make(map[string]*Schema, len(props))
+		for name, item := range props {
+			p, ok := item.(map[string]any)
+			if !ok {
+				return fail("property %v expects object", name)
+			}
+			field, err := schemaFromJSON(p, childPath(path, name))
+			if err != nil {
+				return nil, err
+			}
+			schema.Fields[name] = field
+		}
+	}
+
+	if required, ok := doc["required"].([]any); ok {
+		for _, item := range required {
+			name, _ := item.(string)
+			field, has := schema.Fields[name]
+			if !has {
+				return fail("required field %v is not in properties", item)
+			}
+			field.Required = true
+		}
+	}
+
+	switch x := doc["additionalProperties"].(type) {
+	case bool:
+		schema.Closed = !x
+	case map[string]any:
+		values, err := schemaFromJSON(x, childPath(path, "values"))
+		if err != nil {
+			return nil, err
+		}
+		schema.Values = values
+	}
+
+	if items, ok := doc["items"].(map[string]any); ok {
+		s, err := schemaFromJSON(items, childPath(path, "items"))
+		if err != nil {
+			return nil, err
+		}
+		schema.Items = s
+	}
+
+	types, err := jsonSchemaTypes(doc, schema)
+	if err != nil {
+		return fail("%v", err)
+	}
+	if types != nil {
+		schema.Types = types
+	}
+
+	schema.normalizeJSON(doc)
+
+	return schema, nil
+}
+
+// jsonSchemaTypes 解析 x-nson-type 或 type
+func jsonSchemaTypes(doc map[string]any, schema *Schema) ([]DataType, error) {
+	var names []any
+
+	if v, has := doc["x-nson-type"]; has {
+		switch x := v.(type) {
+		case string:
+			names = []any{x}
+		case []any:
+			names = x
+		default:
+			return nil, fmt.Errorf("x-nson-type expects string or array")
+		}
+
+		types := make([]DataType, 0, len(names))
+		for _, name := range names {
+			s, _ := name.(string)
+			dt, ok := ParseDataType(s)
+			if !ok {
+				return nil, fmt.Errorf("unknown type %q", name)
+			}
+			types = append(types, dt)
+		}
+		return types, nil
+	}
+
+	switch x := doc["type"].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		names = []any{x}
+	case []any:
+		names = x
+	default:
+		return nil, fmt.Errorf("type expects string or array")
+	}
+
+	types := make([]DataType, 0, len(names))
+	for _, name := range names {
+		var dt DataType
+
+		switch name {
+		case "integer":
+			dt = narrowestIntType(schema.Min, schema.Max)
+		case "number":
+			dt = DataTypeF64
+		case "string":
+			dt = DataTypeSTRING
+			if doc["contentEncoding"] == "base16" {
+				dt = DataTypeBINARY
+			} else if doc["pattern"] == idPattern {
+				dt = DataTypeID
+			}
+		case "boolean":
+			dt = DataTypeBOOL
+		case "null":
+			dt = DataTypeNULL
+		case "object":
+			dt = DataTypeMAP
+		case "array":
+			dt = DataTypeARRAY
+		default:
+			return nil, fmt.Errorf("unknown type %q", name)
+		}
+
+		if !slices.Contains(types, dt) {
+			types = append(types, dt)
+		}
+	}
+
+	return types, nil
+}
+
+// normalizeJSON 去掉 JSON Schema 为表达 NSON 类型而附加的约束
+func (self *Schema) normalizeJSON(doc map[string]any) {
+	for _, dt := range self.Types {
+		lo, hi := jsonTypeRange(dt)
+		if lo != nil && self.Min != nil && EqualNumeric(self.Min, jsonBound(lo)) {
+			self.Min = nil
+		}
+		if hi != nil && self.Max != nil && EqualNumeric(self.Max, jsonBound(hi)) {
+			self.Max = nil
+		}
+
+		switch dt {
+		case DataTypeID:
+			if self.Pattern == idPattern {
+				self.Pattern = ""
+			}
+		case DataTypeBINARY:
+			if doc["contentEncoding"] == "base16" {
+				if self.MinLength != nil {
+					self.MinLength = Limit(*self.MinLength / 2)
+				}
+				if self.MaxLength != nil {
+					self.MaxLength = Limit(*self.MaxLength / 2)
+				}
+			}
+		}
+	}
+}
+
+// merge 合并 anyOf 分支
+func (self *Schema) merge(other *Schema) {
+	for _, dt := range other.Types {
+		if !slices.Contains(self.Types, dt) {
+			self.Types = append(self.Types, dt)
+		}
+	}
+
+	if self.Fields == nil {
+		self.Fields = other.Fields
+	}
+	if self.Values == nil {
+		self.Values = other.Values
+	}
+	self.Closed = self.Closed || other.Closed
+	if self.Items == nil {
+		self.Items = other.Items
+	}
+	if self.MinItems == nil {
+		self.MinItems = other.MinItems
+	}
+	if self.MaxItems == nil {
+		self.MaxItems = other.MaxItems
+	}
+	if self.Min == nil {
+		self.Min = other.Min
+	}
+	if self.Max == nil {
+		self.Max = other.Max
+	}
+	if self.MinLength == nil {
+		self.MinLength = other.MinLength
+	}
+	if self.MaxLength == nil {
+		self.MaxLength = other.MaxLength
+	}
+	if self.Pattern == "" {
+		self.Pattern = other.Pattern
+	}
+}
+
+// narrowestIntType 选择能容纳 [min, max] 的最窄整数类型，非负时使用无符号类型
+func narrowestIntType(min, max Value) DataType {
+	if min == nil || max == nil {
+		return DataTypeI64
+	}
+
+	candidates := []DataType{DataTypeI8, DataTypeI16, DataTypeI32, DataTypeI64}
+	if lo, _ := toNumber(min); compareNumber(lo, number{kind: numberInt}) >= 0 {
+		candidates = []DataType{DataTypeU8, DataTypeU16, DataTypeU32, DataTypeU64}
+	}
+
+	for _, dt := range candidates {
+		lo, hi := jsonTypeRange(dt)
+		if Compare(min, jsonBound(lo)) >= 0 && Compare(max, jsonBound(hi)) <= 0 {
+			return dt
+		}
+	}
+
+	return DataTypeI64
+}
+
+// jsonBound 将 jsonTypeRange 的结果转换为 Value
+func jsonBound(v any) Value {
+	switch x := v.(type) {
+	case int:
+		return I64(x)
+	case int64:
+		return I64(x)
+	case uint32:
+		return U32(x)
+	case uint64:
+		return U64(x)
+	}
+
+	return nil
+}
+
+func jsonNumber(v any) (Value, error) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return nil, fmt.Errorf("expected number, got %v", v)
+	}
+
+	if i, err := n.Int64(); err == nil {
+		return I64(i), nil
+	}
+
+	if u, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+		return U64(u), nil
+	}
+
+	f, err := n.Float64()
+	if err != nil {
+		return nil, err
+	}
+
+	return F64(f), nil
+}
+
+// valueFromJSON 将 JSON 值转换为 NSON 值，整数为 I64，小数为 F64
+func valueFromJSON(v any) (Value, error) {
+	switch x := v.(type) {
+	case nil:
+		return Null{}, nil
+	case bool:
+		return Bool(x), nil
+	case string:
+		return String(x), nil
+	case json.Number:
+		return jsonNumber(x)
+	case []any:
+		arr := make(Array, 0, len(x))
+		for _, item := range x {
+			value, err := valueFromJSON(item)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, value)
+		}
+		return arr, nil
+	case map[string]any:
+		m := make(Map, len(x))
+		for k, item := range x {
+			value, err := valueFromJSON(item)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = value
+		}
+		return m, nil
+	}
+
+	return nil, fmt.Errorf("unsupported JSON value %v", v)
+}
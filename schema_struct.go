@@ -0,0 +1,180 @@
+package nson
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"time"
+)
+
+// SchemaOf 根据 Go 类型生成 Schema，类型映射与默认选项的 Marshal 一致：
+//
+//   - 指针可以为 Null，[]byte 为 Binary，time.Time 为 Timestamp，Id 为 Id
+//   - 实现了 encoding.TextMarshaler 的类型为 String，实现了 encoding.BinaryMarshaler
+//     的类型为 Binary，实现了 Marshaler 的类型编码结果未知，不约束类型
+//   - 结构体字段使用 nson tag 中的名称，有 type 选项时为指定的类型，
+//     validate tag 中的规则转换为对应的约束
+//   - 有 required 选项的字段总是 Required，其他字段在没有 omitempty、没有 default
+//     且不在内联的结构体指针中时为 Required
+//   - remain 字段或内联的 map 的值约束为 Values
+//   - 递归引用自身的结构体在第二次出现时只约束为 Map
+func SchemaOf(t reflect.Type) (*Schema, error) {
+	return schemaOfType(t, map[reflect.Type]bool{})
+}
+
+// SchemaFor 与 SchemaOf 相同，类型由类型参数给出
+func SchemaFor[T any]() (*Schema, error) {
+	return SchemaOf(reflect.TypeFor[T]())
+}
+
+func schemaOfType(t reflect.Type, visiting map[reflect.Type]bool) (*Schema, error) {
+	if codecOf(t).marshaler != implNone {
+		return &Schema{}, nil
+	}
+
+	if t.Kind() == reflect.Pointer {
+		schema, err := schemaOfType(t.Elem(), visiting)
+		if err != nil {
+			return nil, err
+		}
+		if len(schema.Types) > 0 && !slices.Contains(schema.Types, DataTypeNULL) {
+			schema.Types = append(schema.Types, DataTypeNULL)
+		}
+		return schema, nil
+	}
+
+	single := func(dt DataType) (*Schema, error) {
+		return &Schema{Types: []DataType{dt}}, nil
+	}
+
+	if t != reflect.TypeFor[time.Time]() {
+		switch {
+		case codecOf(t).textMarshaler != implNone:
+			return single(DataTypeSTRING)
+		case codecOf(t).binaryMarshaler != implNone:
+			return single(DataTypeBINARY)
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return single(DataTypeBOOL)
+	case reflect.Int8:
+		return single(DataTypeI8)
+	case reflect.Int16:
+		return single(DataTypeI16)
+	case reflect.Int32, reflect.Int:
+		return single(DataTypeI32)
+	case reflect.Int64:
+		return single(DataTypeI64)
+	case reflect.Uint8:
+		return single(DataTypeU8)
+	case reflect.Uint16:
+		return single(DataTypeU16)
+	case reflect.Uint32, reflect.Uint:
+		return single(DataTypeU32)
+	case reflect.Uint64:
+		return single(DataTypeU64)
+	case reflect.Float32:
+		return single(DataTypeF32)
+	case reflect.Float64:
+		return single(DataTypeF64)
+	case reflect.String:
+		return single(DataTypeSTRING)
+
+	case reflect.Slice:
+		if isByteSlice(t) {
+			return single(DataTypeBINARY)
+		}
+		items, err := schemaOfType(t.Elem(), visiting)
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Types: []DataType{DataTypeARRAY}, Items: items}, nil
+
+	case reflect.Array:
+		if t == reflect.TypeFor[Id]() {
+			return single(DataTypeID)
+		}
+		items, err := schemaOfType(t.Elem(), visiting)
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Types: []DataType{DataTypeARRAY}, Items: items, MinItems: Limit(t.Len()), MaxItems: Limit(t.Len())}, nil
+
+	case reflect.Struct:
+		if t == reflect.TypeFor[time.Time]() {
+			return single(DataTypeTIMESTAMP)
+		}
+		return schemaOfStruct(t, visiting)
+
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("map key must be string")
+		}
+		values, err := schemaOfType(t.Elem(), visiting)
+		if err != nil {
+			return nil, err
+		}
+		schema := &Schema{Types: []DataType{DataTypeMAP}}
+		if len(values.Types) > 0 {
+			schema.Values = values
+		}
+		return schema, nil
+
+	case reflect.Interface:
+		// 任意类型
+		return &Schema{}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported type: %v", t)
+	}
+}
+
+func schemaOfStruct(t reflect.Type, visiting map[reflect.Type]bool) (*Schema, error) {
+	schema := &Schema{Types: []DataType{DataTypeMAP}}
+
+	if visiting[t] {
+		return schema, nil
+	}
+
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	cache := getStructCache(t, nil)
+	if cache.err != nil {
+		return nil, cache.err
+	}
+
+	schema.Fields = make(map[string]*Schema, len(cache.fields))
+
+	for _, field := range cache.fields {
+		fs, err := schemaOfType(field.typ, visiting)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.name, err)
+		}
+
+		// type 选项替代 Go 类型对应的类型，指针仍然可以为 Null
+		if field.wire != 0 {
+			fs.Types[0] = field.wire
+		}
+
+		if field.rules != nil {
+			fs.constrain(field.rules)
+		}
+
+		fs.Required = field.required || !field.omitEmpty && !field.optional && !field.def.IsValid()
+		schema.Fields[field.nsonName] = fs
+	}
+
+	// remain 字段或内联的 map 收集其他字段
+	if cache.remain != nil {
+		vs, err := schemaOfType(cache.remain.typ.Elem(), visiting)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", cache.remain.name, err)
+		}
+		schema.Values = vs
+	}
+
+	return schema, nil
+}
@@ -0,0 +1,218 @@
+package nson
+
+import (
+	"bytes"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func deviceSchema() *Schema {
+	return &Schema{
+		Types:  []DataType{DataTypeMAP},
+		Closed: true,
+		Fields: map[string]*Schema{
+			"id":   {Types: []DataType{DataTypeID}, Required: true},
+			"name": {Types: []DataType{DataTypeSTRING}, Required: true, MinLength: Limit(1), MaxLength: Limit(8), Pattern: "^[a-z0-9-]+$"},
+			"port": {Types: []DataType{DataTypeU16}, Min: U16(1), Max: I32(1024)},
+			"mode": {Types: []DataType{DataTypeSTRING}, Enum: []Value{String("auto"), String("manual")}},
+			"note": {Types: []DataType{DataTypeSTRING, DataTypeNULL}},
+			"tags": {
+				Types:    []DataType{DataTypeARRAY},
+				MaxItems: Limit(2),
+				Items:    &Schema{Types: []DataType{DataTypeSTRING}},
+			},
+			"meta": {
+				Types: []DataType{DataTypeMAP},
+				Fields: map[string]*Schema{
+					"rev": {Types: []DataType{DataTypeU32}, Required: true},
+				},
+			},
+		},
+	}
+}
+
+func TestSchemaValidateOK(t *testing.T) {
+	doc := Map{
+		"id":   NewId(),
+		"name": String("sensor-1"),
+		"port": U16(80),
+		"mode": String("auto"),
+		"note": Null{},
+		"tags": Array{String("a")},
+		"meta": Map{"rev": U32(3), "extra": Bool(true)},
+	}
+
+	if errs := deviceSchema().Validate(doc); errs != nil {
+		t.Errorf("Expected no errors, got %v", errs)
+	}
+}
+
+func TestSchemaValidateErrors(t *testing.T) {
+	doc := Map{
+		"name":  String("Sensor_1!!"),
+		"port":  U16(2000),
+		"mode":  String("off"),
+		"note":  I32(1),
+		"tags":  Array{String("a"), I32(2), String("c")},
+		"meta":  Map{},
+		"bogus": Bool(true),
+	}
+
+	errs := deviceSchema().Validate(doc)
+
+	var got []string
+	for _, err := range errs {
+		got = append(got, err.Error())
+	}
+
+	want := []string{
+		"id: required field is missing",
+		"meta.rev: required field is missing",
+		"mode: value String(off) is not one of Array[String(auto), String(manual)]",
+		"name: expected length at most 8, got 10",
+		`name: value "Sensor_1!!" does not match pattern "^[a-z0-9-]+$"`,
+		"note: expected String or Null, got I32",
+		"port: value U16(2000) is greater than maximum I32(1024)",
+		"tags: expected at most 2 items, got 3",
+		"tags.1: expected String, got I32",
+		"bogus: unknown field",
+	}
+
+	if !slices.Equal(got, want) {
+		t.Errorf("Unexpected errors:\n got: %q\nwant: %q", got, want)
+	}
+}
+
+func TestSchemaValidateNil(t *testing.T) {
+	doc := Map{"id": NewId(), "name": nil, "tags": Array{nil}}
+
+	var got []string
+	for _, err := range deviceSchema().Validate(doc) {
+		got = append(got, err.Error())
+	}
+
+	want := []string{"name: invalid nil value", "tags.0: invalid nil value"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Unexpected errors:\n got: %q\nwant: %q", got, want)
+	}
+
+	if errs := (&Schema{}).ValidateValue(nil); len(errs) != 1 {
+		t.Errorf("Expected one error, got %v", errs)
+	}
+}
+
+func TestSchemaFromMapNil(t *testing.T) {
+	invalid := map[string]Map{
+		"Invalid schema, type: invalid nil value":                         {"type": nil},
+		"Invalid schema, a.b: min: invalid nil value":                     {"fields": Map{"a": Map{"fields": Map{"b": Map{"min": nil}}}}},
+		"Invalid schema, field a expects Map, got nil":                    {"fields": Map{"a": nil}},
+		"Invalid schema, type expects String or Array of String, got nil": {"type": Array{nil}},
+		"Invalid schema, items: enum.1: invalid nil value":                {"items": Map{"enum": Array{I32(1), nil}}},
+	}
+
+	for want, m := range invalid {
+		if _, err := SchemaFromMap(m); err == nil || err.Error() != want {
+			t.Errorf("Expected %q, got %v", want, err)
+		}
+	}
+}
+
+func TestSchemaMapRoundTrip(t *testing.T) {
+	schema := deviceSchema()
+
+	m := schema.Map()
+
+	buf := new(bytes.Buffer)
+	if err := EncodeMap(m, buf); err != nil {
+		t.Fatalf("EncodeMap failed: %v", err)
+	}
+
+	decoded, err := DecodeMap(buf)
+	if err != nil {
+		t.Fatalf("DecodeMap failed: %v", err)
+	}
+
+	parsed, err := SchemaFromMap(decoded)
+	if err != nil {
+		t.Fatalf("SchemaFromMap failed: %v", err)
+	}
+
+	if !Equal(parsed.Map(), m) {
+		t.Errorf("Round trip mismatch:\n got: %v\nwant: %v", parsed.Map(), m)
+	}
+
+	if errs := parsed.Validate(Map{"name": String("x")}); len(errs) != 1 || errs[0].Path != "id" {
+		t.Errorf("Unexpected errors: %v", errs)
+	}
+}
+
+func TestSchemaFromMapErrors(t *testing.T) {
+	invalid := []Map{
+		{"type": String("Integer")},
+		{"type": I32(1)},
+		{"required": String("yes")},
+		{"fields": Map{"a": Map{"type": String("Bogus")}}},
+		{"items": String("I32")},
+		{"minLength": I32(-1)},
+		{"pattern": String("(")},
+		{"unknown": Bool(true)},
+	}
+
+	for _, m := range invalid {
+		if _, err := SchemaFromMap(m); err == nil {
+			t.Errorf("Expected error for %v", m)
+		}
+	}
+
+	schema, err := SchemaFromMap(Map{"type": Array{String("i32"), String("null")}, "min": I8(0)})
+	if err != nil {
+		t.Fatalf("SchemaFromMap failed: %v", err)
+	}
+	if !slices.Equal(schema.Types, []DataType{DataTypeI32, DataTypeNULL}) {
+		t.Errorf("Unexpected types: %v", schema.Types)
+	}
+	if errs := schema.ValidateValue(I32(-1)); len(errs) != 1 {
+		t.Errorf("Expected range error, got %v", errs)
+	}
+}
+
+func TestSchemaValues(t *testing.T) {
+	schema := &Schema{
+		Types:  []DataType{DataTypeMAP},
+		Fields: map[string]*Schema{"version": {Types: []DataType{DataTypeU32}}},
+		Values: &Schema{Types: []DataType{DataTypeI8}},
+	}
+
+	errs := schema.Validate(Map{"version": U32(1), "a": I8(1), "b": String("x")})
+	if len(errs) != 1 || errs[0].Path != "b" {
+		t.Errorf("Unexpected errors: %v", errs)
+	}
+
+	parsed, err := SchemaFromMap(schema.Map())
+	if err != nil || parsed.Values == nil || parsed.Values.Types[0] != DataTypeI8 {
+		t.Errorf("Unexpected round trip: %v, %v", parsed, err)
+	}
+}
+
+func TestSchemaJSONNonFinite(t *testing.T) {
+	schemas := map[string]*Schema{
+		"minimum":        {Types: []DataType{DataTypeF64}, Min: F64(math.NaN())},
+		"maximum":        {Types: []DataType{DataTypeF32}, Max: F32(math.Inf(1))},
+		"enum":           {Enum: []Value{F64(1), F64(math.Inf(-1))}},
+		"a.items.enum":   {Fields: map[string]*Schema{"a": {Items: &Schema{Enum: []Value{Array{F64(math.NaN())}}}}}},
+		"values.minimum": {Values: &Schema{Min: F32(float32(math.NaN()))}},
+	}
+
+	for want, schema := range schemas {
+		_, err := schema.JSONSchema()
+		if err == nil || !strings.HasPrefix(err.Error(), want+": ") {
+			t.Errorf("Expected error at %v, got %v", want, err)
+		}
+	}
+
+	if _, err := (&Schema{Min: F64(math.MaxFloat64)}).JSONSchema(); err != nil {
+		t.Errorf("JSONSchema failed: %v", err)
+	}
+}
@@ -1,5 +1,10 @@
 package nson
 
+import (
+	"fmt"
+	"strings"
+)
+
 type DataType uint8
 
 const (
@@ -106,3 +111,49 @@ func (dt DataType) ZeroValue() Value {
 		return nil
 	}
 }
+
+var dataTypeNames = map[DataType]string{
+	DataTypeBOOL:      "Bool",
+	DataTypeNULL:      "Null",
+	DataTypeF32:       "F32",
+	DataTypeF64:       "F64",
+	DataTypeI32:       "I32",
+	DataTypeI64:       "I64",
+	DataTypeU32:       "U32",
+	DataTypeU64:       "U64",
+	DataTypeI8:        "I8",
+	DataTypeU8:        "U8",
+	DataTypeI16:       "I16",
+	DataTypeU16:       "U16",
+	DataTypeSTRING:    "String",
+	DataTypeBINARY:    "Binary",
+	DataTypeARRAY:     "Array",
+	DataTypeMAP:       "Map",
+	DataTypeTIMESTAMP: "Timestamp",
+	DataTypeID:        "Id",
+}
+
+// String 返回类型名称，与对应 Value 类型的名称一致，如 "I32"、"Map"
+func (dt DataType) String() string {
+	if name, ok := dataTypeNames[dt]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("DataType(0x%02X)", uint8(dt))
+}
+
+// ParseDataType 按名称解析类型，不区分大小写
+func ParseDataType(name string) (DataType, bool) {
+	for dt, n := range dataTypeNames {
+		if strings.EqualFold(n, name) {
+			return dt, true
+		}
+	}
+
+	return 0, false
+}
+
+// IsNumeric 判断是否为整数或浮点数类型
+func (dt DataType) IsNumeric() bool {
+	return dt >= DataTypeF32 && dt <= DataTypeU16
+}
@@ -1,13 +1,24 @@
 package nson
 
 import (
+	"fmt"
 	"reflect"
+	"slices"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/danclive/nson-go/internal/nsontag"
 )
 
 // structCache 缓存结构体的字段信息以提高性能
 type structCache struct {
+	naming *Naming // 没有在 tag 中指定名称的字段的键名规则
 	fields []fieldInfo
+	names  map[string]bool // fields 使用的 NSON 名称
+	folded map[string]bool // 转为小写的 NSON 名称，用于不区分大小写的匹配
+	remain *fieldInfo      // remain 字段或内联的 map，收集没有对应字段的键
+	err    error           // 结构体定义错误，Marshal 和 Unmarshal 时返回
 }
 
 type fieldInfo struct {
@@ -16,17 +27,44 @@ type fieldInfo struct {
 	nsonName  string
 	typ       reflect.Type
 	omitEmpty bool
+	required  bool          // Unmarshal 时 Map 中必须有对应的键
+	def       reflect.Value // Map 中没有对应的键时写入的默认值，无效表示没有默认值
+	rules     *Schema       // validate tag 中的约束
+	wire      DataType      // type 选项指定的 NSON 类型，0 表示按 Go 类型编码
+	optional  bool          // 位于内联的指针中，指针为 nil 时不编码
+	depth     int           // 嵌入和内联的层级，同名时层级浅的字段优先
+	tagged    bool          // tag 中指定了名称，同一层级同名时优先
+}
+
+// fieldPath 嵌入或内联的结构体所在的位置
+type fieldPath struct {
+	indices  []int
+	depth    int
+	optional bool
+}
+
+// child 返回位于 indices 的结构体中字段的位置，ptr 表示经过指针
+func (self fieldPath) child(indices []int, ptr bool) fieldPath {
+	return fieldPath{indices: indices, depth: self.depth + 1, optional: self.optional || ptr}
+}
+
+// structKey 结构体缓存的键，同一类型使用不同的 Naming 时字段名称不同
+type structKey struct {
+	t      reflect.Type
+	naming *Naming
 }
 
 var (
 	structCacheMutex sync.RWMutex
-	structCacheMap   = make(map[reflect.Type]*structCache)
+	structCacheMap   = make(map[structKey]*structCache)
 )
 
-// getStructCache 获取或创建结构体缓存
-func getStructCache(t reflect.Type) *structCache {
+// getStructCache 获取或创建结构体缓存，naming 为 nil 时使用字段名
+func getStructCache(t reflect.Type, naming *Naming) *structCache {
+	key := structKey{t, naming}
+
 	structCacheMutex.RLock()
-	cache, ok := structCacheMap[t]
+	cache, ok := structCacheMap[key]
 	structCacheMutex.RUnlock()
 
 	if ok {
@@ -37,28 +75,59 @@ func getStructCache(t reflect.Type) *structCache {
 	defer structCacheMutex.Unlock()
 
 	// 双重检查
-	if cache, ok := structCacheMap[t]; ok {
+	if cache, ok := structCacheMap[key]; ok {
 		return cache
 	}
 
-	cache = buildStructCache(t)
-	structCacheMap[t] = cache
+	cache = buildStructCache(t, naming)
+	structCacheMap[key] = cache
 	return cache
 }
 
 // buildStructCache 构建结构体缓存
-func buildStructCache(t reflect.Type) *structCache {
+func buildStructCache(t reflect.Type, naming *Naming) *structCache {
 	cache := &structCache{
+		naming: naming,
 		fields: make([]fieldInfo, 0, t.NumField()),
 	}
 
-	buildFieldsRecursive(t, nil, &cache.fields)
+	buildFieldsRecursive(t, fieldPath{}, map[reflect.Type]bool{}, cache)
+
+	fields, err := nsontag.Dominant(cache.fields, func(field fieldInfo) nsontag.Field {
+		return nsontag.Field{Name: field.name, Key: field.nsonName, Depth: field.depth, Tagged: field.tagged}
+	})
+	if err != nil {
+		cache.fail(err)
+	}
+	cache.fields = fields
+
+	cache.names = make(map[string]bool, len(cache.fields))
+	cache.folded = make(map[string]bool, len(cache.fields))
+	for _, field := range cache.fields {
+		cache.names[field.nsonName] = true
+		cache.folded[strings.ToLower(field.nsonName)] = true
+	}
 
 	return cache
 }
 
-// buildFieldsRecursive 递归构建字段列表（支持嵌入字段）
-func buildFieldsRecursive(t reflect.Type, indexPrefix []int, fields *[]fieldInfo) {
+// fail 记录第一个定义错误
+func (self *structCache) fail(err error) {
+	if self.err == nil {
+		self.err = err
+	}
+}
+
+// buildFieldsRecursive 递归构建字段列表（支持嵌入字段和内联字段）
+func buildFieldsRecursive(t reflect.Type, at fieldPath, visiting map[reflect.Type]bool, cache *structCache) {
+	if visiting[t] {
+		cache.fail(fmt.Errorf("inline cycle through %v", t))
+		return
+	}
+
+	visiting[t] = true
+	defer delete(visiting, t)
+
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
 
@@ -67,12 +136,12 @@ func buildFieldsRecursive(t reflect.Type, indexPrefix []int, fields *[]fieldInfo
 			continue
 		}
 
-		indices := append(indexPrefix, i)
+		indices := append(slices.Clip(at.indices), i)
 
-		// 处理匿名嵌入字段
-		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+		// 处理匿名嵌入字段，自定义编码的类型作为普通字段
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && !hasCustomCodec(field.Type) {
 			// 递归处理嵌入结构体
-			buildFieldsRecursive(field.Type, indices, fields)
+			buildFieldsRecursive(field.Type, at.child(indices, false), visiting, cache)
 			continue
 		}
 
@@ -82,32 +151,110 @@ func buildFieldsRecursive(t reflect.Type, indexPrefix []int, fields *[]fieldInfo
 			continue
 		}
 
-		nsonName := tag
-		omitEmpty := false
-
-		// 解析 tag，支持 "name,omitempty"
-		if tag != "" {
-			for j, part := range []byte(tag) {
-				if part == ',' {
-					nsonName = tag[:j]
-					if len(tag) > j+1 && tag[j+1:] == "omitempty" {
-						omitEmpty = true
-					}
-					break
-				}
-			}
-		}
-
-		if nsonName == "" {
-			nsonName = field.Name
-		}
+		nsonName, opts := nsontag.Parse(tag)
 
-		*fields = append(*fields, fieldInfo{
+		info := fieldInfo{
 			indices:   indices,
 			name:      field.Name,
 			nsonName:  nsonName,
 			typ:       field.Type,
-			omitEmpty: omitEmpty,
-		})
+			omitEmpty: opts.OmitEmpty,
+			required:  opts.Required,
+			optional:  at.optional,
+			depth:     at.depth,
+			tagged:    nsonName != "",
+		}
+
+		if nsonName == "" {
+			info.nsonName = cache.naming.Name(field.Name)
+		}
+
+		if opts.Type != "" {
+			wire, err := wireType(opts.Type, field.Type)
+			if err != nil {
+				cache.fail(fmt.Errorf("field %s: %w", field.Name, err))
+				continue
+			}
+			info.wire = wire
+		}
+
+		if opts.HasDefault {
+			def, err := parseDefault(opts.Default, field.Type)
+			if err != nil {
+				cache.fail(fmt.Errorf("field %s: invalid default %q: %w", field.Name, opts.Default, err))
+				continue
+			}
+			info.def = def
+		}
+
+		if rules, ok := field.Tag.Lookup("validate"); ok {
+			parsed, err := parseRules(rules, field.Type, info.wire)
+			if err != nil {
+				cache.fail(fmt.Errorf("field %s: invalid validate tag: %w", field.Name, err))
+				continue
+			}
+			info.rules = parsed
+		}
+
+		switch {
+		case opts.Inline:
+			buildInline(field, info, at, visiting, cache)
+
+		case opts.Remain:
+			if field.Type != reflect.TypeFor[Map]() {
+				cache.fail(fmt.Errorf("field %s: remain requires nson.Map, got %v", field.Name, field.Type))
+				continue
+			}
+			cache.setRemain(info)
+
+		default:
+			cache.fields = append(cache.fields, info)
+		}
 	}
 }
+
+// buildInline 展开内联的结构体、结构体指针或键为字符串的 map
+func buildInline(field reflect.StructField, info fieldInfo, at fieldPath, visiting map[reflect.Type]bool, cache *structCache) {
+	t := field.Type
+
+	switch {
+	case t.Kind() == reflect.Struct:
+		buildFieldsRecursive(t, at.child(info.indices, false), visiting, cache)
+
+	case t.Kind() == reflect.Pointer && t.Elem().Kind() == reflect.Struct:
+		buildFieldsRecursive(t.Elem(), at.child(info.indices, true), visiting, cache)
+
+	case t.Kind() == reflect.Map && t.Key().Kind() == reflect.String && !hasCustomCodec(t):
+		cache.setRemain(info)
+
+	default:
+		cache.fail(fmt.Errorf("field %s: inline requires a struct, pointer to struct or map with string keys, got %v", field.Name, t))
+	}
+}
+
+// setRemain 设置收集没有对应字段的键的字段，最多只能有一个
+func (self *structCache) setRemain(info fieldInfo) {
+	if self.remain != nil {
+		self.fail(fmt.Errorf("field %s: duplicate remain field, already %s", info.name, self.remain.name))
+		return
+	}
+
+	self.remain = &info
+}
+
+// hasCustomCodec 检查类型是否有自己的编码方式：实现了 Marshaler、Unmarshaler、
+// encoding.TextMarshaler 或 encoding.BinaryMarshaler。time.Time 按内置规则处理
+func hasCustomCodec(t reflect.Type) bool {
+	if t == reflect.TypeFor[time.Time]() {
+		return false
+	}
+
+	c := codecOf(t)
+	return c.marshaler != implNone || c.unmarshaler != implNone ||
+		c.textMarshaler != implNone || c.binaryMarshaler != implNone
+}
+
+// isByteSlice 检查切片是否按 Binary 处理，元素自定义编码时按 Array 处理
+func isByteSlice(t reflect.Type) bool {
+	return t.Elem().Kind() == reflect.Uint8 && !hasCustomCodec(t.Elem())
+}
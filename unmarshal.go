@@ -1,13 +1,70 @@
 package nson
 
 import (
+	"bytes"
+	"encoding"
 	"fmt"
+	"maps"
 	"reflect"
+	"slices"
+	"strings"
 	"time"
 )
 
-// Unmarshal 将 nson.Map 反序列化到结构体
+// Unmarshaler 由需要自定义解码的类型实现，通常使用指针接收者。
+// 除了写入指针时直接置为 nil，Null 也会传给 UnmarshalNSON
+type Unmarshaler interface {
+	UnmarshalNSON(v Value) error
+}
+
+// MapUnmarshaler 由 nsongen -marshal 为结构体生成，Unmarshal 会直接调用而不使用反射。
+// 生成的方法只实现默认规则，UnmarshalOptions 不为零值时不会调用
+type MapUnmarshaler interface {
+	UnmarshalNSON(m Map) error
+}
+
+// UnmarshalOptions 反序列化选项，零值与 Unmarshal 相同
+type UnmarshalOptions struct {
+	// NumericConversion 数值类型不一致时的转换规则，默认要求类型完全一致
+	NumericConversion NumericConversion
+	// DisallowUnknownFields Map 中有结构体没有对应字段的键时返回错误，
+	// 有 remain 字段的结构体不受影响
+	DisallowUnknownFields bool
+	// Validate 解码成功后使用 Validate 按 validate tag 校验结果
+	Validate bool
+	// Naming 没有在 tag 中指定名称的字段的键名规则，默认使用字段名
+	Naming *Naming
+	// CaseInsensitive Map 中没有与字段名称完全相同的键时，不区分大小写查找，
+	// 有多个键时使用排序最小的一个
+	CaseInsensitive bool
+}
+
+// Unmarshal 将 nson.Map 反序列化到结构体，其他类型使用 UnmarshalValue
 func Unmarshal(m Map, v any) error {
+	return UnmarshalOptions{}.Unmarshal(m, v)
+}
+
+// UnmarshalValue 将 nson.Value 反序列化到 v 指向的任意支持的 Go 值，
+// 规则与结构体字段相同
+func UnmarshalValue(val Value, v any) error {
+	return UnmarshalOptions{}.UnmarshalValue(val, v)
+}
+
+// UnmarshalBytes 解码 MarshalBytes 或 EncodeValue 输出的字节并反序列化到 v
+func UnmarshalBytes(data []byte, v any) error {
+	return UnmarshalOptions{}.UnmarshalBytes(data, v)
+}
+
+// Unmarshal 按选项将 nson.Map 反序列化到结构体
+func (self UnmarshalOptions) Unmarshal(m Map, v any) error {
+	if err := self.unmarshal(m, v); err != nil || !self.Validate {
+		return err
+	}
+
+	return validate(v, self.Naming)
+}
+
+func (self UnmarshalOptions) unmarshal(m Map, v any) error {
 	rv := reflect.ValueOf(v)
 
 	if rv.Kind() != reflect.Pointer {
@@ -24,46 +81,194 @@ func Unmarshal(m Map, v any) error {
 		return fmt.Errorf("expected pointer to struct, got pointer to %v", rv.Kind())
 	}
 
-	return unmarshalStruct(m, rv)
+	c := codecOf(rv.Type())
+
+	if u, ok := self.mapUnmarshaler(rv, c); ok {
+		return u.UnmarshalNSON(m)
+	}
+
+	if u, ok := implementer[Unmarshaler](rv, c.unmarshaler); ok {
+		return u.UnmarshalNSON(m)
+	}
+
+	return self.unmarshalStruct(m, rv)
+}
+
+// UnmarshalValue 按选项将 nson.Value 反序列化到 v 指向的值
+func (self UnmarshalOptions) UnmarshalValue(val Value, v any) error {
+	rv := reflect.ValueOf(v)
+
+	if rv.Kind() != reflect.Pointer {
+		return fmt.Errorf("expected pointer, got %v", rv.Kind())
+	}
+
+	if rv.IsNil() {
+		return fmt.Errorf("cannot unmarshal into nil pointer")
+	}
+
+	if err := self.unmarshalValue(val, rv.Elem()); err != nil || !self.Validate {
+		return err
+	}
+
+	return validate(v, self.Naming)
+}
+
+// UnmarshalBytes 按选项解码字节并反序列化到 v
+func (self UnmarshalOptions) UnmarshalBytes(data []byte, v any) error {
+	buf := bytes.NewBuffer(data)
+
+	val, err := DecodeValue(buf)
+	if err != nil {
+		return err
+	}
+
+	if buf.Len() > 0 {
+		return fmt.Errorf("unexpected %d trailing bytes", buf.Len())
+	}
+
+	return self.UnmarshalValue(val, v)
+}
+
+// mapUnmarshaler 只在默认选项下使用 MapUnmarshaler
+func (self UnmarshalOptions) mapUnmarshaler(rv reflect.Value, c *typeCodec) (MapUnmarshaler, bool) {
+	if self != (UnmarshalOptions{}) {
+		return nil, false
+	}
+
+	return implementer[MapUnmarshaler](rv, c.mapUnmarshaler)
 }
 
 // unmarshalStruct 将 Map 反序列化到结构体
-func unmarshalStruct(m Map, rv reflect.Value) error {
+func (self UnmarshalOptions) unmarshalStruct(m Map, rv reflect.Value) error {
 	t := rv.Type()
-	cache := getStructCache(t)
+	cache := getStructCache(t, self.Naming)
+	if cache.err != nil {
+		return cache.err
+	}
+
+	var missing []string
+	var folded map[string]string
 
 	for _, field := range cache.fields {
-		val, has := m[field.nsonName]
+		val, has := self.lookup(m, field.nsonName, &folded)
 		if !has {
+			switch {
+			case field.required:
+				missing = append(missing, field.nsonName)
+			case field.def.IsValid():
+				// 内联的指针为 nil 时不写入默认值
+				if fv, ok := fieldByIndex(rv, field.indices); ok && fv.CanSet() {
+					setDefault(fv, field.def)
+				}
+			}
 			continue
 		}
 
-		fv := rv
 		// 通过索引路径获取字段值
-		for _, idx := range field.indices {
-			fv = fv.Field(idx)
+		fv, ok := fieldByIndexAlloc(rv, field.indices)
+		if !ok || !fv.CanSet() {
+			continue
 		}
 
-		if !fv.CanSet() {
+		if err := self.unmarshalField(val, fv, field.wire); err != nil {
+			return fmt.Errorf("field %s: %w", field.name, err)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required fields: %s", strings.Join(missing, ", "))
+	}
+
+	if cache.remain == nil && !self.DisallowUnknownFields {
+		return nil
+	}
+
+	var unknown Map
+	for key, val := range m {
+		if cache.names[key] || self.CaseInsensitive && cache.folded[strings.ToLower(key)] {
 			continue
 		}
+		if unknown == nil {
+			unknown = make(Map)
+		}
+		unknown[key] = val
+	}
 
-		if err := unmarshalValue(val, fv); err != nil {
-			return fmt.Errorf("field %s: %w", field.name, err)
+	if cache.remain != nil {
+		if unknown == nil {
+			return nil
+		}
+		fv, ok := fieldByIndexAlloc(rv, cache.remain.indices)
+		if !ok || !fv.CanSet() {
+			return nil
 		}
+		if err := self.unmarshalValue(unknown, fv); err != nil {
+			return fmt.Errorf("field %s: %w", cache.remain.name, err)
+		}
+		return nil
+	}
+
+	if len(unknown) > 0 {
+		keys := slices.Sorted(maps.Keys(unknown))
+		return fmt.Errorf("unknown fields: %s", strings.Join(keys, ", "))
 	}
 
 	return nil
 }
 
-// unmarshalValue 将 nson.Value 反序列化到 reflect.Value
-func unmarshalValue(val Value, rv reflect.Value) error {
+// unmarshalField 反序列化字段值，wire 为字段的 type 选项，值必须是指定的类型
+func (self UnmarshalOptions) unmarshalField(val Value, fv reflect.Value, wire DataType) error {
+	if wire != 0 {
+		return unmarshalWire(val, fv, wire)
+	}
+
+	return self.unmarshalValue(val, fv)
+}
+
+// lookup 查找字段对应的值，CaseInsensitive 时没有完全相同的键则不区分大小写查找，
+// folded 为转为小写的键到原键的索引，第一次使用时创建
+func (self UnmarshalOptions) lookup(m Map, name string, folded *map[string]string) (Value, bool) {
+	if val, has := m[name]; has || !self.CaseInsensitive {
+		return val, has
+	}
+
+	if *folded == nil {
+		*folded = make(map[string]string, len(m))
+		for key := range m {
+			lower := strings.ToLower(key)
+			if prev, has := (*folded)[lower]; !has || key < prev {
+				(*folded)[lower] = key
+			}
+		}
+	}
+
+	key, has := (*folded)[strings.ToLower(name)]
+	if !has {
+		return nil, false
+	}
+
+	return m[key], true
+}
+
+// unmarshalValue 将 nson.Value 反序列化到 reflect.Value，依次检查：
+//
+//  1. Null 将指针置为 nil，传给 Unmarshaler，其他类型保持不变
+//  2. Unmarshaler
+//  3. time.Time 需要 Timestamp，Id 需要 Id
+//  4. MapUnmarshaler 需要 Map
+//  5. encoding.TextUnmarshaler 需要 String，encoding.BinaryUnmarshaler 需要 Binary
+//  6. 按 NumericConversion 转换数值
+//  7. 按 Kind 解码
+func (self UnmarshalOptions) unmarshalValue(val Value, rv reflect.Value) error {
 	// 处理 nil 值
 	if _, ok := val.(Null); ok {
 		if rv.Kind() == reflect.Pointer {
 			rv.Set(reflect.Zero(rv.Type()))
 			return nil
 		}
+		if u, ok := implementer[Unmarshaler](rv, codecOf(rv.Type()).unmarshaler); ok {
+			return u.UnmarshalNSON(val)
+		}
 		// 对于非指针类型，保持零值
 		return nil
 	}
@@ -73,7 +278,77 @@ func unmarshalValue(val Value, rv reflect.Value) error {
 		if rv.IsNil() {
 			rv.Set(reflect.New(rv.Type().Elem()))
 		}
-		return unmarshalValue(val, rv.Elem())
+		return self.unmarshalValue(val, rv.Elem())
+	}
+
+	c := codecOf(rv.Type())
+
+	if u, ok := implementer[Unmarshaler](rv, c.unmarshaler); ok {
+		return u.UnmarshalNSON(val)
+	}
+
+	switch rv.Type() {
+	case reflect.TypeFor[time.Time]():
+		if v, ok := val.(Timestamp); ok {
+			// 从毫秒时间戳转换为 time.Time
+			rv.Set(reflect.ValueOf(time.UnixMilli(int64(v))))
+			return nil
+		}
+		return fmt.Errorf("expected Timestamp for time.Time, got %T", val)
+	case reflect.TypeFor[Id]():
+		if v, ok := val.(Id); ok {
+			rv.Set(reflect.ValueOf(v))
+			return nil
+		}
+		return fmt.Errorf("expected nson.Id, got %T", val)
+	}
+
+	if u, ok := self.mapUnmarshaler(rv, c); ok {
+		m, ok := val.(Map)
+		if !ok {
+			return fmt.Errorf("expected Map, got %T", val)
+		}
+		return u.UnmarshalNSON(m)
+	}
+
+	text, isText := implementer[encoding.TextUnmarshaler](rv, c.textUnmarshaler)
+	bin, isBinary := implementer[encoding.BinaryUnmarshaler](rv, c.binaryUnmarshaler)
+	if isText || isBinary {
+		switch v := val.(type) {
+		case String:
+			if isText {
+				return text.UnmarshalText([]byte(v))
+			}
+		case Binary:
+			if isBinary {
+				return bin.UnmarshalBinary([]byte(v))
+			}
+		}
+
+		switch {
+		case isText && isBinary:
+			return fmt.Errorf("expected String or Binary, got %T", val)
+		case isText:
+			return fmt.Errorf("expected String, got %T", val)
+		default:
+			return fmt.Errorf("expected Binary, got %T", val)
+		}
+	}
+
+	if self.NumericConversion != NumericExact {
+		converted, err := self.NumericConversion.convert(val, rv.Type())
+		if err != nil {
+			return err
+		}
+		switch {
+		case converted == nil:
+		case rv.Kind() == reflect.String:
+			val = converted
+		default:
+			// int 和 uint 的转换结果可能是 I64 和 U64，不经过下面的按 Kind 解码
+			setNumber(rv, converted)
+			return nil
+		}
 	}
 
 	switch rv.Kind() {
@@ -170,7 +445,7 @@ func unmarshalValue(val Value, rv reflect.Value) error {
 		return fmt.Errorf("expected String, got %T", val)
 
 	case reflect.Slice:
-		if rv.Type().Elem().Kind() == reflect.Uint8 {
+		if isByteSlice(rv.Type()) {
 			// []byte
 			if v, ok := val.(Binary); ok {
 				rv.SetBytes([]byte(v))
@@ -186,7 +461,7 @@ func unmarshalValue(val Value, rv reflect.Value) error {
 
 		slice := reflect.MakeSlice(rv.Type(), len(arr), len(arr))
 		for i, item := range arr {
-			if err := unmarshalValue(item, slice.Index(i)); err != nil {
+			if err := self.unmarshalValue(item, slice.Index(i)); err != nil {
 				return fmt.Errorf("index %d: %w", i, err)
 			}
 		}
@@ -194,15 +469,6 @@ func unmarshalValue(val Value, rv reflect.Value) error {
 		return nil
 
 	case reflect.Array:
-		// 检查是否是 nson.Id 类型（[12]byte）
-		if rv.Type() == reflect.TypeFor[Id]() {
-			if v, ok := val.(Id); ok {
-				rv.Set(reflect.ValueOf(v))
-				return nil
-			}
-			return fmt.Errorf("expected nson.Id, got %T", val)
-		}
-
 		arr, ok := val.(Array)
 		if !ok {
 			return fmt.Errorf("expected Array, got %T", val)
@@ -213,28 +479,18 @@ func unmarshalValue(val Value, rv reflect.Value) error {
 		}
 
 		for i, item := range arr {
-			if err := unmarshalValue(item, rv.Index(i)); err != nil {
+			if err := self.unmarshalValue(item, rv.Index(i)); err != nil {
 				return fmt.Errorf("index %d: %w", i, err)
 			}
 		}
 		return nil
 
 	case reflect.Struct:
-		// 检查是否是 time.Time 类型
-		if rv.Type() == reflect.TypeFor[time.Time]() {
-			if v, ok := val.(Timestamp); ok {
-				// 从毫秒时间戳转换为 time.Time
-				t := time.UnixMilli(int64(v))
-				rv.Set(reflect.ValueOf(t))
-				return nil
-			}
-			return fmt.Errorf("expected Timestamp for time.Time, got %T", val)
-		}
 		m, ok := val.(Map)
 		if !ok {
 			return fmt.Errorf("expected Map, got %T", val)
 		}
-		return unmarshalStruct(m, rv)
+		return self.unmarshalStruct(m, rv)
 
 	case reflect.Map:
 		// 检查是否是 nson.Map 类型
@@ -259,7 +515,7 @@ func unmarshalValue(val Value, rv reflect.Value) error {
 		mapVal := reflect.MakeMap(rv.Type())
 		for key, item := range m {
 			elemVal := reflect.New(rv.Type().Elem()).Elem()
-			if err := unmarshalValue(item, elemVal); err != nil {
+			if err := self.unmarshalValue(item, elemVal); err != nil {
 				return fmt.Errorf("key %s: %w", key, err)
 			}
 			mapVal.SetMapIndex(reflect.ValueOf(key), elemVal)
@@ -0,0 +1,278 @@
+package nson
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+)
+
+// ApplyUpdate 将更新操作应用到 m 上，语法参考 MongoDB 的更新操作符：
+//
+//	Map{
+//		"$set":   Map{"status": String("ok"), "meta.rev": I32(2)},
+//		"$unset": Map{"tmp": Bool(true)},
+//		"$inc":   Map{"count": I32(1)},
+//		"$push":  Map{"log": Map{"$each": Array{String("a"), String("b")}}},
+//	}
+//
+// 支持 $set $unset $inc $mul $min $max $push $pull $addToSet $rename $currentDate，
+// 路径使用点分格式。$inc 和 $mul 的结果保持字段原有的数值类型，整数溢出或结果不是整数时
+// 返回错误，字段不存在时使用操作数的类型。$min 和 $max 使用 Compare 的顺序。
+//
+// 更新是原子的：任意操作失败时 m 保持不变。
+func ApplyUpdate(m Map, update Map) error {
+	if m == nil {
+		return errors.New("Cannot update nil map")
+	}
+
+	work := m.Clone()
+
+	for _, op := range sortedKeys(update) {
+		fields, ok := update[op].(Map)
+		if !ok {
+			return fmt.Errorf("%v expects Map, got %v", op, dataTypeOf(update[op]))
+		}
+
+		apply, has := updateOperators[op]
+		if !has {
+			return fmt.Errorf("Unknown update operator: %v", op)
+		}
+
+		for _, path := range sortedKeys(fields) {
+			if err := apply(&work, path, fields[path]); err != nil {
+				return fmt.Errorf("%v %v: %w", op, path, err)
+			}
+		}
+	}
+
+	clear(m)
+	maps.Copy(m, work)
+
+	return nil
+}
+
+type updateOperator func(doc *Map, path string, operand Value) error
+
+var updateOperators = map[string]updateOperator{
+	"$set":         updateSet,
+	"$unset":       updateUnset,
+	"$inc":         updateInc,
+	"$mul":         updateMul,
+	"$min":         updateMin,
+	"$max":         updateMax,
+	"$push":        updatePush,
+	"$pull":        updatePull,
+	"$addToSet":    updateAddToSet,
+	"$rename":      updateRename,
+	"$currentDate": updateCurrentDate,
+}
+
+func updateSet(doc *Map, path string, operand Value) error {
+	return doc.SetPath(path, Clone(operand))
+}
+
+func updateUnset(doc *Map, path string, operand Value) error {
+	_, err := doc.DeletePath(path)
+	return err
+}
+
+// updateInc 字段不存在时设置为 operand
+func updateInc(doc *Map, path string, operand Value) error {
+	if _, ok := toNumber(operand); !ok {
+		return fmt.Errorf("Unexpected Type, expected number, got %v", dataTypeOf(operand))
+	}
+
+	current, has := doc.GetPath(path)
+	if !has {
+		return doc.SetPath(path, operand)
+	}
+
+	result, err := arithInPlace(arithAdd, current, operand)
+	if err != nil {
+		return err
+	}
+
+	return doc.SetPath(path, result)
+}
+
+// updateMul 字段不存在时设置为 operand 类型的零值
+func updateMul(doc *Map, path string, operand Value) error {
+	if _, ok := toNumber(operand); !ok {
+		return fmt.Errorf("Unexpected Type, expected number, got %v", dataTypeOf(operand))
+	}
+
+	current, has := doc.GetPath(path)
+	if !has {
+		return doc.SetPath(path, operand.DataType().ZeroValue())
+	}
+
+	result, err := arithInPlace(arithMul, current, operand)
+	if err != nil {
+		return err
+	}
+
+	return doc.SetPath(path, result)
+}
+
+func updateMin(doc *Map, path string, operand Value) error {
+	current, has := doc.GetPath(path)
+	if has && Compare(operand, current) >= 0 {
+		return nil
+	}
+
+	return doc.SetPath(path, Clone(operand))
+}
+
+func updateMax(doc *Map, path string, operand Value) error {
+	current, has := doc.GetPath(path)
+	if has && Compare(operand, current) <= 0 {
+		return nil
+	}
+
+	return doc.SetPath(path, Clone(operand))
+}
+
+// eachValues 解析 $push 和 $addToSet 的操作数，支持 Map{"$each": Array{...}}
+func eachValues(operand Value) (Array, error) {
+	if m, ok := operand.(Map); ok {
+		if each, has := m["$each"]; has {
+			if len(m) != 1 {
+				return nil, errors.New("Unsupported modifier with $each")
+			}
+			arr, ok := each.(Array)
+			if !ok {
+				return nil, fmt.Errorf("$each expects Array, got %v", dataTypeOf(each))
+			}
+			return arr.Clone(), nil
+		}
+	}
+
+	return Array{Clone(operand)}, nil
+}
+
+func updatePush(doc *Map, path string, operand Value) error {
+	values, err := eachValues(operand)
+	if err != nil {
+		return err
+	}
+
+	return doc.AppendPath(path, values...)
+}
+
+func updateAddToSet(doc *Map, path string, operand Value) error {
+	values, err := eachValues(operand)
+	if err != nil {
+		return err
+	}
+
+	current, _ := doc.GetPath(path)
+	existing, _ := current.(Array)
+
+	var add Array
+	for _, v := range values {
+		if !arrayContains(existing, v) && !arrayContains(add, v) {
+			add = append(add, v)
+		}
+	}
+
+	return doc.AppendPath(path, add...)
+}
+
+func arrayContains(arr Array, v Value) bool {
+	for _, item := range arr {
+		if Equal(item, v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// updatePull 删除数组中所有匹配的元素。
+// operand 为操作符 Map 时作为元素的条件，为普通 Map 时作为 Map 元素的过滤器，
+// 其他值按 EqualNumeric 比较。
+func updatePull(doc *Map, path string, operand Value) error {
+	current, has := doc.GetPath(path)
+	if !has {
+		return nil
+	}
+
+	arr, ok := current.(Array)
+	if !ok {
+		return fmt.Errorf("Unexpected Type, expected Array, got %v", dataTypeOf(current))
+	}
+
+	var match func(Value) bool
+
+	if cond, ok := operand.(Map); ok && isOperatorMap(cond) {
+		pred, err := compileOperators(cond)
+		if err != nil {
+			return err
+		}
+		match = func(v Value) bool { return pred([]Value{v}) }
+	} else if ok {
+		pred, err := compileFilter(cond)
+		if err != nil {
+			return err
+		}
+		match = func(v Value) bool {
+			m, ok := v.(Map)
+			return ok && pred(m)
+		}
+	} else {
+		match = func(v Value) bool { return EqualNumeric(v, operand) }
+	}
+
+	result := make(Array, 0, len(arr))
+	for _, item := range arr {
+		if !match(item) {
+			result = append(result, item)
+		}
+	}
+
+	return doc.SetPath(path, result)
+}
+
+func updateRename(doc *Map, path string, operand Value) error {
+	to, ok := operand.(String)
+	if !ok {
+		return fmt.Errorf("$rename expects String, got %v", dataTypeOf(operand))
+	}
+
+	if string(to) == path || strings.HasPrefix(string(to), path+".") || strings.HasPrefix(path, string(to)+".") {
+		return errors.New("Cannot rename a field into itself")
+	}
+
+	value, has := doc.GetPath(path)
+	if !has {
+		return nil
+	}
+
+	if _, err := doc.DeletePath(path); err != nil {
+		return err
+	}
+
+	return doc.SetPath(string(to), value)
+}
+
+// updateCurrentDate 将字段设置为当前时间的毫秒时间戳，
+// operand 为 Bool(true) 或 Map{"$type": String("timestamp")}
+func updateCurrentDate(doc *Map, path string, operand Value) error {
+	switch x := operand.(type) {
+	case Bool:
+		if !x {
+			return errors.New("$currentDate expects true")
+		}
+	case Map:
+		t, err := x.GetString("$type")
+		if err != nil || !strings.EqualFold(t, "timestamp") {
+			return errors.New("$currentDate expects {$type: \"timestamp\"}")
+		}
+	default:
+		return fmt.Errorf("$currentDate expects Bool or Map, got %v", dataTypeOf(operand))
+	}
+
+	return doc.SetPath(path, Timestamp(time.Now().UnixMilli()))
+}
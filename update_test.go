@@ -0,0 +1,234 @@
+package nson
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPromoteTypes(t *testing.T) {
+	tests := []struct {
+		a, b DataType
+		want DataType
+	}{
+		{DataTypeI32, DataTypeI32, DataTypeI32},
+		{DataTypeI8, DataTypeI32, DataTypeI32},
+		{DataTypeU8, DataTypeU64, DataTypeU64},
+		{DataTypeI8, DataTypeU8, DataTypeI16},
+		{DataTypeU32, DataTypeI32, DataTypeI64},
+		{DataTypeU16, DataTypeI64, DataTypeI64},
+		{DataTypeU64, DataTypeI8, DataTypeI64},
+		{DataTypeF32, DataTypeF32, DataTypeF32},
+		{DataTypeF32, DataTypeI8, DataTypeF64},
+		{DataTypeU64, DataTypeF64, DataTypeF64},
+	}
+
+	for _, tt := range tests {
+		if got, _ := PromoteTypes(tt.a, tt.b); got != tt.want {
+			t.Errorf("PromoteTypes(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	if _, ok := PromoteTypes(DataTypeI32, DataTypeSTRING); ok {
+		t.Error("Expected String to be rejected")
+	}
+}
+
+func TestUpdateSetUnset(t *testing.T) {
+	doc := Map{"a": I32(1), "tmp": String("x")}
+
+	err := ApplyUpdate(doc, Map{
+		"$set":   Map{"b.c": String("y"), "a": I64(2)},
+		"$unset": Map{"tmp": Bool(true)},
+	})
+	if err != nil {
+		t.Fatalf("ApplyUpdate failed: %v", err)
+	}
+
+	want := Map{"a": I64(2), "b": Map{"c": String("y")}}
+	if !Equal(doc, want) {
+		t.Errorf("Expected %v, got %v", want, doc)
+	}
+}
+
+func TestUpdateIncMul(t *testing.T) {
+	doc := Map{"i8": I8(100), "u8": U8(10), "f": F32(1.5), "i32": I32(7), "d": F64(2)}
+
+	err := ApplyUpdate(doc, Map{
+		"$inc": Map{"u8": U8(5), "f": F64(1), "i32": I64(1), "new": U16(3)},
+		"$mul": Map{"missing": I16(4), "d": I8(-2)},
+	})
+	if err != nil {
+		t.Fatalf("ApplyUpdate failed: %v", err)
+	}
+
+	// 结果保持字段原有的类型，字段不存在时使用操作数的类型
+	want := Map{"i8": I8(100), "u8": U8(15), "f": F32(2.5), "i32": I32(8), "d": F64(-4), "new": U16(3), "missing": I16(0)}
+	if !Equal(doc, want) {
+		t.Errorf("Expected %v, got %v", want, doc)
+	}
+	for key, v := range want {
+		if doc[key].DataType() != v.DataType() {
+			t.Errorf("%v: expected %v, got %v", key, v.DataType(), doc[key].DataType())
+		}
+	}
+
+	if err := ApplyUpdate(doc, Map{"$inc": Map{"i8": I8(100)}}); err == nil {
+		t.Error("Expected I8 overflow error")
+	}
+	if doc["i8"] != I8(100) {
+		t.Errorf("Expected document to be unchanged after error, got %v", doc["i8"])
+	}
+
+	if err := ApplyUpdate(doc, Map{"$mul": Map{"u8": I8(-1)}}); err == nil {
+		t.Error("Expected U8 overflow error for a negative result")
+	}
+
+	big := Map{"n": I64(math.MaxInt64)}
+	if err := ApplyUpdate(big, Map{"$inc": Map{"n": I32(1)}}); err == nil {
+		t.Error("Expected I64 overflow error")
+	}
+
+	// 结果类型只取决于字段的类型，与值无关
+	typed := []struct {
+		doc    Map
+		update Map
+		want   Value
+	}{
+		{Map{"n": U8(5)}, Map{"$inc": Map{"n": I32(1)}}, U8(6)},
+		{Map{"n": U8(5)}, Map{"$inc": Map{"n": I32(-5)}}, U8(0)},
+		{Map{"n": U64(5)}, Map{"$inc": Map{"n": I32(1)}}, U64(6)},
+		{Map{"n": U64(1 << 63)}, Map{"$inc": Map{"n": I32(1)}}, U64(1<<63 + 1)},
+		{Map{"n": U64(1 << 63)}, Map{"$inc": Map{"n": I64(math.MaxInt64)}}, U64(math.MaxUint64)},
+		{Map{"n": U64(1 << 63)}, Map{"$mul": Map{"n": I8(0)}}, U64(0)},
+		{Map{"n": I16(3)}, Map{"$inc": Map{"n": U64(4)}}, I16(7)},
+		{Map{"n": I32(3)}, Map{"$mul": Map{"n": F64(2)}}, I32(6)},
+		{Map{"n": F32(1)}, Map{"$inc": Map{"n": U64(1)}}, F32(2)},
+	}
+	for _, tt := range typed {
+		if err := ApplyUpdate(tt.doc, tt.update); err != nil {
+			t.Errorf("ApplyUpdate(%v, %v) failed: %v", tt.doc, tt.update, err)
+			continue
+		}
+		if got := tt.doc["n"]; got.DataType() != tt.want.DataType() || !Equal(got, tt.want) {
+			t.Errorf("ApplyUpdate(%v): expected %v, got %v", tt.update, tt.want, got)
+		}
+	}
+
+	overflows := []struct {
+		doc    Map
+		update Map
+	}{
+		{Map{"n": U64(math.MaxUint64)}, Map{"$inc": Map{"n": I32(1)}}},
+		{Map{"n": U64(math.MaxUint64)}, Map{"$mul": Map{"n": I8(2)}}},
+		{Map{"n": U64(1)}, Map{"$mul": Map{"n": I8(-1)}}},
+		{Map{"n": U8(0)}, Map{"$inc": Map{"n": I8(-1)}}},
+		{Map{"n": I8(1)}, Map{"$inc": Map{"n": U64(200)}}},
+		{Map{"n": I32(3)}, Map{"$inc": Map{"n": F64(0.5)}}},
+		{Map{"n": I8(1)}, Map{"$mul": Map{"n": F64(1e3)}}},
+	}
+	for _, tt := range overflows {
+		if err := ApplyUpdate(tt.doc, tt.update); err == nil {
+			t.Errorf("ApplyUpdate(%v, %v): expected error", tt.doc, tt.update)
+		}
+	}
+
+	if err := ApplyUpdate(doc, Map{"$inc": Map{"i8": String("1")}}); err == nil {
+		t.Error("Expected error for non-numeric operand")
+	}
+}
+
+func TestUpdateMinMax(t *testing.T) {
+	doc := Map{"low": I32(10), "high": F64(10)}
+
+	err := ApplyUpdate(doc, Map{
+		"$min": Map{"low": U8(5), "other": I32(1)},
+		"$max": Map{"high": I8(9)},
+	})
+	if err != nil {
+		t.Fatalf("ApplyUpdate failed: %v", err)
+	}
+
+	want := Map{"low": U8(5), "high": F64(10), "other": I32(1)}
+	if !Equal(doc, want) {
+		t.Errorf("Expected %v, got %v", want, doc)
+	}
+}
+
+func TestUpdateArrays(t *testing.T) {
+	doc := Map{
+		"tags":  Array{String("a"), String("b")},
+		"nums":  Array{I32(1), I64(5), U8(9), I32(3)},
+		"items": Array{Map{"id": I32(1)}, Map{"id": I32(2)}},
+	}
+
+	err := ApplyUpdate(doc, Map{
+		"$push":     Map{"log": String("x"), "tags": Map{"$each": Array{String("c"), String("a")}}},
+		"$addToSet": Map{"set": Map{"$each": Array{I32(1), I32(1), I32(2)}}},
+	})
+	if err != nil {
+		t.Fatalf("ApplyUpdate failed: %v", err)
+	}
+
+	err = ApplyUpdate(doc, Map{
+		"$pull": Map{
+			"tags":  String("a"),
+			"nums":  Map{"$gte": I32(5)},
+			"items": Map{"id": I32(2)},
+		},
+		"$addToSet": Map{"set": I32(2)},
+	})
+	if err != nil {
+		t.Fatalf("ApplyUpdate failed: %v", err)
+	}
+
+	want := Map{
+		"tags":  Array{String("b"), String("c")},
+		"nums":  Array{I32(1), I32(3)},
+		"items": Array{Map{"id": I32(1)}},
+		"log":   Array{String("x")},
+		"set":   Array{I32(1), I32(2)},
+	}
+	if !Equal(doc, want) {
+		t.Errorf("Expected %v, got %v", want, doc)
+	}
+
+	if err := ApplyUpdate(doc, Map{"$push": Map{"items.0.id": I32(1)}}); err == nil {
+		t.Error("Expected error when pushing to a non-array")
+	}
+}
+
+func TestUpdateRenameCurrentDate(t *testing.T) {
+	doc := Map{"old": Map{"name": String("x")}}
+
+	before := time.Now().UnixMilli()
+	err := ApplyUpdate(doc, Map{
+		"$rename":      Map{"old.name": String("new.title")},
+		"$currentDate": Map{"updated": Bool(true), "meta.seen": Map{"$type": String("timestamp")}},
+	})
+	if err != nil {
+		t.Fatalf("ApplyUpdate failed: %v", err)
+	}
+
+	if v, _ := doc.GetPath("new.title"); v != String("x") {
+		t.Errorf("Expected renamed field, got %v", doc)
+	}
+	if _, has := doc.GetPath("old.name"); has {
+		t.Error("Expected old field to be removed")
+	}
+
+	ts, err := doc.GetTimestamp("updated")
+	if err != nil || ts < before {
+		t.Errorf("Expected current timestamp, got %v, %v", ts, err)
+	}
+	if v, _ := doc.GetPath("meta.seen"); v.DataType() != DataTypeTIMESTAMP {
+		t.Errorf("Expected Timestamp, got %v", v)
+	}
+
+	if err := ApplyUpdate(doc, Map{"$rename": Map{"new": String("new.sub")}}); err == nil {
+		t.Error("Expected error when renaming into itself")
+	}
+	if err := ApplyUpdate(doc, Map{"$bogus": Map{"a": I32(1)}}); err == nil {
+		t.Error("Expected error for unknown operator")
+	}
+}
@@ -0,0 +1,348 @@
+package nson
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danclive/nson-go/internal/nsontag"
+)
+
+// ValidationErrors Validate 返回的全部校验失败
+type ValidationErrors []ValidationError
+
+func (self ValidationErrors) Error() string {
+	msgs := make([]string, len(self))
+	for i, err := range self {
+		msgs[i] = err.Error()
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// Validate 按结构体字段的 validate tag 校验 v，包括嵌套的结构体、指针、切片、数组和 map。
+// 校验失败时返回 ValidationErrors，Path 为字段在 Marshal 结果中的点分路径。
+// 支持的规则以逗号分隔：
+//
+//   - min=n、max=n：数值和 Timestamp 比较值，字符串比较字符数，Binary 比较字节数，
+//     切片和数组比较元素个数
+//   - len=n：字符串、Binary、切片和数组的长度
+//   - oneof=a b c：数值或字符串必须是以空格分隔的值之一
+//   - regex=pattern：字符串必须匹配正则表达式，必须是最后一条规则，可以包含逗号
+//
+// 有 type 选项的字段按指定的类型校验，nil 指针不校验，需要时使用 required
+func Validate(v any) error {
+	return validate(v, nil)
+}
+
+// validate 校验 v，Path 使用 naming 生成的键名
+func validate(v any, naming *Naming) error {
+	var errs []ValidationError
+
+	if err := validateValue(&errs, "", reflect.ValueOf(v), naming); err != nil {
+		return err
+	}
+
+	if len(errs) > 0 {
+		return ValidationErrors(errs)
+	}
+
+	return nil
+}
+
+// validateValue 在 rv 中查找结构体并校验其字段
+func validateValue(errs *[]ValidationError, path string, rv reflect.Value, naming *Naming) error {
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+
+	// 自定义编码的类型不按字段处理
+	if !rv.IsValid() || hasCustomCodec(rv.Type()) {
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		if rv.Type() == reflect.TypeFor[time.Time]() {
+			return nil
+		}
+		return validateStruct(errs, path, rv, naming)
+
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && isByteSlice(rv.Type()) {
+			return nil
+		}
+		for i := 0; i < rv.Len(); i++ {
+			if err := validateValue(errs, childPath(path, strconv.Itoa(i)), rv.Index(i), naming); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		return validateMap(errs, path, rv, nil, naming)
+	}
+
+	return nil
+}
+
+// validateStruct 校验结构体的字段，remain 字段和内联的 map 中的值位于结构体的路径下
+func validateStruct(errs *[]ValidationError, path string, rv reflect.Value, naming *Naming) error {
+	cache := getStructCache(rv.Type(), naming)
+	if cache.err != nil {
+		return cache.err
+	}
+
+	for _, field := range cache.fields {
+		fv, ok := fieldByIndex(rv, field.indices)
+		if !ok {
+			continue
+		}
+
+		fieldPath := childPath(path, field.nsonName)
+
+		if field.rules != nil {
+			val, err := MarshalOptions{Naming: naming}.marshalField(fv, field.wire)
+			if err != nil {
+				return fmt.Errorf("field %s: %w", field.name, err)
+			}
+			if _, ok := val.(Null); !ok {
+				field.rules.validate(errs, fieldPath, val)
+			}
+		}
+
+		if err := validateValue(errs, fieldPath, fv, naming); err != nil {
+			return err
+		}
+	}
+
+	if cache.remain != nil {
+		if fv, ok := fieldByIndex(rv, cache.remain.indices); ok {
+			return validateMap(errs, path, fv, cache.names, naming)
+		}
+	}
+
+	return nil
+}
+
+// validateMap 按键的顺序校验 map 中的值，跳过 skip 中的键
+func validateMap(errs *[]ValidationError, path string, rv reflect.Value, skip map[string]bool, naming *Naming) error {
+	keys := rv.MapKeys()
+	slices.SortFunc(keys, func(a, b reflect.Value) int {
+		return strings.Compare(a.String(), b.String())
+	})
+
+	for _, key := range keys {
+		if skip[key.String()] {
+			continue
+		}
+		if err := validateValue(errs, childPath(path, key.String()), rv.MapIndex(key), naming); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseRules 将 validate tag 解析为只有约束的 Schema，规则见 Validate。
+// wire 不为 0 时按 type 选项指定的类型解析
+func parseRules(tag string, t reflect.Type, wire DataType) (*Schema, error) {
+	dt, ok := ruleType(t)
+	if wire != 0 {
+		dt, ok = wire, true
+	}
+	if !ok {
+		return nil, fmt.Errorf("unsupported type %v", t)
+	}
+
+	rules := &Schema{}
+
+	for tag != "" {
+		var rule string
+		if strings.HasPrefix(tag, "regex=") {
+			rule, tag = tag, ""
+		} else {
+			rule, tag, _ = strings.Cut(tag, ",")
+		}
+
+		name, arg, _ := strings.Cut(rule, "=")
+
+		if err := rules.addRule(name, arg, dt); err != nil {
+			return nil, fmt.Errorf("rule %q: %w", rule, err)
+		}
+	}
+
+	return rules, nil
+}
+
+// addRule 按值的类型将一条规则转换为约束
+func (self *Schema) addRule(name, arg string, dt DataType) error {
+	length := func() (*int, error) {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid length %q", arg)
+		}
+		return &n, nil
+	}
+
+	switch {
+	case (name == "min" || name == "max") && (dt.IsNumeric() || dt == DataTypeTIMESTAMP):
+		val, err := parseRuleValue(arg, dt)
+		if err != nil {
+			return err
+		}
+		if name == "min" {
+			self.Min = val
+		} else {
+			self.Max = val
+		}
+
+	case name == "min" || name == "max" || name == "len":
+		if dt != DataTypeSTRING && dt != DataTypeBINARY && dt != DataTypeARRAY {
+			return fmt.Errorf("not supported for %v", dt)
+		}
+		n, err := length()
+		if err != nil {
+			return err
+		}
+		if dt == DataTypeARRAY {
+			if name != "max" {
+				self.MinItems = n
+			}
+			if name != "min" {
+				self.MaxItems = n
+			}
+		} else {
+			if name != "max" {
+				self.MinLength = n
+			}
+			if name != "min" {
+				self.MaxLength = n
+			}
+		}
+
+	case name == "oneof":
+		if !dt.IsNumeric() && dt != DataTypeSTRING {
+			return fmt.Errorf("not supported for %v", dt)
+		}
+		for _, s := range strings.Fields(arg) {
+			val, err := parseRuleValue(s, dt)
+			if err != nil {
+				return err
+			}
+			self.Enum = append(self.Enum, val)
+		}
+
+	case name == "regex":
+		if dt != DataTypeSTRING {
+			return fmt.Errorf("not supported for %v", dt)
+		}
+		if _, err := compilePattern(arg); err != nil {
+			return err
+		}
+		self.Pattern = arg
+
+	default:
+		return fmt.Errorf("unknown rule")
+	}
+
+	return nil
+}
+
+// parseRuleValue 将规则中的值解析为 dt 类型
+func parseRuleValue(s string, dt DataType) (Value, error) {
+	switch dt {
+	case DataTypeSTRING:
+		return String(s), nil
+	case DataTypeTIMESTAMP:
+		ms, err := nsontag.ParseMillis(s)
+		if err != nil {
+			return nil, err
+		}
+		return Timestamp(ms), nil
+	}
+
+	return parseNumber(s, dt)
+}
+
+// ruleType 返回类型编码后的 NSON 类型，与 marshalValue 一致，
+// 类型不确定（如实现了 Marshaler）时返回 false
+func ruleType(t reflect.Type) (DataType, bool) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case codecOf(t).marshaler != implNone:
+		return 0, false
+	case t == reflect.TypeFor[time.Time]():
+		return DataTypeTIMESTAMP, true
+	case t == reflect.TypeFor[Timestamp]():
+		return DataTypeTIMESTAMP, true
+	case codecOf(t).textMarshaler != implNone:
+		return DataTypeSTRING, true
+	case codecOf(t).binaryMarshaler != implNone:
+		return DataTypeBINARY, true
+	}
+
+	if dt, ok := kindDataType(t.Kind()); ok {
+		return dt, true
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return DataTypeSTRING, true
+	case reflect.Slice:
+		if isByteSlice(t) {
+			return DataTypeBINARY, true
+		}
+		return DataTypeARRAY, true
+	case reflect.Array:
+		if t == reflect.TypeFor[Id]() {
+			return 0, false
+		}
+		return DataTypeARRAY, true
+	}
+
+	return 0, false
+}
+
+// constrain 将 rules 中的约束复制到 schema
+func (self *Schema) constrain(rules *Schema) {
+	if rules.Min != nil {
+		self.Min = rules.Min
+	}
+	if rules.Max != nil {
+		self.Max = rules.Max
+	}
+	if rules.Enum != nil {
+		self.Enum = slices.Clone(rules.Enum)
+		// 指针可以为 Null
+		if slices.Contains(self.Types, DataTypeNULL) {
+			self.Enum = append(self.Enum, Null{})
+		}
+	}
+	if rules.MinLength != nil {
+		self.MinLength = rules.MinLength
+	}
+	if rules.MaxLength != nil {
+		self.MaxLength = rules.MaxLength
+	}
+	if rules.MinItems != nil {
+		self.MinItems = rules.MinItems
+	}
+	if rules.MaxItems != nil {
+		self.MaxItems = rules.MaxItems
+	}
+	if rules.Pattern != "" {
+		self.Pattern = rules.Pattern
+	}
+}
@@ -48,7 +48,7 @@ func EncodeValue(buf *bytes.Buffer, value Value) error {
 	case Map:
 		return EncodeMap(v, buf)
 	default:
-		return fmt.Errorf("Unsupported type '%X'", value.DataType())
+		return fmt.Errorf("Unsupported type '%X'", uint8(value.DataType()))
 	}
 }
 
@@ -100,6 +100,6 @@ func decodeValueWithTag(buf *bytes.Buffer, tag DataType) (Value, error) {
 	case DataTypeMAP:
 		return DecodeMap(buf)
 	default:
-		return nil, fmt.Errorf("Unsupported type '%X'", tag)
+		return nil, fmt.Errorf("Unsupported type '%X'", uint8(tag))
 	}
 }
@@ -0,0 +1,131 @@
+package nson
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// wireType 解析 type 选项，如 "i64"、"u16"、"timestamp" 或 "string"，t 为字段类型。
+// 只支持数值字段和指向数值的指针，timestamp 只支持整数
+func wireType(name string, t reflect.Type) (DataType, error) {
+	dt, ok := ParseDataType(name)
+	if !ok || !dt.IsNumeric() && dt != DataTypeTIMESTAMP && dt != DataTypeSTRING {
+		return 0, fmt.Errorf("unsupported type=%s", name)
+	}
+
+	elem := t
+	for elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+
+	src, ok := kindDataType(elem.Kind())
+	if !ok || hasCustomCodec(elem) {
+		return 0, fmt.Errorf("type=%s requires a numeric field, got %v", name, t)
+	}
+
+	if dt == DataTypeTIMESTAMP && (src == DataTypeF32 || src == DataTypeF64) {
+		return 0, fmt.Errorf("type=%s requires an integer field, got %v", name, t)
+	}
+
+	return dt, nil
+}
+
+// marshalWire 将数值字段编码为 dt 类型，超出范围或无法精确表示时返回错误，nil 指针编码为 Null
+func marshalWire(rv reflect.Value, dt DataType) (Value, error) {
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return Null{}, nil
+		}
+		rv = rv.Elem()
+	}
+
+	var val Value
+	switch {
+	case rv.CanInt():
+		val = I64(rv.Int())
+	case rv.CanUint():
+		val = U64(rv.Uint())
+	case rv.Kind() == reflect.Float32:
+		val = F32(rv.Float())
+	default:
+		val = F64(rv.Float())
+	}
+
+	n, _ := toNumber(val)
+
+	switch dt {
+	case DataTypeSTRING:
+		return String(formatNumber(val)), nil
+
+	case DataTypeTIMESTAMP:
+		if n.kind == numberInt {
+			if n.i < 0 {
+				return nil, fmt.Errorf("%v overflows %v", val, dt)
+			}
+			return Timestamp(n.i), nil
+		}
+		return Timestamp(n.u), nil
+	}
+
+	return convertNumber(n, val, dt)
+}
+
+// unmarshalWire 将 dt 类型的值解码到数值字段，超出范围或无法精确表示时返回错误。
+// Null 的处理与 unmarshalValue 相同
+func unmarshalWire(val Value, rv reflect.Value, dt DataType) error {
+	if _, ok := val.(Null); ok {
+		if rv.Kind() == reflect.Pointer {
+			rv.Set(reflect.Zero(rv.Type()))
+		}
+		return nil
+	}
+
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return unmarshalWire(val, rv.Elem(), dt)
+	}
+
+	if val.DataType() != dt {
+		return fmt.Errorf("expected %v, got %T", dt, val)
+	}
+
+	target := exactDataType(rv.Type())
+
+	var converted Value
+	var err error
+	switch v := val.(type) {
+	case String:
+		converted, err = parseNumber(string(v), target)
+	case Timestamp:
+		converted, err = convertNumber(number{kind: numberUint, u: uint64(v)}, v, target)
+	default:
+		n, _ := toNumber(val)
+		converted, err = convertNumber(n, val, target)
+	}
+	if err != nil {
+		return err
+	}
+
+	setNumber(rv, converted)
+	return nil
+}
+
+// exactDataType 返回能表示数值类型 t 所有值的 NSON 类型，与 kindDataType 不同，
+// int 和 uint 按实际位数
+func exactDataType(t reflect.Type) DataType {
+	switch t.Kind() {
+	case reflect.Int:
+		if t.Bits() == 64 {
+			return DataTypeI64
+		}
+	case reflect.Uint:
+		if t.Bits() == 64 {
+			return DataTypeU64
+		}
+	}
+
+	dt, _ := kindDataType(t.Kind())
+	return dt
+}